@@ -1,7 +1,16 @@
 package main
 
-import "assignerPR/internal/initializers"
+import (
+	"assignerPR/internal/initializers"
+	"os"
+)
 
 func main() {
+	// assigner roster import|export - работа с составом команд без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "roster" {
+		initializers.RunRoster(os.Args[2:])
+		return
+	}
+
 	initializers.RunPRAssigner()
 }
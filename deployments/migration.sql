@@ -1,16 +1,41 @@
 DO $$
 BEGIN
     IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'pull_request_status') THEN
-        CREATE TYPE pull_request_status AS ENUM ('OPEN', 'MERGED');
+        CREATE TYPE pull_request_status AS ENUM ('OPEN', 'MERGED', 'CLOSED', 'DRAFT');
     END IF;
 END $$;
 
+-- Для баз, созданных до появления новых статусов
+ALTER TYPE pull_request_status ADD VALUE IF NOT EXISTS 'CLOSED';
+ALTER TYPE pull_request_status ADD VALUE IF NOT EXISTS 'DRAFT';
+
 CREATE TABLE IF NOT EXISTS teams (
     team_name VARCHAR(64) PRIMARY KEY,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS selection_strategy VARCHAR(32) NOT NULL DEFAULT 'LEAST_LOADED';
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS reviewers_required INT NOT NULL DEFAULT 2;
+-- 0 - без лимита
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS max_open_reviews INT NOT NULL DEFAULT 0;
+-- 0 - мерж без проверки одобрений
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS required_approvals INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS team_rotation_cursors (
+    team_name VARCHAR(64) PRIMARY KEY REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    last_user_id VARCHAR(64) NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Запасные команды, куда добирается выбор ревьюверов, если своих не хватило
+CREATE TABLE IF NOT EXISTS team_fallbacks (
+    team_name VARCHAR(64) NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    fallback_team_name VARCHAR(64) NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    position INT NOT NULL,
+    PRIMARY KEY (team_name, fallback_team_name)
+);
+
 CREATE TABLE IF NOT EXISTS users (
     user_id VARCHAR(64) PRIMARY KEY,
     username VARCHAR(255) NOT NULL,
@@ -22,6 +47,36 @@ CREATE TABLE IF NOT EXISTS users (
 
 CREATE INDEX IF NOT EXISTS idx_users_team_name ON users(team_name);
 
+-- NULL - лимит команды
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_open_reviews INT;
+-- надгробие уволенного автора PR, удалить его не дает pull_requests.author_id
+ALTER TABLE users ADD COLUMN IF NOT EXISTS offboarded_at TIMESTAMPTZ;
+
+-- Отпуска и прочие OOO: пока окно активно, пользователя не назначают
+CREATE TABLE IF NOT EXISTS user_unavailabilities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_unavailabilities_user ON user_unavailabilities(user_id, ends_at);
+
+-- Правила владения путями в духе CODEOWNERS, одна строка на пару (шаблон, владелец)
+CREATE TABLE IF NOT EXISTS team_path_rules (
+    id BIGSERIAL PRIMARY KEY,
+    team_name VARCHAR(64) NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    position INT NOT NULL,
+    pattern VARCHAR(255) NOT NULL,
+    user_id VARCHAR(64) NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_path_rules_team ON team_path_rules(team_name);
+
 CREATE TABLE IF NOT EXISTS pull_requests (
     pull_request_id VARCHAR(64) PRIMARY KEY,
     pull_request_name VARCHAR(255) NOT NULL,
@@ -32,9 +87,19 @@ CREATE TABLE IF NOT EXISTS pull_requests (
     merged_at TIMESTAMPTZ
 );
 
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS reviewers_required INT NOT NULL DEFAULT 2;
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS changed_paths JSONB;
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ;
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS labels JSONB;
+
 -- Вряд ли бы подумал, если бы не упоминание в "полезных" ссылках c прошлых наборов
 CREATE INDEX IF NOT EXISTS idx_pull_requests_author ON pull_requests(author_id);
 CREATE INDEX IF NOT EXISTS idx_pull_requests_status ON pull_requests(status);
+-- под keyset-пагинацию /pullRequest/list
+CREATE INDEX IF NOT EXISTS idx_pull_requests_created ON pull_requests(created_at, pull_request_id);
+CREATE INDEX IF NOT EXISTS idx_pull_requests_updated ON pull_requests(updated_at, pull_request_id);
+-- под фильтр labels @> '["..."]'
+CREATE INDEX IF NOT EXISTS idx_pull_requests_labels ON pull_requests USING GIN (labels);
 
 CREATE TABLE IF NOT EXISTS pr_reviewers (
     pull_request_id VARCHAR(64) NOT NULL REFERENCES pull_requests(pull_request_id) ON UPDATE CASCADE ON DELETE CASCADE,
@@ -43,5 +108,74 @@ CREATE TABLE IF NOT EXISTS pr_reviewers (
     PRIMARY KEY (pull_request_id, user_id)
 );
 
+-- Команда, из которой назначен ревьювер (своя или запасная). Проставляется триггером из users.team_name
+-- на момент назначения: кандидаты всегда выбираются из членов той команды, в которую идет выбор
+ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS team_name VARCHAR(64)
+    REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE OR REPLACE FUNCTION pr_reviewers_set_team() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.team_name IS NULL THEN
+        SELECT team_name INTO NEW.team_name FROM users WHERE user_id = NEW.user_id;
+    END IF;
+    RETURN NEW;
+END $$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS pr_reviewers_set_team ON pr_reviewers;
+CREATE TRIGGER pr_reviewers_set_team BEFORE INSERT ON pr_reviewers
+    FOR EACH ROW EXECUTE FUNCTION pr_reviewers_set_team();
+
+-- Для назначений, сделанных до появления колонки, лучшее, что есть - текущая команда
+UPDATE pr_reviewers SET team_name = u.team_name
+FROM users u
+WHERE u.user_id = pr_reviewers.user_id AND pr_reviewers.team_name IS NULL;
+
 CREATE INDEX IF NOT EXISTS idx_pr_reviewers_user ON pr_reviewers(user_id);
-CREATE INDEX IF NOT EXISTS idx_pr_reviewers_pr ON pr_reviewers(pull_request_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_pr_reviewers_pr ON pr_reviewers(pull_request_id);
+-- под keyset-пагинацию /users/getReview
+CREATE INDEX IF NOT EXISTS idx_pr_reviewers_user_assigned ON pr_reviewers(user_id, created_at, pull_request_id);
+
+-- История вердиктов, для гейта мержа берется последний вердикт каждого ревьювера
+CREATE TABLE IF NOT EXISTS pr_reviews (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(64) NOT NULL REFERENCES pull_requests(pull_request_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_id VARCHAR(64) NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    verdict VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_reviews_pr_user ON pr_reviews(pull_request_id, user_id, created_at DESC);
+
+-- Логины GitHub/GitLab -> user_id для вебхуков, login в нижнем регистре
+CREATE TABLE IF NOT EXISTS user_identities (
+    provider VARCHAR(32) NOT NULL,
+    login VARCHAR(255) NOT NULL,
+    user_id VARCHAR(64) NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, login)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+-- id автора у провайдера: GitLab присылает в событиях MR только author_id
+ALTER TABLE user_identities ADD COLUMN IF NOT EXISTS external_id BIGINT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_external ON user_identities(provider, external_id)
+    WHERE external_id IS NOT NULL;
+
+-- Где PR живет на GitHub/GitLab и статус отправки туда назначенных ревьюверов
+CREATE TABLE IF NOT EXISTS pr_reviewer_syncs (
+    pull_request_id VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(32) NOT NULL,
+    repo VARCHAR(255) NOT NULL,
+    number BIGINT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'PENDING',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    reviewers JSONB,
+    unmapped JSONB,
+    synced_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- недоотправленные PR поднимаются отсюда при старте сервиса
+CREATE INDEX IF NOT EXISTS idx_pr_reviewer_syncs_pending ON pr_reviewer_syncs(updated_at) WHERE status = 'PENDING';
\ No newline at end of file
@@ -11,15 +11,28 @@ type PullRequest struct {
 	AuthorID          string     `json:"author_id"`
 	Status            string     `json:"status"`
 	AssignedReviewers []string   `json:"assigned_reviewers"`
+	Reviewers         []Reviewer `json:"reviewers"`
+	ReviewersRequired int        `json:"reviewers_required"`
+	ChangedPaths      []string   `json:"changed_paths,omitempty"`
+	Labels            []string   `json:"labels"`
 	CreatedAt         *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
 	MergedAt          *time.Time `json:"mergedAt,omitempty"`
+	ClosedAt          *time.Time `json:"closedAt,omitempty"`
+}
+
+// Reviewer - ревьювер вместе с командой, из которой он назначен (своя или запасная)
+type Reviewer struct {
+	UserID   string `json:"user_id"`
+	TeamName string `json:"team_name"`
 }
 
 type PRShort struct {
-	PullRequestID   string `json:"pull_request_id"`
-	PullRequestName string `json:"pull_request_name"`
-	AuthorID        string `json:"author_id"`
-	Status          string `json:"status"`
+	PullRequestID   string   `json:"pull_request_id"`
+	PullRequestName string   `json:"pull_request_name"`
+	AuthorID        string   `json:"author_id"`
+	Status          string   `json:"status"`
+	Labels          []string `json:"labels"`
 }
 
 func FromPR(pr *pullrequest.PullRequest) PullRequest {
@@ -28,17 +41,23 @@ func FromPR(pr *pullrequest.PullRequest) PullRequest {
 	}
 
 	reviewerIDs := make([]string, 0, len(pr.AssignedReviewers))
+	reviewers := make([]Reviewer, 0, len(pr.AssignedReviewers))
 	for _, r := range pr.AssignedReviewers {
 		if r != nil && r.UserID != "" {
 			reviewerIDs = append(reviewerIDs, r.UserID)
+			reviewers = append(reviewers, Reviewer{UserID: r.UserID, TeamName: pr.ReviewerTeam(r)})
 		}
 	}
 
-	var createdAtPtr *time.Time
+	var createdAtPtr, updatedAtPtr *time.Time
 	if !pr.CreatedAt.IsZero() {
 		t := pr.CreatedAt
 		createdAtPtr = &t
 	}
+	if !pr.UpdatedAt.IsZero() {
+		t := pr.UpdatedAt
+		updatedAtPtr = &t
+	}
 
 	return PullRequest{
 		PullRequestID:     pr.PullRequestID,
@@ -46,8 +65,14 @@ func FromPR(pr *pullrequest.PullRequest) PullRequest {
 		AuthorID:          pr.AuthorID,
 		Status:            pr.Status,
 		AssignedReviewers: reviewerIDs,
+		Reviewers:         reviewers,
+		ReviewersRequired: pr.ReviewersRequired,
+		ChangedPaths:      pr.ChangedPaths,
+		Labels:            labelsOrEmpty(pr.Labels),
 		CreatedAt:         createdAtPtr,
+		UpdatedAt:         updatedAtPtr,
 		MergedAt:          pr.MergedAt,
+		ClosedAt:          pr.ClosedAt,
 	}
 }
 
@@ -59,6 +84,22 @@ func FromPRs(prs []*pullrequest.PullRequest) []PullRequest {
 	return out
 }
 
+// PRPage - страница /pullRequest/list, next_cursor пустой на последней странице
+type PRPage struct {
+	PullRequests []PullRequest `json:"pull_requests"`
+	NextCursor   string        `json:"next_cursor"`
+}
+
+func FromPRPage(page *pullrequest.PRPage) PRPage {
+	if page == nil {
+		return PRPage{PullRequests: []PullRequest{}}
+	}
+	return PRPage{
+		PullRequests: FromPRs(page.PullRequests),
+		NextCursor:   page.NextCursor,
+	}
+}
+
 func FromPRToShort(pr *pullrequest.PullRequest) PRShort {
 	if pr == nil {
 		return PRShort{}
@@ -68,7 +109,16 @@ func FromPRToShort(pr *pullrequest.PullRequest) PRShort {
 		PullRequestName: pr.PullRequestName,
 		AuthorID:        pr.AuthorID,
 		Status:          pr.Status,
+		Labels:          labelsOrEmpty(pr.Labels),
+	}
+}
+
+// labelsOrEmpty - в ответе всегда массив, а не null
+func labelsOrEmpty(labels []string) []string {
+	if labels == nil {
+		return []string{}
 	}
+	return labels
 }
 
 func FromPRsToShort(prs []*pullrequest.PullRequest) []PRShort {
@@ -78,3 +128,66 @@ func FromPRsToShort(prs []*pullrequest.PullRequest) []PRShort {
 	}
 	return out
 }
+
+type MovedReview struct {
+	PullRequestID string   `json:"pull_request_id"`
+	FromUserID    string   `json:"from_user_id"`
+	ReplacedBy    []string `json:"replaced_by"`
+}
+
+type ShortPR struct {
+	PullRequestID string `json:"pull_request_id"`
+	Missing       int    `json:"missing"`
+	Reason        string `json:"reason"`
+}
+
+// ReleaseReport - что стало с открытыми ревью деактивированных пользователей
+type ReleaseReport struct {
+	Moved []MovedReview `json:"moved"`
+	Short []ShortPR     `json:"short"`
+}
+
+func FromReleaseReport(r *pullrequest.ReleaseReport) *ReleaseReport {
+	if r == nil {
+		return nil
+	}
+
+	out := &ReleaseReport{
+		Moved: make([]MovedReview, 0, len(r.Moved)),
+		Short: make([]ShortPR, 0, len(r.Short)),
+	}
+	for _, m := range r.Moved {
+		out.Moved = append(out.Moved, MovedReview{
+			PullRequestID: m.PullRequestID,
+			FromUserID:    m.FromUserID,
+			ReplacedBy:    m.ReplacedBy,
+		})
+	}
+	for _, s := range r.Short {
+		out.Short = append(out.Short, ShortPR{
+			PullRequestID: s.PullRequestID,
+			Missing:       s.Missing,
+			Reason:        s.Reason,
+		})
+	}
+	return out
+}
+
+type Review struct {
+	PullRequestID string    `json:"pull_request_id"`
+	ReviewerID    string    `json:"reviewer_id"`
+	Verdict       string    `json:"verdict"`
+	SubmittedAt   time.Time `json:"submittedAt"`
+}
+
+func FromReview(r *pullrequest.Review) Review {
+	if r == nil {
+		return Review{}
+	}
+	return Review{
+		PullRequestID: r.PullRequestID,
+		ReviewerID:    r.UserID,
+		Verdict:       r.Verdict,
+		SubmittedAt:   r.CreatedAt,
+	}
+}
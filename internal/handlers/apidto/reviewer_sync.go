@@ -0,0 +1,41 @@
+package apidto
+
+import (
+	"assignerPR/internal/reviewersync"
+	"time"
+)
+
+type ReviewerSync struct {
+	PullRequestID string     `json:"pull_request_id"`
+	Provider      string     `json:"provider"`
+	Repo          string     `json:"repo"`
+	Number        int64      `json:"number"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	Reviewers     []string   `json:"reviewers"`
+	Unmapped      []string   `json:"unmapped_reviewers,omitempty"`
+	SyncedAt      *time.Time `json:"syncedAt,omitempty"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+func FromPRSync(s *reviewersync.PRSync) ReviewerSync {
+	reviewers := s.Reviewers
+	if reviewers == nil {
+		reviewers = []string{}
+	}
+
+	return ReviewerSync{
+		PullRequestID: s.PullRequestID,
+		Provider:      s.Provider,
+		Repo:          s.Repo,
+		Number:        s.Number,
+		Status:        s.Status,
+		Attempts:      s.Attempts,
+		LastError:     s.LastError,
+		Reviewers:     reviewers,
+		Unmapped:      s.Unmapped,
+		SyncedAt:      s.SyncedAt,
+		UpdatedAt:     s.UpdatedAt,
+	}
+}
@@ -51,6 +51,38 @@ func ToTeam(dto Team) *team.Team {
 	}
 }
 
+type PathRule struct {
+	Pattern string   `json:"pattern"`
+	Owners  []string `json:"owners"`
+}
+
+// FromPathRules - строки с одинаковой позицией схлопываются обратно в одно правило с несколькими владельцами
+func FromPathRules(rules []*team.PathRule) []PathRule {
+	out := make([]PathRule, 0, len(rules))
+	for i, r := range rules {
+		if i == 0 || rules[i-1].Position != r.Position {
+			out = append(out, PathRule{Pattern: r.Pattern, Owners: []string{}})
+		}
+		last := &out[len(out)-1]
+		last.Owners = append(last.Owners, r.UserID)
+	}
+	return out
+}
+
+func ToPathRules(dtos []PathRule) []*team.PathRule {
+	out := make([]*team.PathRule, 0, len(dtos))
+	for i, dto := range dtos {
+		for _, owner := range dto.Owners {
+			out = append(out, &team.PathRule{
+				Position: i,
+				Pattern:  dto.Pattern,
+				UserID:   owner,
+			})
+		}
+	}
+	return out
+}
+
 type UserStats struct {
 	OpenCount   int `json:"open_count"`
 	MergedCount int `json:"merged_count"`
@@ -1,12 +1,18 @@
 package apidto
 
-import "assignerPR/pkg/user"
+import (
+	"assignerPR/pkg/user"
+	"time"
+)
 
 type User struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	TeamName string `json:"team_name"`
 	IsActive bool   `json:"is_active"`
+
+	MaxOpenReviews *int       `json:"max_open_reviews,omitempty"`
+	OffboardedAt   *time.Time `json:"offboarded_at,omitempty"`
 }
 
 func FromUser(u *user.User) User {
@@ -18,6 +24,9 @@ func FromUser(u *user.User) User {
 		Username: u.Username,
 		TeamName: u.TeamName,
 		IsActive: u.IsActive,
+
+		MaxOpenReviews: u.MaxOpenReviews,
+		OffboardedAt:   u.OffboardedAt,
 	}
 }
 
@@ -35,6 +44,8 @@ func ToUser(dto User) *user.User {
 		Username: dto.Username,
 		TeamName: dto.TeamName,
 		IsActive: dto.IsActive,
+
+		MaxOpenReviews: dto.MaxOpenReviews,
 	}
 }
 
@@ -45,3 +56,30 @@ func ToUsers(dtos []User) []*user.User {
 	}
 	return out
 }
+
+type Unavailability struct {
+	ID       uint64    `json:"id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+func FromUnavailability(w *user.Unavailability) Unavailability {
+	if w == nil {
+		return Unavailability{}
+	}
+	return Unavailability{
+		ID:       w.ID,
+		StartsAt: w.StartsAt,
+		EndsAt:   w.EndsAt,
+		Reason:   w.Reason,
+	}
+}
+
+func FromUnavailabilities(windows []*user.Unavailability) []Unavailability {
+	out := make([]Unavailability, 0, len(windows))
+	for _, w := range windows {
+		out = append(out, FromUnavailability(w))
+	}
+	return out
+}
@@ -2,6 +2,7 @@ package apierr
 
 import (
 	"assignerPR/internal/pullrequest"
+	"assignerPR/internal/reviewersync"
 	"assignerPR/pkg/team"
 	"assignerPR/pkg/user"
 	"errors"
@@ -26,16 +27,50 @@ func Map(err error) (int, APIError, bool) {
 	switch {
 	case errors.Is(err, team.ErrTeamExists):
 		return http.StatusBadRequest, TeamExists, true
+	case errors.Is(err, pullrequest.ErrInvalidCursor),
+		errors.Is(err, user.ErrInvalidCursor):
+		return http.StatusBadRequest, InvalidCursor, true
 
 	case errors.Is(err, pullrequest.ErrPRNotFound),
 		errors.Is(err, user.ErrUserNotFound),
+		errors.Is(err, user.ErrUnavailabilityNotFound),
+		errors.Is(err, user.ErrIdentityNotFound),
+		errors.Is(err, team.ErrTeamNotFound),
+		errors.Is(err, reviewersync.ErrSyncNotFound),
 		errors.Is(err, gorm.ErrRecordNotFound):
 		return http.StatusNotFound, NotFound, true
 
+	case errors.Is(err, team.ErrTeamNotEmpty):
+		return http.StatusConflict, TeamNotEmpty, true
+	case errors.Is(err, team.ErrMemberHasPRs):
+		return http.StatusConflict, MemberHasPRs, true
+	case errors.Is(err, team.ErrMemberHasReviews):
+		return http.StatusConflict, MemberHasReviews, true
+	case errors.Is(err, team.ErrMemberOfOtherTeam):
+		return http.StatusConflict, MemberOfOtherTeam, true
+	case errors.Is(err, user.ErrUserOffboarded):
+		return http.StatusConflict, UserOffboarded, true
+
 	case errors.Is(err, pullrequest.ErrPRExists):
 		return http.StatusConflict, PRExists, true
 	case errors.Is(err, pullrequest.ErrPRMerged):
 		return http.StatusConflict, PRMerged, true
+	case errors.Is(err, pullrequest.ErrPRClosed):
+		return http.StatusConflict, PRClosed, true
+	case errors.Is(err, pullrequest.ErrPRDraft):
+		return http.StatusConflict, PRDraft, true
+	case errors.Is(err, pullrequest.ErrMergeBlocked):
+		return http.StatusConflict, MergeBlocked, true
+	case errors.Is(err, pullrequest.ErrAlreadyAssigned):
+		return http.StatusConflict, AlreadyAssigned, true
+	case errors.Is(err, pullrequest.ErrReviewerIsAuthor):
+		return http.StatusConflict, ReviewerIsAuthor, true
+	case errors.Is(err, pullrequest.ErrReviewerInactive):
+		return http.StatusConflict, ReviewerInactive, true
+	case errors.Is(err, pullrequest.ErrAuthorInactive):
+		return http.StatusConflict, AuthorInactive, true
+	case errors.Is(err, pullrequest.ErrReviewerNotInTeam):
+		return http.StatusConflict, ReviewerNotInTeam, true
 	case errors.Is(err, pullrequest.ErrNotAssigned):
 		return http.StatusConflict, NotAssigned, true
 	case errors.Is(err, pullrequest.ErrNoCandidate):
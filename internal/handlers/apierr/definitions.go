@@ -5,6 +5,10 @@ var (
 		Code:    "INVALID_REQUEST",
 		Message: "invalid request body",
 	}
+	InvalidCursor = APIError{
+		Code:    "INVALID_CURSOR",
+		Message: "cursor is malformed or was issued for a different sort",
+	}
 	Unauthorized = APIError{
 		Code:    "UNAUTHORIZED_REQUEST",
 		Message: "unauthorized request",
@@ -21,6 +25,26 @@ var (
 		Code:    "TEAM_EXISTS",
 		Message: "team_name already exists",
 	}
+	TeamNotEmpty = APIError{
+		Code:    "TEAM_NOT_EMPTY",
+		Message: "team still has members, move or remove them first",
+	}
+	MemberHasPRs = APIError{
+		Code:    "MEMBER_HAS_PRS",
+		Message: "user has authored PRs, move or deactivate them instead",
+	}
+	MemberHasReviews = APIError{
+		Code:    "MEMBER_HAS_REVIEWS",
+		Message: "user has review history, deactivate or offboard them instead",
+	}
+	MemberOfOtherTeam = APIError{
+		Code:    "MEMBER_OF_OTHER_TEAM",
+		Message: "user belongs to another team, use move instead",
+	}
+	UserOffboarded = APIError{
+		Code:    "USER_OFFBOARDED",
+		Message: "user is offboarded and cannot be reactivated",
+	}
 	PRExists = APIError{
 		Code:    "PR_EXISTS",
 		Message: "PR id already exists",
@@ -29,6 +53,38 @@ var (
 		Code:    "PR_MERGED",
 		Message: "cannot reassign on merged PR",
 	}
+	PRClosed = APIError{
+		Code:    "PR_CLOSED",
+		Message: "PR is closed",
+	}
+	PRDraft = APIError{
+		Code:    "PR_DRAFT",
+		Message: "PR is a draft",
+	}
+	MergeBlocked = APIError{
+		Code:    "MERGE_BLOCKED",
+		Message: "not enough approvals or changes requested",
+	}
+	AlreadyAssigned = APIError{
+		Code:    "ALREADY_ASSIGNED",
+		Message: "reviewer is already assigned to this PR",
+	}
+	ReviewerIsAuthor = APIError{
+		Code:    "REVIEWER_IS_AUTHOR",
+		Message: "author cannot review own PR",
+	}
+	ReviewerInactive = APIError{
+		Code:    "REVIEWER_INACTIVE",
+		Message: "reviewer is not active",
+	}
+	AuthorInactive = APIError{
+		Code:    "AUTHOR_INACTIVE",
+		Message: "new author is not active",
+	}
+	ReviewerNotInTeam = APIError{
+		Code:    "REVIEWER_NOT_IN_TEAM",
+		Message: "reviewer must be in the author's team or one of its fallback teams",
+	}
 	NotAssigned = APIError{
 		Code:    "NOT_ASSIGNED",
 		Message: "reviewer is not assigned to this PR",
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"assignerPR/internal/handlers/apidto"
+	"assignerPR/internal/handlers/apierr"
+	"assignerPR/internal/pullrequest"
+	"assignerPR/pkg/user"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// offboardReq - без transfer_to открытые и черновые PR уволенного закрываются, остальные остаются за ним
+type offboardReq struct {
+	UserID     string `json:"user_id" binding:"required"`
+	TransferTo string `json:"transfer_to" binding:"omitempty,nefield=UserID"`
+	Anonymize  bool   `json:"anonymize"`
+}
+
+type authoredPRsResp struct {
+	Transferred []string `json:"transferred"`
+	Closed      []string `json:"closed"`
+	Kept        []string `json:"kept"`
+}
+
+type offboardRemovedResp struct {
+	PathRules        int64 `json:"path_rules"`
+	Unavailabilities int64 `json:"unavailabilities"`
+	Identities       int64 `json:"identities"`
+}
+
+type offboardResp struct {
+	User         apidto.User           `json:"user"`
+	Deleted      bool                  `json:"deleted"`
+	Tombstoned   bool                  `json:"tombstoned"`
+	Anonymized   bool                  `json:"anonymized"`
+	TransferTo   string                `json:"transfer_to,omitempty"`
+	Reassignment *apidto.ReleaseReport `json:"reassignment"`
+	AuthoredPRs  authoredPRsResp       `json:"authored_prs"`
+	Removed      offboardRemovedResp   `json:"removed"`
+}
+
+// Offboard - увольнение одной транзакцией: деактивация, снятие с ревью, передача или закрытие своих PR,
+// затем удаление или надгробие. При ошибке не меняется ничего
+func (h *UserHandler) Offboard(c *gin.Context) {
+	var req offboardReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	var report *pullrequest.ReleaseReport
+	var result *user.OffboardResult
+	prs := authoredPRsResp{Transferred: []string{}, Closed: []string{}, Kept: []string{}}
+	err := h.inTx(func(repos Repos) error {
+		if req.TransferTo != "" {
+			target, err := repos.Users.GetUser(req.TransferTo)
+			if err != nil {
+				return err
+			}
+			// PR не передаются неактивным и уволенным: их никто не будет вести
+			if !target.IsActive || target.OffboardedAt != nil {
+				h.logger.Warnw("transfer target is inactive", "userID", req.UserID, "transferTo", req.TransferTo)
+				return pullrequest.ErrAuthorInactive
+			}
+		}
+
+		if _, err := repos.Users.SetIsActive(req.UserID, false); err != nil {
+			return err
+		}
+
+		var err error
+		report, err = repos.PRs.ReleaseReviews([]string{req.UserID})
+		if err != nil {
+			return err
+		}
+
+		authored, err := authoredPRs(repos.PRs, req.UserID)
+		if err != nil {
+			return err
+		}
+
+		for _, pr := range authored {
+			switch {
+			case req.TransferTo != "" && pr.Status != pullrequest.StatusMerged:
+				if _, err := repos.PRs.Update(pr.PullRequestID, pullrequest.PRUpdate{AuthorID: &req.TransferTo}); err != nil {
+					return err
+				}
+				prs.Transferred = append(prs.Transferred, pr.PullRequestID)
+			case req.TransferTo == "" && (pr.Status == pullrequest.StatusOpen || pr.Status == pullrequest.StatusDraft):
+				if _, err := repos.PRs.Close(pr.PullRequestID); err != nil {
+					return err
+				}
+				prs.Closed = append(prs.Closed, pr.PullRequestID)
+			default:
+				prs.Kept = append(prs.Kept, pr.PullRequestID)
+			}
+		}
+
+		result, err = repos.Users.Offboard(req.UserID, req.Anonymize)
+		return err
+	})
+	if err != nil {
+		h.handleOffboardErr(c, req.UserID, err)
+		return
+	}
+
+	h.logger.Infow("user offboarded", "userID", req.UserID, "deleted", result.Deleted,
+		"transferred", len(prs.Transferred), "closed", len(prs.Closed), "kept", len(prs.Kept))
+	c.JSON(http.StatusOK, offboardResp{
+		User:         apidto.FromUser(result.User),
+		Deleted:      result.Deleted,
+		Tombstoned:   !result.Deleted,
+		Anonymized:   result.Anonymized,
+		TransferTo:   req.TransferTo,
+		Reassignment: apidto.FromReleaseReport(report),
+		AuthoredPRs:  prs,
+		Removed: offboardRemovedResp{
+			PathRules:        result.PathRulesDeleted,
+			Unavailabilities: result.UnavailabilitiesDeleted,
+			Identities:       result.IdentitiesDeleted,
+		},
+	})
+}
+
+// authoredPRs - все PR пользователя целиком, до изменений: передача убирает PR из выборки по автору
+func authoredPRs(prRepo pullrequest.PullRequestsRepo, userID string) ([]*pullrequest.PullRequest, error) {
+	var out []*pullrequest.PullRequest
+	filter := pullrequest.PRFilter{AuthorID: userID, Limit: pullrequest.MaxListLimit}
+	for {
+		page, err := prRepo.ListPRs(filter)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page.PullRequests...)
+		if page.NextCursor == "" {
+			return out, nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+func (h *UserHandler) handleOffboardErr(c *gin.Context, userID string, err error) {
+	if apierr.Handle(c, err) {
+		h.logger.Warnw("mapped error offboarding user", "userID", userID, "error", err)
+		return
+	}
+	h.logger.Errorw("error offboarding user", "userID", userID, "err", err)
+	apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+}
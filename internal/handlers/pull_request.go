@@ -5,6 +5,7 @@ import (
 	"assignerPR/internal/handlers/apierr"
 	"assignerPR/internal/pullrequest"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -26,12 +27,25 @@ type createPRReq struct {
 	PullRequestID   string `json:"pull_request_id" binding:"required"`
 	PullRequestName string `json:"pull_request_name" binding:"required"`
 	AuthorID        string `json:"author_id" binding:"required"`
+
+	ReviewersRequired int      `json:"reviewers_required" binding:"omitempty,min=1,max=10"`
+	ChangedPaths      []string `json:"changed_paths"`
+	Labels            []string `json:"labels" binding:"omitempty,dive,required,max=64"`
+	IsDraft           bool     `json:"is_draft"`
 }
 
 type prResp struct {
 	PR apidto.PullRequest `json:"pr"`
 }
 
+// createPRResp - PartialAssignment выставляется, если в команде (и запасных) не нашлось достаточно
+// свободных ревьюверов: кто-то недоступен или уже выбрал лимит открытых ревью
+type createPRResp struct {
+	PR                apidto.PullRequest `json:"pr"`
+	PartialAssignment bool               `json:"partial_assignment"`
+	MissingReviewers  int                `json:"missing_reviewers"`
+}
+
 func (h *PullRequestHandler) CreatePR(c *gin.Context) {
 	var req createPRReq
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -40,7 +54,12 @@ func (h *PullRequestHandler) CreatePR(c *gin.Context) {
 		return
 	}
 
-	pr, err := h.repo.CreatePR(req.PullRequestID, req.PullRequestName, req.AuthorID)
+	pr, err := h.repo.CreatePR(req.PullRequestID, req.PullRequestName, req.AuthorID, pullrequest.CreatePROptions{
+		ReviewersRequired: req.ReviewersRequired,
+		ChangedPaths:      req.ChangedPaths,
+		Labels:            req.Labels,
+		IsDraft:           req.IsDraft,
+	})
 	if err != nil {
 		if apierr.Handle(c, err) {
 			h.logger.Warnw("mapped error creating pull request", "error", err)
@@ -52,9 +71,43 @@ func (h *PullRequestHandler) CreatePR(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, prResp{
-		apidto.FromPR(pr),
-	})
+	c.JSON(http.StatusCreated, toAssignmentResp(pr))
+}
+
+// toAssignmentResp - у черновика ревьюверов нет намеренно, это не частичное назначение
+func toAssignmentResp(pr *pullrequest.PullRequest) createPRResp {
+	missing := 0
+	if pr.Status != pullrequest.StatusDraft {
+		missing = pr.MissingReviewers()
+	}
+
+	return createPRResp{
+		PR:                apidto.FromPR(pr),
+		PartialAssignment: missing > 0,
+		MissingReviewers:  missing,
+	}
+}
+
+func (h *PullRequestHandler) Ready(c *gin.Context) {
+	var req prIDReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	pr, err := h.repo.Ready(req.PullRequestID)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error marking pull request ready", "error", err)
+			return
+		}
+		h.logger.Errorw("Ready failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, toAssignmentResp(pr))
 }
 
 type mergePRReq struct {
@@ -89,14 +142,281 @@ func (h *PullRequestHandler) Merge(c *gin.Context) {
 	})
 }
 
+type prIDReq struct {
+	PullRequestID string `json:"pull_request_id" binding:"required"`
+}
+
+func (h *PullRequestHandler) Close(c *gin.Context) {
+	var req prIDReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	pr, err := h.repo.Close(req.PullRequestID)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error closing pull request", "error", err)
+			return
+		}
+		h.logger.Errorw("Close failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, prResp{
+		apidto.FromPR(pr),
+	})
+}
+
+func (h *PullRequestHandler) Reopen(c *gin.Context) {
+	var req prIDReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	pr, err := h.repo.Reopen(req.PullRequestID)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error reopening pull request", "error", err)
+			return
+		}
+		h.logger.Errorw("Reopen failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, prResp{
+		apidto.FromPR(pr),
+	})
+}
+
+type reviewReq struct {
+	PullRequestID string `json:"pull_request_id" binding:"required"`
+	ReviewerID    string `json:"reviewer_id" binding:"required"`
+	Verdict       string `json:"verdict" binding:"required"`
+}
+
+type reviewResp struct {
+	Review apidto.Review `json:"review"`
+}
+
+func (h *PullRequestHandler) Review(c *gin.Context) {
+	var req reviewReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	if !pullrequest.KnownVerdict(req.Verdict) {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("unknown verdict", "verdict", req.Verdict)
+		return
+	}
+
+	review, err := h.repo.SubmitReview(req.PullRequestID, req.ReviewerID, req.Verdict)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error submitting review", "error", err)
+			return
+		}
+		h.logger.Errorw("Review failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reviewResp{
+		Review: apidto.FromReview(review),
+	})
+}
+
+type reviewerReq struct {
+	PullRequestID string `json:"pull_request_id" binding:"required"`
+	ReviewerID    string `json:"reviewer_id" binding:"required"`
+}
+
+func (h *PullRequestHandler) AddReviewer(c *gin.Context) {
+	var req reviewerReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	pr, err := h.repo.AddReviewer(req.PullRequestID, req.ReviewerID)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error adding reviewer", "error", err)
+			return
+		}
+		h.logger.Errorw("AddReviewer failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, prResp{
+		apidto.FromPR(pr),
+	})
+}
+
+func (h *PullRequestHandler) RemoveReviewer(c *gin.Context) {
+	var req reviewerReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	pr, err := h.repo.RemoveReviewer(req.PullRequestID, req.ReviewerID)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error removing reviewer", "error", err)
+			return
+		}
+		h.logger.Errorw("RemoveReviewer failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, prResp{
+		apidto.FromPR(pr),
+	})
+}
+
+func (h *PullRequestHandler) GetPR(c *gin.Context) {
+	prID := c.Query("pull_request_id")
+	if prID == "" {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("no pull_request_id provided")
+		return
+	}
+
+	pr, err := h.repo.GetPR(prID)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error getting pull request", "error", err)
+			return
+		}
+
+		h.logger.Errorw("GetPR failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, prResp{
+		PR: apidto.FromPR(pr),
+	})
+}
+
+// listPRsReq - все фильтры необязательные, время в RFC3339, label можно повторять
+type listPRsReq struct {
+	Status      string     `form:"status" binding:"omitempty,oneof=OPEN MERGED CLOSED DRAFT"`
+	AuthorID    string     `form:"author_id"`
+	ReviewerID  string     `form:"reviewer_id"`
+	TeamName    string     `form:"team_name"`
+	Labels      []string   `form:"label"`
+	CreatedFrom *time.Time `form:"created_from"`
+	CreatedTo   *time.Time `form:"created_to"`
+	MergedFrom  *time.Time `form:"merged_from"`
+	MergedTo    *time.Time `form:"merged_to"`
+
+	SortBy string `form:"sort_by" binding:"omitempty,oneof=created_at updated_at"`
+	Order  string `form:"order" binding:"omitempty,oneof=asc desc"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+func (h *PullRequestHandler) ListPRs(c *gin.Context) {
+	var req listPRsReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	page, err := h.repo.ListPRs(pullrequest.PRFilter{
+		Status:      req.Status,
+		AuthorID:    req.AuthorID,
+		ReviewerID:  req.ReviewerID,
+		TeamName:    req.TeamName,
+		Labels:      req.Labels,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		MergedFrom:  req.MergedFrom,
+		MergedTo:    req.MergedTo,
+		SortBy:      req.SortBy,
+		Order:       req.Order,
+		Cursor:      req.Cursor,
+		Limit:       req.Limit,
+	})
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error listing pull requests", "error", err)
+			return
+		}
+
+		h.logger.Errorw("ListPRs failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, apidto.FromPRPage(page))
+}
+
+// updatePRReq - nil поля не меняются, labels заменяются целиком
+type updatePRReq struct {
+	PullRequestID   string    `json:"pull_request_id" binding:"required"`
+	PullRequestName *string   `json:"pull_request_name" binding:"omitempty,min=1,max=255"`
+	Labels          *[]string `json:"labels" binding:"omitempty,dive,required,max=64"`
+	AuthorID        *string   `json:"author_id" binding:"omitempty,min=1"`
+}
+
+func (h *PullRequestHandler) Update(c *gin.Context) {
+	var req updatePRReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	pr, err := h.repo.Update(req.PullRequestID, pullrequest.PRUpdate{
+		Name:     req.PullRequestName,
+		Labels:   req.Labels,
+		AuthorID: req.AuthorID,
+	})
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error updating pull request", "error", err)
+			return
+		}
+
+		h.logger.Errorw("Update failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, prResp{
+		PR: apidto.FromPR(pr),
+	})
+}
+
 type reassignPRReq struct {
 	PullRequestID string `json:"pull_request_id" binding:"required"`
 	OldReviewerID string `json:"old_reviewer_id" binding:"required"`
+	// NewReviewerID - если задан, назначается именно он вместо случайного выбора
+	NewReviewerID string `json:"new_reviewer_id"`
 }
 
+// reassignPRResp - replaced_by встал на место старого ревьювера, added_reviewers - все добавленные,
+// включая тех, кем добрали недоукомплектованный PR
 type reassignPRResp struct {
-	PR         apidto.PullRequest `json:"pr"`
-	ReplacedBy string             `json:"replaced_by"`
+	PR             apidto.PullRequest `json:"pr"`
+	ReplacedBy     string             `json:"replaced_by"`
+	AddedReviewers []string           `json:"added_reviewers"`
 }
 
 func (h *PullRequestHandler) ReassignPR(c *gin.Context) {
@@ -107,7 +427,7 @@ func (h *PullRequestHandler) ReassignPR(c *gin.Context) {
 		return
 	}
 
-	pr, replacedBy, err := h.repo.Reassign(req.PullRequestID, req.OldReviewerID)
+	pr, added, err := h.repo.Reassign(req.PullRequestID, req.OldReviewerID, req.NewReviewerID)
 	if err != nil {
 		if apierr.Handle(c, err) {
 			h.logger.Warnw("mapped error creating pull request", "error", err)
@@ -119,7 +439,8 @@ func (h *PullRequestHandler) ReassignPR(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, reassignPRResp{
-		PR:         apidto.FromPR(pr),
-		ReplacedBy: replacedBy,
+		PR:             apidto.FromPR(pr),
+		ReplacedBy:     added[0],
+		AddedReviewers: added,
 	})
 }
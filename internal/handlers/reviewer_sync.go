@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"assignerPR/internal/handlers/apidto"
+	"assignerPR/internal/handlers/apierr"
+	"assignerPR/internal/reviewersync"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ReviewerSyncHandler struct {
+	syncer *reviewersync.Syncer
+	logger *zap.SugaredLogger
+}
+
+func NewReviewerSyncHandler(logger *zap.SugaredLogger, syncer *reviewersync.Syncer) *ReviewerSyncHandler {
+	return &ReviewerSyncHandler{
+		syncer: syncer,
+		logger: logger,
+	}
+}
+
+type reviewerSyncResp struct {
+	Sync apidto.ReviewerSync `json:"sync"`
+}
+
+type resyncReq struct {
+	PullRequestID string `json:"pull_request_id" binding:"required"`
+}
+
+func (h *ReviewerSyncHandler) GetSync(c *gin.Context) {
+	prID := c.Query("pull_request_id")
+	if prID == "" {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("no pull_request_id provided")
+		return
+	}
+
+	s, err := h.syncer.GetSync(prID)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error getting reviewer sync", "error", err)
+			return
+		}
+
+		h.logger.Errorw("GetSync failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, reviewerSyncResp{
+		Sync: apidto.FromPRSync(s),
+	})
+}
+
+// Resync - повторная отправка после FAILED (например, выдали токену права или завели логины).
+// Отправка идет в фоне, в ответе статус на момент постановки в очередь
+func (h *ReviewerSyncHandler) Resync(c *gin.Context) {
+	var req resyncReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	s, err := h.syncer.GetSync(req.PullRequestID)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error getting reviewer sync", "error", err)
+			return
+		}
+
+		h.logger.Errorw("Resync failed, couldnt map the error", "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	h.syncer.Notify(req.PullRequestID)
+	c.JSON(http.StatusAccepted, reviewerSyncResp{
+		Sync: apidto.FromPRSync(s),
+	})
+}
@@ -7,6 +7,8 @@ import (
 	"assignerPR/pkg/team"
 	"assignerPR/pkg/user"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -15,6 +17,7 @@ import (
 type TeamHandler struct {
 	prRepo    pullrequest.PullRequestsRepo
 	teamsRepo team.TeamsRepo
+	inTx      InTx
 	logger    *zap.SugaredLogger
 }
 
@@ -22,10 +25,12 @@ func NewTeamHandler(
 	logger *zap.SugaredLogger,
 	teamsRepo team.TeamsRepo,
 	prRepo pullrequest.PullRequestsRepo,
+	inTx InTx,
 ) *TeamHandler {
 	return &TeamHandler{
 		prRepo:    prRepo,
 		teamsRepo: teamsRepo,
+		inTx:      inTx,
 		logger:    logger,
 	}
 }
@@ -173,3 +178,452 @@ func (h *TeamHandler) StatsTeam(c *gin.Context) {
 		TeamStats: userStatsMap,
 	})
 }
+
+type teamSettingsReq struct {
+	TeamName          string    `json:"team_name" binding:"required"`
+	SelectionStrategy *string   `json:"selection_strategy"`
+	ReviewersRequired *int      `json:"reviewers_required" binding:"omitempty,min=1,max=10"`
+	MaxOpenReviews    *int      `json:"max_open_reviews" binding:"omitempty,min=0"`
+	RequiredApprovals *int      `json:"required_approvals" binding:"omitempty,min=0,max=10"`
+	FallbackTeams     *[]string `json:"fallback_teams" binding:"omitempty,unique,dive,required"`
+}
+
+type teamSettingsResp struct {
+	TeamName          string   `json:"team_name"`
+	SelectionStrategy string   `json:"selection_strategy"`
+	ReviewersRequired int      `json:"reviewers_required"`
+	MaxOpenReviews    int      `json:"max_open_reviews"`
+	RequiredApprovals int      `json:"required_approvals"`
+	FallbackTeams     []string `json:"fallback_teams"`
+}
+
+func toFallbackNames(fallbacks []*team.Fallback) []string {
+	res := make([]string, 0, len(fallbacks))
+	for _, fb := range fallbacks {
+		res = append(res, fb.FallbackTeamName)
+	}
+	return res
+}
+
+func (h *TeamHandler) UpdateSettings(c *gin.Context) {
+	var req teamSettingsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	if req.SelectionStrategy != nil && !pullrequest.KnownStrategy(*req.SelectionStrategy) {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("unknown selection strategy", "strategy", *req.SelectionStrategy)
+		return
+	}
+
+	if req.FallbackTeams != nil && slices.Contains(*req.FallbackTeams, req.TeamName) {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("team cannot fall back to itself", "teamName", req.TeamName)
+		return
+	}
+
+	updated, err := h.teamsRepo.UpdateSettings(req.TeamName, team.Settings{
+		SelectionStrategy: req.SelectionStrategy,
+		ReviewersRequired: req.ReviewersRequired,
+		MaxOpenReviews:    req.MaxOpenReviews,
+		RequiredApprovals: req.RequiredApprovals,
+		FallbackTeams:     req.FallbackTeams,
+	})
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error updating team settings", "error", err)
+			return
+		}
+		h.logger.Errorw("error updating team settings", "error", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, teamSettingsResp{
+		TeamName:          updated.TeamName,
+		SelectionStrategy: updated.SelectionStrategy,
+		ReviewersRequired: updated.ReviewersRequired,
+		MaxOpenReviews:    updated.MaxOpenReviews,
+		RequiredApprovals: updated.RequiredApprovals,
+		FallbackTeams:     toFallbackNames(updated.Fallbacks),
+	})
+}
+
+type pathRulesReq struct {
+	TeamName string            `json:"team_name" binding:"required"`
+	Rules    []apidto.PathRule `json:"rules" binding:"required,dive"`
+}
+
+type pathRulesResp struct {
+	TeamName string            `json:"team_name"`
+	Rules    []apidto.PathRule `json:"rules"`
+}
+
+func (h *TeamHandler) SetPathRules(c *gin.Context) {
+	var req pathRulesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	for _, r := range req.Rules {
+		if strings.Trim(r.Pattern, "/") == "" || len(r.Owners) == 0 {
+			apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+			h.logger.Warnw("invalid path rule", "pattern", r.Pattern)
+			return
+		}
+	}
+
+	saved, err := h.teamsRepo.SetPathRules(req.TeamName, apidto.ToPathRules(req.Rules))
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error setting path rules", "error", err)
+			return
+		}
+		h.logger.Errorw("error setting path rules", "error", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, pathRulesResp{
+		TeamName: req.TeamName,
+		Rules:    apidto.FromPathRules(saved),
+	})
+}
+
+func (h *TeamHandler) GetPathRules(c *gin.Context) {
+	teamName := c.Query("team_name")
+	if teamName == "" {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("no team name provided")
+		return
+	}
+
+	rules, err := h.teamsRepo.GetPathRules(teamName)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error getting path rules", "error", err)
+			return
+		}
+		h.logger.Errorw("error getting path rules", "error", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, pathRulesResp{
+		TeamName: teamName,
+		Rules:    apidto.FromPathRules(rules),
+	})
+}
+
+type addMembersReq struct {
+	TeamName string          `json:"team_name" binding:"required"`
+	Members  []teamMemberReq `json:"members" binding:"required,min=1,dive"`
+}
+
+type teamResp struct {
+	Team teamWithMembersResp `json:"team"`
+}
+
+func (h *TeamHandler) AddMembers(c *gin.Context) {
+	var req addMembersReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	updated, err := h.teamsRepo.AddMembers(req.TeamName, toDomainUsers(req.TeamName, req.Members))
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error adding members", "error", err)
+			return
+		}
+		h.logger.Errorw("error adding members", "error", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, teamResp{
+		Team: teamWithMembersResp{
+			TeamName: updated.TeamName,
+			Members:  toTeamMembers(updated.Members),
+		},
+	})
+}
+
+type removeMemberReq struct {
+	TeamName string `json:"team_name" binding:"required"`
+	UserID   string `json:"user_id" binding:"required"`
+}
+
+type removeMemberResp struct {
+	TeamName     string                `json:"team_name"`
+	UserID       string                `json:"user_id"`
+	Reassignment *apidto.ReleaseReport `json:"reassignment"`
+}
+
+// RemoveMember - снятие с ревью и удаление одной транзакцией. Ревью снимаются до удаления, поэтому сначала
+// отсекаем то, на чем удаление все равно упадет: не член команды и автор PR
+func (h *TeamHandler) RemoveMember(c *gin.Context) {
+	var req removeMemberReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	var report *pullrequest.ReleaseReport
+	err := h.inTx(func(repos Repos) error {
+		current, err := repos.Teams.GetTeam(req.TeamName)
+		if err != nil {
+			return err
+		}
+		if !slices.ContainsFunc(current.Members, func(u *user.User) bool { return u.UserID == req.UserID }) {
+			return user.ErrUserNotFound
+		}
+
+		authored, err := repos.PRs.ListPRs(pullrequest.PRFilter{AuthorID: req.UserID, Limit: 1})
+		if err != nil {
+			return err
+		}
+		if len(authored.PullRequests) > 0 {
+			return team.ErrMemberHasPRs
+		}
+
+		// деактивация блокирует строку пользователя до конца транзакции и убирает его из кандидатов,
+		// так что параллельное назначение не вернет его на ревью между снятием и удалением
+		if _, err := repos.Users.SetIsActive(req.UserID, false); err != nil {
+			return err
+		}
+
+		report, err = repos.PRs.ReleaseReviews([]string{req.UserID})
+		if err != nil {
+			return err
+		}
+
+		return repos.Teams.RemoveMember(req.TeamName, req.UserID)
+	})
+	if err != nil {
+		h.handleMemberErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, removeMemberResp{
+		TeamName:     req.TeamName,
+		UserID:       req.UserID,
+		Reassignment: apidto.FromReleaseReport(report),
+	})
+}
+
+type moveMemberReq struct {
+	UserID     string `json:"user_id" binding:"required"`
+	ToTeamName string `json:"to_team_name" binding:"required"`
+}
+
+type moveMemberResp struct {
+	User         apidto.User           `json:"user"`
+	FromTeamName string                `json:"from_team_name"`
+	Reassignment *apidto.ReleaseReport `json:"reassignment"`
+}
+
+// MoveMember - перевод и переназначение ревью старой команды одной транзакцией: если переназначить
+// не удалось, перевод откатывается, и повторный запрос снова увидит прежнюю команду
+func (h *TeamHandler) MoveMember(c *gin.Context) {
+	var req moveMemberReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	var moved *user.User
+	var fromTeam string
+	report := &pullrequest.ReleaseReport{Moved: []pullrequest.MovedReview{}, Short: []pullrequest.ShortPR{}}
+	err := h.inTx(func(repos Repos) error {
+		var err error
+		moved, fromTeam, err = repos.Teams.MoveMember(req.UserID, req.ToTeamName)
+		if err != nil || fromTeam == req.ToTeamName {
+			return err
+		}
+		report, err = repos.PRs.ReleaseTeamReviews(req.UserID, fromTeam)
+		return err
+	})
+	if err != nil {
+		h.handleMemberErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, moveMemberResp{
+		User:         apidto.FromUser(moved),
+		FromTeamName: fromTeam,
+		Reassignment: apidto.FromReleaseReport(report),
+	})
+}
+
+type renameTeamReq struct {
+	TeamName    string `json:"team_name" binding:"required"`
+	NewTeamName string `json:"new_team_name" binding:"required,max=64,nefield=TeamName"`
+}
+
+func (h *TeamHandler) RenameTeam(c *gin.Context) {
+	var req renameTeamReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	renamed, err := h.teamsRepo.RenameTeam(req.TeamName, req.NewTeamName)
+	if err != nil {
+		h.handleMemberErr(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, teamResp{
+		Team: teamWithMembersResp{
+			TeamName: renamed.TeamName,
+			Members:  toTeamMembers(renamed.Members),
+		},
+	})
+}
+
+type deleteTeamReq struct {
+	TeamName string `json:"team_name" binding:"required"`
+}
+
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	var req deleteTeamReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	if err := h.teamsRepo.DeleteTeam(req.TeamName); err != nil {
+		h.handleMemberErr(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// handleMemberErr - общий ответ на ошибки управления составом команды
+func (h *TeamHandler) handleMemberErr(c *gin.Context, err error) {
+	if apierr.Handle(c, err) {
+		h.logger.Warnw("mapped error managing team", "error", err)
+		return
+	}
+	h.logger.Errorw("error managing team", "error", err)
+	apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+}
+
+// syncMemberReq - is_active необязателен, по умолчанию пользователь активен
+type syncMemberReq struct {
+	UserID   string `json:"user_id" binding:"required,max=64"`
+	Username string `json:"username" binding:"required,max=255"`
+	IsActive *bool  `json:"is_active"`
+}
+
+type syncTeamReq struct {
+	TeamName string          `json:"team_name" binding:"required,max=64"`
+	Members  []syncMemberReq `json:"members" binding:"required,unique=UserID,dive"`
+}
+
+type movedMemberResp struct {
+	UserID       string `json:"user_id"`
+	FromTeamName string `json:"from_team_name"`
+}
+
+type syncDiffResp struct {
+	Added       []string          `json:"added"`
+	Updated     []string          `json:"updated"`
+	Moved       []movedMemberResp `json:"moved"`
+	Deactivated []string          `json:"deactivated"`
+}
+
+type syncTeamResp struct {
+	Team         teamWithMembersResp   `json:"team"`
+	Created      bool                  `json:"created"`
+	Diff         syncDiffResp          `json:"diff"`
+	Reassignment *apidto.ReleaseReport `json:"reassignment"`
+}
+
+// SyncTeam - идемпотентная выгрузка состава из внешнего справочника. Состав и переназначение ревью
+// деактивированных и переведенных сохраняются одной транзакцией, при ошибке не остается ни того, ни другого
+func (h *TeamHandler) SyncTeam(c *gin.Context) {
+	var req syncTeamReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	members := make([]*user.User, 0, len(req.Members))
+	for _, m := range req.Members {
+		members = append(members, &user.User{
+			UserID:   m.UserID,
+			Username: m.Username,
+			TeamName: req.TeamName,
+			IsActive: m.IsActive == nil || *m.IsActive,
+		})
+	}
+
+	var result *team.SyncResult
+	var report *pullrequest.ReleaseReport
+	err := h.inTx(func(repos Repos) error {
+		var err error
+		result, err = repos.Teams.SyncTeam(req.TeamName, members)
+		if err != nil {
+			return err
+		}
+
+		report, err = repos.PRs.ReleaseReviews(result.Deactivated)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range result.Moved {
+			// с деактивированных уже сняты все ревью
+			if slices.Contains(result.Deactivated, m.UserID) {
+				continue
+			}
+
+			teamReport, err := repos.PRs.ReleaseTeamReviews(m.UserID, m.FromTeam)
+			if err != nil {
+				return err
+			}
+			report.Moved = append(report.Moved, teamReport.Moved...)
+			report.Short = append(report.Short, teamReport.Short...)
+		}
+		return nil
+	})
+	if err != nil {
+		h.handleMemberErr(c, err)
+		return
+	}
+
+	moved := make([]movedMemberResp, 0, len(result.Moved))
+	for _, m := range result.Moved {
+		moved = append(moved, movedMemberResp{UserID: m.UserID, FromTeamName: m.FromTeam})
+	}
+
+	c.JSON(http.StatusOK, syncTeamResp{
+		Team: teamWithMembersResp{
+			TeamName: result.Team.TeamName,
+			Members:  toTeamMembers(result.Team.Members),
+		},
+		Created: result.Created,
+		Diff: syncDiffResp{
+			Added:       result.Added,
+			Updated:     result.Updated,
+			Moved:       moved,
+			Deactivated: result.Deactivated,
+		},
+		Reassignment: apidto.FromReleaseReport(report),
+	})
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"assignerPR/internal/pullrequest"
+	"assignerPR/pkg/team"
+	"assignerPR/pkg/user"
+)
+
+// Repos - репозитории поверх одной транзакции
+type Repos struct {
+	Users user.UsersRepo
+	Teams team.TeamsRepo
+	PRs   pullrequest.PullRequestsRepo
+}
+
+// InTx выполняет fn в одной транзакции БД, ошибка fn ее откатывает. Так изменение состава
+// и снятие ревью коммитятся вместе: после сбоя между ними повторный запрос уже не увидел бы, что снимать
+type InTx func(fn func(repos Repos) error) error
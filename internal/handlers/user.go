@@ -6,6 +6,7 @@ import (
 	"assignerPR/internal/pullrequest"
 	"assignerPR/pkg/user"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -14,6 +15,7 @@ import (
 type UserHandler struct {
 	prRepo   pullrequest.PullRequestsRepo
 	userRepo user.UsersRepo
+	inTx     InTx
 	logger   *zap.SugaredLogger
 }
 
@@ -21,10 +23,12 @@ func NewUserHandler(
 	logger *zap.SugaredLogger,
 	userRepo user.UsersRepo,
 	prRepo pullrequest.PullRequestsRepo,
+	inTx InTx,
 ) *UserHandler {
 	return &UserHandler{
 		prRepo:   prRepo,
 		userRepo: userRepo,
+		inTx:     inTx,
 		logger:   logger,
 	}
 }
@@ -35,7 +39,8 @@ type setActiveReq struct {
 }
 
 type userResp struct {
-	User apidto.User `json:"user"`
+	User         apidto.User           `json:"user"`
+	Reassignment *apidto.ReleaseReport `json:"reassignment,omitempty"`
 }
 
 func (h *UserHandler) SetIsActive(c *gin.Context) {
@@ -47,15 +52,169 @@ func (h *UserHandler) SetIsActive(c *gin.Context) {
 		return
 	}
 
-	usr, err := h.userRepo.SetIsActive(req.UserID, req.IsActive)
+	var usr *user.User
+	var report *pullrequest.ReleaseReport
+	err := h.inTx(func(repos Repos) error {
+		var err error
+		usr, err = repos.Users.SetIsActive(req.UserID, req.IsActive)
+		if err != nil || usr.IsActive {
+			return err
+		}
+		report, err = repos.PRs.ReleaseReviews([]string{usr.UserID})
+		return err
+	})
+	if err != nil {
+		h.handleUserErr(c, req.UserID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResp{
+		User:         apidto.FromUser(usr),
+		Reassignment: apidto.FromReleaseReport(report),
+	})
+}
+
+// handleUserErr - общий ответ на ошибки деактивации вместе со снятием ревью
+func (h *UserHandler) handleUserErr(c *gin.Context, userID string, err error) {
+	if apierr.Handle(c, err) {
+		h.logger.Warnw("mapped error updating user", "userID", userID, "error", err)
+		return
+	}
+	h.logger.Errorw("error updating user", "userID", userID, "err", err)
+	apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+}
+
+func (h *UserHandler) GetUser(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("no user id provided")
+		return
+	}
+
+	usr, err := h.userRepo.GetUser(userID)
 	if err != nil {
 		if apierr.Handle(c, err) {
-			h.logger.Warnw("error setting user", "error", err)
+			h.logger.Warnw("mapped error getting user", "error", err)
 			return
 		}
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		h.logger.Errorw("error getting user", "userID", userID, "err", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userResp{
+		User: apidto.FromUser(usr),
+	})
+}
+
+type listUsersReq struct {
+	TeamName string `form:"team_name"`
+	IsActive *bool  `form:"is_active"`
+	Search   string `form:"search" binding:"max=255"`
+	Cursor   string `form:"cursor"`
+	Limit    int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type listUsersResp struct {
+	Users      []apidto.User `json:"users"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	var req listUsersReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	page, err := h.userRepo.ListUsers(user.UserFilter{
+		TeamName: req.TeamName,
+		IsActive: req.IsActive,
+		Search:   req.Search,
+		Cursor:   req.Cursor,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error listing users", "error", err)
+			return
+		}
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		h.logger.Errorw("error listing users", "err", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listUsersResp{
+		Users:      apidto.FromUsers(page.Users),
+		NextCursor: page.NextCursor,
+	})
+}
+
+// updateUserReq - отсутствующие поля не меняются
+type updateUserReq struct {
+	UserID   string  `json:"user_id" binding:"required"`
+	Username *string `json:"username" binding:"omitempty,min=1,max=255"`
+	IsActive *bool   `json:"is_active"`
+}
+
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	var req updateUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	var usr *user.User
+	var report *pullrequest.ReleaseReport
+	err := h.inTx(func(repos Repos) error {
+		var err error
+		usr, err = repos.Users.UpdateUser(req.UserID, user.UserUpdate{
+			Username: req.Username,
+			IsActive: req.IsActive,
+		})
+		// как и в SetIsActive, ревью снимаются только при явной деактивации
+		if err != nil || req.IsActive == nil || usr.IsActive {
+			return err
+		}
+		report, err = repos.PRs.ReleaseReviews([]string{usr.UserID})
+		return err
+	})
+	if err != nil {
+		h.handleUserErr(c, req.UserID, err)
+		return
+	}
 
+	c.JSON(http.StatusOK, userResp{
+		User:         apidto.FromUser(usr),
+		Reassignment: apidto.FromReleaseReport(report),
+	})
+}
+
+// setMaxOpenReviewsReq - null в max_open_reviews сбрасывает личный лимит к лимиту команды
+type setMaxOpenReviewsReq struct {
+	UserID         string `json:"user_id" binding:"required"`
+	MaxOpenReviews *int   `json:"max_open_reviews" binding:"omitempty,min=0"`
+}
+
+func (h *UserHandler) SetMaxOpenReviews(c *gin.Context) {
+	var req setMaxOpenReviewsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	usr, err := h.userRepo.SetMaxOpenReviews(req.UserID, req.MaxOpenReviews)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error setting max open reviews", "error", err)
+			return
+		}
 		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
-		h.logger.Warnw("error setting user", "error", err)
+		h.logger.Errorw("error setting max open reviews", "userID", req.UserID, "err", err)
 		return
 	}
 
@@ -67,30 +226,46 @@ func (h *UserHandler) SetIsActive(c *gin.Context) {
 type getPRResp struct {
 	UserID       string           `json:"user_id"`
 	PullRequests []apidto.PRShort `json:"pull_requests"`
+	NextCursor   string           `json:"next_cursor"`
+}
+
+// getReviewReq - label можно передать несколько раз, тогда нужны все метки сразу
+type getReviewReq struct {
+	UserID string   `form:"user_id" binding:"required"`
+	Status string   `form:"status" binding:"omitempty,oneof=OPEN MERGED CLOSED DRAFT"`
+	Labels []string `form:"label"`
+	Cursor string   `form:"cursor"`
+	Limit  int      `form:"limit" binding:"omitempty,min=1,max=100"`
 }
 
 func (h *UserHandler) GetUserReviews(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
+	var req getReviewReq
+	if err := c.ShouldBindQuery(&req); err != nil {
 		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
-		h.logger.Warnw("no user_id provided")
+		h.logger.Warnw("error parsing request", "error", err)
 		return
 	}
 
-	prs, err := h.prRepo.ListPRsByReviewer(userID)
+	page, err := h.prRepo.ListPRsByReviewer(req.UserID, pullrequest.ReviewerPRsFilter{
+		Status: req.Status,
+		Labels: req.Labels,
+		Cursor: req.Cursor,
+		Limit:  req.Limit,
+	})
 	if err != nil {
 		if apierr.Handle(c, err) {
 			h.logger.Warnw("mapped error listing user reviews", "error", err)
 			return
 		}
 		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
-		h.logger.Errorw("error listing user reviews", "userID", userID, "err", err)
+		h.logger.Errorw("error listing user reviews", "userID", req.UserID, "err", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, getPRResp{
-		UserID:       userID,
-		PullRequests: apidto.FromPRsToShort(prs),
+		UserID:       req.UserID,
+		PullRequests: apidto.FromPRsToShort(page.PullRequests),
+		NextCursor:   page.NextCursor,
 	})
 }
 
@@ -99,8 +274,9 @@ type deactivateTeam struct {
 }
 
 type deactivateTeamResp struct {
-	TeamName string        `json:"team_name"`
-	Members  []apidto.User `json:"members"`
+	TeamName     string                `json:"team_name"`
+	Members      []apidto.User         `json:"members"`
+	Reassignment *apidto.ReleaseReport `json:"reassignment"`
 }
 
 func (h *UserHandler) DeactivateTeam(c *gin.Context) {
@@ -111,19 +287,231 @@ func (h *UserHandler) DeactivateTeam(c *gin.Context) {
 		return
 	}
 
-	deactivatedUsers, err := h.userRepo.SetIsActiveByTeam(req.TeamName, false)
+	var deactivatedUsers []*user.User
+	var report *pullrequest.ReleaseReport
+	err := h.inTx(func(repos Repos) error {
+		var err error
+		deactivatedUsers, err = repos.Users.SetIsActiveByTeam(req.TeamName, false)
+		if err != nil {
+			return err
+		}
+
+		userIDs := make([]string, 0, len(deactivatedUsers))
+		for _, u := range deactivatedUsers {
+			userIDs = append(userIDs, u.UserID)
+		}
+		report, err = repos.PRs.ReleaseReviews(userIDs)
+		return err
+	})
 	if err != nil {
 		if apierr.Handle(c, err) {
-			h.logger.Warnw("error setting user", "error", err)
+			h.logger.Warnw("mapped error deactivating team", "teamName", req.TeamName, "error", err)
 			return
 		}
 		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
-		h.logger.Errorw("error setting user", "error", err)
+		h.logger.Errorw("error deactivating team", "teamName", req.TeamName, "err", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, deactivateTeamResp{
-		TeamName: req.TeamName,
-		Members:  apidto.FromUsers(deactivatedUsers),
+		TeamName:     req.TeamName,
+		Members:      apidto.FromUsers(deactivatedUsers),
+		Reassignment: apidto.FromReleaseReport(report),
+	})
+}
+
+type addUnavailabilityReq struct {
+	UserID   string    `json:"user_id" binding:"required"`
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required,gtfield=StartsAt"`
+	Reason   string    `json:"reason" binding:"max=255"`
+}
+
+type unavailabilityResp struct {
+	UserID         string                `json:"user_id"`
+	Unavailability apidto.Unavailability `json:"unavailability"`
+}
+
+func (h *UserHandler) AddUnavailability(c *gin.Context) {
+	var req addUnavailabilityReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	window, err := h.userRepo.AddUnavailability(&user.Unavailability{
+		UserID:   req.UserID,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Reason:   req.Reason,
+	})
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error adding unavailability", "error", err)
+			return
+		}
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		h.logger.Errorw("error adding unavailability", "userID", req.UserID, "err", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, unavailabilityResp{
+		UserID:         req.UserID,
+		Unavailability: apidto.FromUnavailability(window),
+	})
+}
+
+type listUnavailabilityResp struct {
+	UserID         string                  `json:"user_id"`
+	Unavailability []apidto.Unavailability `json:"unavailability"`
+}
+
+func (h *UserHandler) ListUnavailability(c *gin.Context) {
+	userID := c.Query("user_id")
+	if userID == "" {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("no user_id provided")
+		return
+	}
+
+	windows, err := h.userRepo.ListUnavailability(userID)
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error listing unavailability", "error", err)
+			return
+		}
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		h.logger.Errorw("error listing unavailability", "userID", userID, "err", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, listUnavailabilityResp{
+		UserID:         userID,
+		Unavailability: apidto.FromUnavailabilities(windows),
+	})
+}
+
+type deleteUnavailabilityReq struct {
+	UserID string `json:"user_id" binding:"required"`
+	ID     uint64 `json:"id" binding:"required"`
+}
+
+func (h *UserHandler) DeleteUnavailability(c *gin.Context) {
+	var req deleteUnavailabilityReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	if err := h.userRepo.DeleteUnavailability(req.UserID, req.ID); err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error deleting unavailability", "error", err)
+			return
+		}
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		h.logger.Errorw("error deleting unavailability", "userID", req.UserID, "err", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// identityReq - external_id нужен для GitLab: MR, открытые и переоткрытые не автором, приходят только с его id
+type identityReq struct {
+	Provider   string `json:"provider" binding:"required,oneof=github gitlab"`
+	Login      string `json:"login" binding:"required,max=255"`
+	UserID     string `json:"user_id" binding:"required"`
+	ExternalID *int64 `json:"external_id" binding:"omitempty,gt=0"`
+}
+
+type identityResp struct {
+	Provider   string `json:"provider"`
+	Login      string `json:"login"`
+	UserID     string `json:"user_id"`
+	ExternalID *int64 `json:"external_id,omitempty"`
+}
+
+type setIdentityResp struct {
+	Identity identityResp `json:"identity"`
+}
+
+type listIdentitiesResp struct {
+	Identities []identityResp `json:"identities"`
+}
+
+func toIdentityResp(i *user.Identity) identityResp {
+	return identityResp{Provider: i.Provider, Login: i.Login, UserID: i.UserID, ExternalID: i.ExternalID}
+}
+
+// SetIdentity - привязка логина GitHub/GitLab к пользователю для вебхуков
+func (h *UserHandler) SetIdentity(c *gin.Context) {
+	var req identityReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	identity, err := h.userRepo.SetIdentity(&user.Identity{
+		Provider:   req.Provider,
+		Login:      req.Login,
+		UserID:     req.UserID,
+		ExternalID: req.ExternalID,
 	})
+	if err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error setting identity", "error", err)
+			return
+		}
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		h.logger.Errorw("error setting identity", "err", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, setIdentityResp{Identity: toIdentityResp(identity)})
+}
+
+func (h *UserHandler) ListIdentities(c *gin.Context) {
+	provider := c.Query("provider")
+
+	identities, err := h.userRepo.ListIdentities(provider)
+	if err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		h.logger.Errorw("error listing identities", "provider", provider, "err", err)
+		return
+	}
+
+	out := make([]identityResp, 0, len(identities))
+	for _, i := range identities {
+		out = append(out, toIdentityResp(i))
+	}
+	c.JSON(http.StatusOK, listIdentitiesResp{Identities: out})
+}
+
+type deleteIdentityReq struct {
+	Provider string `json:"provider" binding:"required"`
+	Login    string `json:"login" binding:"required"`
+}
+
+func (h *UserHandler) DeleteIdentity(c *gin.Context) {
+	var req deleteIdentityReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing request", "error", err)
+		return
+	}
+
+	if err := h.userRepo.DeleteIdentity(req.Provider, req.Login); err != nil {
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error deleting identity", "error", err)
+			return
+		}
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		h.logger.Errorw("error deleting identity", "err", err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
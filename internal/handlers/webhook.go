@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"assignerPR/internal/handlers/apidto"
+	"assignerPR/internal/handlers/apierr"
+	"assignerPR/internal/webhook"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxWebhookBody - payload PR/MR у GitHub и GitLab обычно десятки килобайт
+const maxWebhookBody = 5 << 20
+
+type WebhookHandler struct {
+	processor    *webhook.Processor
+	githubSecret []byte
+	gitlabToken  []byte
+	logger       *zap.SugaredLogger
+}
+
+func NewWebhookHandler(
+	logger *zap.SugaredLogger,
+	processor *webhook.Processor,
+	githubSecret string,
+	gitlabToken string,
+) *WebhookHandler {
+	return &WebhookHandler{
+		processor:    processor,
+		githubSecret: []byte(githubSecret),
+		gitlabToken:  []byte(gitlabToken),
+		logger:       logger,
+	}
+}
+
+type webhookResp struct {
+	Provider      string              `json:"provider"`
+	DeliveryID    string              `json:"delivery_id,omitempty"`
+	Action        string              `json:"action,omitempty"`
+	PullRequestID string              `json:"pull_request_id,omitempty"`
+	Outcome       string              `json:"outcome"`
+	Reason        string              `json:"reason,omitempty"`
+	PullRequest   *apidto.PullRequest `json:"pull_request,omitempty"`
+}
+
+func (h *WebhookHandler) GitHub(c *gin.Context) {
+	body, ok := h.readBody(c)
+	if !ok {
+		return
+	}
+
+	if err := webhook.VerifyGitHubSignature(h.githubSecret, body, c.GetHeader(webhook.GitHubSignatureHeader)); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusUnauthorized, apierr.Unauthorized)
+		h.logger.Warnw("bad github webhook signature", "delivery", c.GetHeader(webhook.GitHubDeliveryHeader))
+		return
+	}
+
+	resp := webhookResp{
+		Provider:   webhook.ProviderGitHub,
+		DeliveryID: c.GetHeader(webhook.GitHubDeliveryHeader),
+		Outcome:    webhook.OutcomeIgnored,
+	}
+
+	eventType := c.GetHeader(webhook.GitHubEventHeader)
+	if webhook.IsGitHubPing(eventType) {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	ev, err := webhook.ParseGitHub(eventType, body)
+	h.apply(c, resp, ev, err)
+}
+
+func (h *WebhookHandler) GitLab(c *gin.Context) {
+	if err := webhook.VerifyGitLabToken(h.gitlabToken, c.GetHeader(webhook.GitLabTokenHeader)); err != nil {
+		apierr.WriteApiErrJSON(c, http.StatusUnauthorized, apierr.Unauthorized)
+		h.logger.Warnw("bad gitlab webhook token", "delivery", c.GetHeader(webhook.GitLabDeliveryHeader))
+		return
+	}
+
+	body, ok := h.readBody(c)
+	if !ok {
+		return
+	}
+
+	resp := webhookResp{
+		Provider:   webhook.ProviderGitLab,
+		DeliveryID: c.GetHeader(webhook.GitLabDeliveryHeader),
+		Outcome:    webhook.OutcomeIgnored,
+	}
+
+	ev, err := webhook.ParseGitLab(c.GetHeader(webhook.GitLabEventHeader), body)
+	h.apply(c, resp, ev, err)
+}
+
+func (h *WebhookHandler) readBody(c *gin.Context) ([]byte, bool) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody+1))
+	if err != nil || len(body) > maxWebhookBody {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error reading webhook body", "size", len(body), "error", err)
+		return nil, false
+	}
+	return body, true
+}
+
+// apply - общая часть для всех провайдеров после проверки подписи и разбора события
+func (h *WebhookHandler) apply(c *gin.Context, resp webhookResp, ev *webhook.Event, parseErr error) {
+	if parseErr != nil {
+		apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+		h.logger.Warnw("error parsing webhook payload", "provider", resp.Provider, "error", parseErr)
+		return
+	}
+	if ev == nil {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	res, err := h.processor.Apply(ev)
+	if err != nil {
+		if errors.Is(err, webhook.ErrBadPayload) {
+			apierr.WriteApiErrJSON(c, http.StatusBadRequest, apierr.BadRequest)
+			return
+		}
+		if apierr.Handle(c, err) {
+			h.logger.Warnw("mapped error applying webhook", "provider", resp.Provider, "error", err)
+			return
+		}
+		h.logger.Errorw("error applying webhook", "provider", resp.Provider, "err", err)
+		apierr.WriteApiErrJSON(c, http.StatusInternalServerError, apierr.InternalServerError)
+		return
+	}
+
+	resp.Action = res.Action
+	resp.PullRequestID = res.PullRequestID
+	resp.Outcome = res.Outcome
+	resp.Reason = res.Reason
+	if res.PR != nil {
+		pr := apidto.FromPR(res.PR)
+		resp.PullRequest = &pr
+	}
+	c.JSON(http.StatusOK, resp)
+}
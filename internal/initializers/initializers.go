@@ -5,9 +5,13 @@ import (
 	"assignerPR/internal/handlers/mdlwr"
 	"assignerPR/internal/metrics"
 	"assignerPR/internal/pullrequest"
+	"assignerPR/internal/reviewersync"
+	"assignerPR/internal/webhook"
 	"assignerPR/pkg/team"
 	"assignerPR/pkg/user"
 	"net/http"
+	"strconv"
+	"time"
 
 	jwt "github.com/appleboy/gin-jwt/v2"
 	"github.com/gin-contrib/pprof"
@@ -76,27 +80,167 @@ func gormAutoMigrate(db *gorm.DB) {
 		&pullrequest.PullRequest{},
 		&team.Team{},
 		&user.User{},
+		&pullrequest.RotationCursor{},
+		&team.PathRule{},
+		&team.Fallback{},
+		&user.Unavailability{},
+		&pullrequest.Review{},
+		&user.Identity{},
+		&reviewersync.PRSync{},
 	); errAuto != nil {
 		log.Fatalf("AutoMigrate failed: %v", errAuto)
 		return
 	}
 }
 
+// Окно затухания смерженных ревью в нагрузке, пустое значение - считаются только открытые
+func loadFormulaFromEnv() pullrequest.LoadFormula {
+	var formula pullrequest.LoadFormula
+
+	if windowStr := os.Getenv("LOAD_MERGED_WINDOW"); windowStr != "" {
+		window, err := time.ParseDuration(windowStr)
+		if err != nil {
+			log.Fatalf("Invalid LOAD_MERGED_WINDOW: %v", err)
+		}
+		formula.MergedWindow = window
+	}
+
+	if weightStr := os.Getenv("LOAD_MERGED_WEIGHT"); weightStr != "" {
+		weight, err := strconv.ParseFloat(weightStr, 64)
+		if err != nil {
+			log.Fatalf("Invalid LOAD_MERGED_WEIGHT: %v", err)
+		}
+		formula.MergedWeight = weight
+	}
+
+	return formula
+}
+
+// loadReviewerSyncFromEnv - провайдер подключается, если задан его токен. GITLAB_API_URL обязателен вместе
+// с токеном: у GitLab нет общего адреса по умолчанию
+func loadReviewerSyncFromEnv() []reviewersync.Option {
+	var opts []reviewersync.Option
+
+	if token := os.Getenv("GITHUB_API_TOKEN"); token != "" {
+		opts = append(opts, reviewersync.WithClient(webhook.ProviderGitHub,
+			reviewersync.NewGitHubClient(os.Getenv("GITHUB_API_URL"), token, nil)))
+	}
+
+	if token := os.Getenv("GITLAB_API_TOKEN"); token != "" {
+		apiURL := os.Getenv("GITLAB_API_URL")
+		if apiURL == "" {
+			log.Fatal("GITLAB_API_URL is required with GITLAB_API_TOKEN")
+		}
+		opts = append(opts, reviewersync.WithClient(webhook.ProviderGitLab,
+			reviewersync.NewGitLabClient(apiURL, token, nil)))
+	}
+
+	attempts := reviewersync.DefaultAttempts
+	if attemptsStr := os.Getenv("REVIEWER_SYNC_ATTEMPTS"); attemptsStr != "" {
+		var err error
+		attempts, err = strconv.Atoi(attemptsStr)
+		if err != nil || attempts < 1 {
+			log.Fatalf("Invalid REVIEWER_SYNC_ATTEMPTS: %q", attemptsStr)
+		}
+	}
+
+	backoff := reviewersync.DefaultBackoff
+	if backoffStr := os.Getenv("REVIEWER_SYNC_BACKOFF"); backoffStr != "" {
+		var err error
+		backoff, err = time.ParseDuration(backoffStr)
+		if err != nil || backoff <= 0 {
+			log.Fatalf("Invalid REVIEWER_SYNC_BACKOFF: %q", backoffStr)
+		}
+	}
+
+	return append(opts, reviewersync.WithRetry(attempts, backoff))
+}
+
+// newInTx - репозитории хендлеров поверх одной транзакции. Изменения ревьюверов уходят в syncer,
+// а сдвиг open_prs в метрики только после коммита
+func newInTx(logger *zap.SugaredLogger, db *gorm.DB, formula pullrequest.LoadFormula, syncer *reviewersync.Syncer) handlers2.InTx {
+	return func(fn func(repos handlers2.Repos) error) error {
+		batch := &reviewersync.Batch{}
+		openPRs := &metrics.OpenPRBatch{}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			prRepo := pullrequest.NewPullRequestsRepoPg(logger, tx,
+				pullrequest.WithLoadFormula(formula), pullrequest.WithOpenPRMetric(openPRs.AddOpenPR))
+			return fn(handlers2.Repos{
+				Users: user.NewUsersRepoPg(logger, tx),
+				Teams: team.NewTeamsRepoPg(logger, tx),
+				PRs:   reviewersync.NewSyncingRepo(prRepo, batch),
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		openPRs.Flush()
+		batch.Flush(syncer)
+		return nil
+	}
+}
+
 func initUserRoutes(router *gin.Engine, userHandler *handlers2.UserHandler) {
 	usersGroup := router.Group("/users")
 
 	auth := initAdminAuthMdlwr()
 	usersGroup.POST("/setIsActive", auth.MiddlewareFunc(), userHandler.SetIsActive)
 	usersGroup.POST("/deactivateTeam", auth.MiddlewareFunc(), userHandler.DeactivateTeam)
+	usersGroup.POST("/setMaxOpenReviews", auth.MiddlewareFunc(), userHandler.SetMaxOpenReviews)
 	usersGroup.GET("/getReview", userHandler.GetUserReviews)
+	usersGroup.GET("/get", userHandler.GetUser)
+	usersGroup.GET("/list", userHandler.ListUsers)
+	usersGroup.PATCH("/update", auth.MiddlewareFunc(), userHandler.UpdateUser)
+	usersGroup.POST("/offboard", auth.MiddlewareFunc(), userHandler.Offboard)
+	usersGroup.POST("/unavailability", auth.MiddlewareFunc(), userHandler.AddUnavailability)
+	usersGroup.GET("/unavailability", userHandler.ListUnavailability)
+	usersGroup.POST("/unavailability/delete", auth.MiddlewareFunc(), userHandler.DeleteUnavailability)
+	usersGroup.GET("/identities", userHandler.ListIdentities)
+	usersGroup.POST("/identities", auth.MiddlewareFunc(), userHandler.SetIdentity)
+	usersGroup.POST("/identities/delete", auth.MiddlewareFunc(), userHandler.DeleteIdentity)
+}
+
+// Вебхуки без админского токена: запрос подписан секретом провайдера (у GitLab - токен в заголовке). Без секрета провайдер не подключается
+func initWebhookRoutes(router *gin.Engine, webhookHandler *handlers2.WebhookHandler, logger *zap.SugaredLogger) {
+	webhooksGroup := router.Group("/webhooks")
+
+	if os.Getenv("GITHUB_WEBHOOK_SECRET") != "" {
+		webhooksGroup.POST("/github", webhookHandler.GitHub)
+	} else {
+		logger.Info("GITHUB_WEBHOOK_SECRET not set, /webhooks/github disabled")
+	}
+
+	if os.Getenv("GITLAB_WEBHOOK_TOKEN") != "" {
+		webhooksGroup.POST("/gitlab", webhookHandler.GitLab)
+	} else {
+		logger.Info("GITLAB_WEBHOOK_TOKEN not set, /webhooks/gitlab disabled")
+	}
 }
 
 func initPullRequestRoutes(router *gin.Engine, pullRequestHandler *handlers2.PullRequestHandler) {
 	prsGroup := router.Group("/pullRequest")
 
+	prsGroup.GET("/get", pullRequestHandler.GetPR)
+	prsGroup.GET("/list", pullRequestHandler.ListPRs)
 	prsGroup.POST("/create", pullRequestHandler.CreatePR)
 	prsGroup.POST("/merge", pullRequestHandler.Merge)
+	prsGroup.POST("/review", pullRequestHandler.Review)
+	prsGroup.POST("/ready", pullRequestHandler.Ready)
+	prsGroup.POST("/close", pullRequestHandler.Close)
+	prsGroup.POST("/reopen", pullRequestHandler.Reopen)
+	prsGroup.POST("/update", pullRequestHandler.Update)
 	prsGroup.POST("/reassign", pullRequestHandler.ReassignPR)
+	prsGroup.POST("/reviewers/add", pullRequestHandler.AddReviewer)
+	prsGroup.POST("/reviewers/remove", pullRequestHandler.RemoveReviewer)
+}
+
+func initReviewerSyncRoutes(router *gin.Engine, reviewerSyncHandler *handlers2.ReviewerSyncHandler) {
+	prsGroup := router.Group("/pullRequest")
+
+	auth := initAdminAuthMdlwr()
+	prsGroup.GET("/sync", reviewerSyncHandler.GetSync)
+	prsGroup.POST("/sync", auth.MiddlewareFunc(), reviewerSyncHandler.Resync)
 }
 
 func initTeamRoutes(router *gin.Engine, teamHandler *handlers2.TeamHandler) {
@@ -105,6 +249,17 @@ func initTeamRoutes(router *gin.Engine, teamHandler *handlers2.TeamHandler) {
 	teamsGroup.POST("/add", teamHandler.AddTeam)
 	teamsGroup.GET("/get", teamHandler.GetTeam)
 	teamsGroup.GET("/pr-stats", teamHandler.StatsTeam)
+
+	auth := initAdminAuthMdlwr()
+	teamsGroup.POST("/settings", auth.MiddlewareFunc(), teamHandler.UpdateSettings)
+	teamsGroup.POST("/pathRules", auth.MiddlewareFunc(), teamHandler.SetPathRules)
+	teamsGroup.GET("/pathRules", teamHandler.GetPathRules)
+	teamsGroup.POST("/members/add", auth.MiddlewareFunc(), teamHandler.AddMembers)
+	teamsGroup.POST("/members/remove", auth.MiddlewareFunc(), teamHandler.RemoveMember)
+	teamsGroup.POST("/members/move", auth.MiddlewareFunc(), teamHandler.MoveMember)
+	teamsGroup.POST("/rename", auth.MiddlewareFunc(), teamHandler.RenameTeam)
+	teamsGroup.POST("/delete", auth.MiddlewareFunc(), teamHandler.DeleteTeam)
+	teamsGroup.PUT("/sync", auth.MiddlewareFunc(), teamHandler.SyncTeam)
 }
 
 func initpprof(router *gin.Engine) {
@@ -0,0 +1,130 @@
+package initializers
+
+import (
+	"assignerPR/internal/pullrequest"
+	"assignerPR/internal/reviewersync"
+	"assignerPR/internal/roster"
+	"assignerPR/pkg/team"
+	"assignerPR/pkg/user"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const rosterUsage = `usage:
+  assigner roster import [-format json|csv] [-file path] [-dry-run]
+  assigner roster export [-format json|csv] [-file path]
+
+without -file the roster is read from stdin / written to stdout,
+without -format it is taken from the file extension (json by default)`
+
+// RunRoster - выгрузка и загрузка состава команд из командной строки, args - все после "roster"
+func RunRoster(args []string) {
+	if len(args) == 0 {
+		log.Fatal(rosterUsage)
+	}
+
+	fs := flag.NewFlagSet("roster "+args[0], flag.ExitOnError)
+	format := fs.String("format", "", "roster format: json or csv")
+	file := fs.String("file", "", "roster file, stdin/stdout if empty")
+	dryRun := fs.Bool("dry-run", false, "import: print the diff and roll back")
+	if err := fs.Parse(args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	if *format == "" {
+		*format = roster.FormatJSON
+		if strings.EqualFold(filepath.Ext(*file), ".csv") {
+			*format = roster.FormatCSV
+		}
+	}
+
+	zapLogger := startLogger()
+	defer func() { _ = zapLogger.Sync() }()
+
+	logger := zapLogger.Sugar()
+	db := startPostgres()
+
+	switch args[0] {
+	case "import":
+		runRosterImport(logger, db, *format, *file, *dryRun)
+	case "export":
+		runRosterExport(logger, db, *format, *file)
+	default:
+		log.Fatal(rosterUsage)
+	}
+}
+
+func runRosterImport(logger *zap.SugaredLogger, db *gorm.DB, format, file string, dryRun bool) {
+	var in io.Reader = os.Stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			log.Fatalf("Error opening roster: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	r, err := roster.Read(in, format)
+	if err != nil {
+		log.Fatalf("Error reading roster: %v", err)
+	}
+
+	// очереди синхронизации с код-хостом тут нет: PR с переназначенными ревьюверами помечаются PENDING,
+	// на хост их отправит запущенный сервис
+	formula := loadFormulaFromEnv()
+	batch := &reviewersync.Batch{}
+	importer := roster.NewImporter(logger, db, func(tx *gorm.DB) roster.Repos {
+		return roster.Repos{
+			Teams: team.NewTeamsRepoPg(logger, tx),
+			Users: user.NewUsersRepoPg(logger, tx),
+			PRs: reviewersync.NewSyncingRepo(
+				pullrequest.NewPullRequestsRepoPg(logger, tx, pullrequest.WithLoadFormula(formula)), batch),
+		}
+	})
+
+	report, err := importer.Import(r, dryRun)
+	if err != nil {
+		log.Fatalf("Error importing roster: %v", err)
+	}
+	if !dryRun {
+		batch.Flush(reviewersync.NewPendingNotifier(logger, reviewersync.NewSyncRepoPg(logger, db)))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatalf("Error writing import report: %v", err)
+	}
+}
+
+func runRosterExport(logger *zap.SugaredLogger, db *gorm.DB, format, file string) {
+	r, err := roster.Export(team.NewTeamsRepoPg(logger, db))
+	if err != nil {
+		log.Fatalf("Error exporting roster: %v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			log.Fatalf("Error creating roster file: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := roster.Write(out, format, r); err != nil {
+		log.Fatalf("Error writing roster: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "exported %d teams\n", len(r.Teams))
+}
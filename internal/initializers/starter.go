@@ -3,6 +3,8 @@ package initializers
 import (
 	handlers2 "assignerPR/internal/handlers"
 	"assignerPR/internal/pullrequest"
+	"assignerPR/internal/reviewersync"
+	"assignerPR/internal/webhook"
 	"assignerPR/pkg/team"
 	"assignerPR/pkg/user"
 	"context"
@@ -43,11 +45,21 @@ func RunPRAssigner() {
 
 	userRepo := user.NewUsersRepoPg(logger, db)
 	teamRepo := team.NewTeamsRepoPg(logger, db)
-	prRepo := pullrequest.NewPullRequestsRepoPg(logger, db)
+	formula := loadFormulaFromEnv()
+	prRepoPg := pullrequest.NewPullRequestsRepoPg(logger, db, pullrequest.WithLoadFormula(formula))
 
-	userHandler := handlers2.NewUserHandler(logger, userRepo, prRepo)
-	teamHandler := handlers2.NewTeamHandler(logger, teamRepo, prRepo)
+	// все изменения ревьюверов через API и вебхуки идут через prRepo, чтобы попасть на код-хост
+	syncer := reviewersync.NewSyncer(logger, prRepoPg, reviewersync.NewSyncRepoPg(logger, db), userRepo,
+		loadReviewerSyncFromEnv()...)
+	prRepo := reviewersync.NewSyncingRepo(prRepoPg, syncer)
+	inTx := newInTx(logger, db, formula, syncer)
+
+	userHandler := handlers2.NewUserHandler(logger, userRepo, prRepo, inTx)
+	teamHandler := handlers2.NewTeamHandler(logger, teamRepo, prRepo, inTx)
 	prHandler := handlers2.NewPullRequestHandler(logger, prRepo)
+	reviewerSyncHandler := handlers2.NewReviewerSyncHandler(logger, syncer)
+	webhookHandler := handlers2.NewWebhookHandler(logger, webhook.NewProcessor(logger, prRepo, userRepo, syncer),
+		os.Getenv("GITHUB_WEBHOOK_SECRET"), os.Getenv("GITLAB_WEBHOOK_TOKEN"))
 
 	router := gin.New()
 	initMetricsMdlwr(router)
@@ -65,6 +77,8 @@ func RunPRAssigner() {
 	initUserRoutes(router, userHandler)
 	initTeamRoutes(router, teamHandler)
 	initPullRequestRoutes(router, prHandler)
+	initReviewerSyncRoutes(router, reviewerSyncHandler)
+	initWebhookRoutes(router, webhookHandler, logger)
 	metricsSrv := initMetricsServer()
 	initpprof(router)
 
@@ -80,6 +94,13 @@ func RunPRAssigner() {
 		}
 	}()
 
+	syncCtx, stopSync := context.WithCancel(context.Background())
+	syncDone := make(chan struct{})
+	go func() {
+		syncer.Run(syncCtx)
+		close(syncDone)
+	}()
+
 	go func() {
 		logger.Info("Starting metrics server on port " + os.Getenv("METRICS_PORT"))
 		if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -117,5 +138,9 @@ func RunPRAssigner() {
 
 	wg.Wait()
 
+	// недоотправленные PR останутся в PENDING, Run поднимет их при следующем старте
+	stopSync()
+	<-syncDone
+
 	logger.Info("Server exited")
 }
@@ -47,6 +47,22 @@ var (
 		[]string{"op", "result"},
 	)
 
+	webhookEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_events_total",
+			Help: "Webhook PR events by provider, mapped action and outcome.",
+		},
+		[]string{"provider", "action", "outcome"},
+	)
+
+	reviewerSyncs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "reviewer_sync_total",
+			Help: "Reviewer sync attempts to code hosts by provider and result.",
+		},
+		[]string{"provider", "result"},
+	)
+
 	openPRs = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "open_prs",
@@ -94,16 +110,45 @@ func ObservePROp(op string, start time.Time, err error) {
 	prDuration.WithLabelValues(op, result).Observe(time.Since(start).Seconds())
 }
 
+func ObserveWebhook(provider, action, outcome string) {
+	webhookEvents.WithLabelValues(provider, action, outcome).Inc()
+}
+
+// ObserveReviewerSync - result: synced, retry, failed
+func ObserveReviewerSync(provider, result string) {
+	reviewerSyncs.WithLabelValues(provider, result).Inc()
+}
+
 func AddOpenPR(delta float64) {
 	openPRs.Add(delta)
 }
 
+// OpenPRBatch копит изменения open_prs внешней транзакции: репозиторий PR коммитит только свою (вложенную),
+// и гейдж сдвигался бы еще до коммита внешней или вовсе при ее откате
+type OpenPRBatch struct {
+	delta float64
+}
+
+func (b *OpenPRBatch) AddOpenPR(delta float64) {
+	b.delta += delta
+}
+
+// Flush - вызывать только после успешного коммита
+func (b *OpenPRBatch) Flush() {
+	if b.delta != 0 {
+		AddOpenPR(b.delta)
+	}
+	b.delta = 0
+}
+
 func init() {
 	collectors := []prometheus.Collector{
 		httpRequests,
 		httpDuration,
 		prEvents,
 		prDuration,
+		webhookEvents,
+		reviewerSyncs,
 		openPRs,
 	}
 
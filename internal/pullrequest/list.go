@@ -0,0 +1,217 @@
+package pullrequest
+
+import (
+	"assignerPR/internal/metrics"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Поля сортировки списка PR, при равенстве порядок добивается по pull_request_id
+const (
+	SortByCreatedAt = "created_at"
+	SortByUpdatedAt = "updated_at"
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+
+	// sortByAssignedAt - сортировка списка ревьювера, снаружи не выбирается
+	sortByAssignedAt = "assigned_at"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 100
+)
+
+// PRFilter - фильтры списка PR, пустые поля не применяются. TeamName - команда автора,
+// Labels - PR должен нести все перечисленные метки, *From включительно, *To исключительно
+type PRFilter struct {
+	Status     string
+	AuthorID   string
+	ReviewerID string
+	TeamName   string
+	Labels     []string
+
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MergedFrom  *time.Time
+	MergedTo    *time.Time
+
+	SortBy string
+	Order  string
+	Cursor string
+	Limit  int
+}
+
+// ReviewerPRsFilter - фильтры /users/getReview, Labels - PR должен нести все перечисленные метки
+type ReviewerPRsFilter struct {
+	Status string
+	Labels []string
+	Cursor string
+	Limit  int
+}
+
+// PRPage - страница списка, NextCursor пустой на последней странице
+type PRPage struct {
+	PullRequests []*PullRequest
+	NextCursor   string
+}
+
+// listCursor - позиция последнего PR страницы. Сортировка зашита в курсор, чтобы нельзя было
+// продолжить список с другой сортировкой и получить пропуски
+type listCursor struct {
+	SortBy string    `json:"s"`
+	Order  string    `json:"o"`
+	Value  time.Time `json:"v"`
+	ID     string    `json:"id"`
+}
+
+func encodeCursor(c listCursor) string {
+	// маршалинг структуры из строк и времени не падает
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*listCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c listCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func (repo *PullRequestsRepoPg) GetPR(prID string) (*PullRequest, error) {
+	repo.logger.Debugw("GetPR()", "prID", prID)
+
+	start := time.Now()
+	var err error
+
+	defer func() {
+		metrics.ObservePROp("get_pr", start, err)
+	}()
+
+	var pr PullRequest
+	if err = repo.reloadPR(repo.db, prID, &pr); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			repo.logger.Warnw("PR does not exist", "prID", prID)
+			err = ErrPRNotFound
+			return nil, err
+		}
+		repo.logger.Errorw("error getting PR", "prID", prID, "err", err)
+		return nil, err
+	}
+
+	return &pr, nil
+}
+
+// ListPRs - keyset-пагинация по (поле сортировки, pull_request_id)
+func (repo *PullRequestsRepoPg) ListPRs(filter PRFilter) (*PRPage, error) {
+	repo.logger.Debugw("ListPRs()", "filter", filter)
+
+	start := time.Now()
+	var err error
+
+	defer func() {
+		metrics.ObservePROp("list_prs", start, err)
+	}()
+
+	sortBy := SortByCreatedAt
+	if filter.SortBy == SortByUpdatedAt {
+		sortBy = SortByUpdatedAt
+	}
+	order := SortDesc
+	if filter.Order == SortAsc {
+		order = SortAsc
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+
+	q := repo.db.Model(&PullRequest{})
+	if filter.Status != "" {
+		q = q.Where("pull_requests.status = ?", filter.Status)
+	}
+	if filter.AuthorID != "" {
+		q = q.Where("pull_requests.author_id = ?", filter.AuthorID)
+	}
+	if filter.ReviewerID != "" {
+		q = q.Where("EXISTS (SELECT 1 FROM pr_reviewers prr "+
+			"WHERE prr.pull_request_id = pull_requests.pull_request_id AND prr.user_id = ?)", filter.ReviewerID)
+	}
+	if filter.TeamName != "" {
+		q = q.Where("EXISTS (SELECT 1 FROM users a "+
+			"WHERE a.user_id = pull_requests.author_id AND a.team_name = ?)", filter.TeamName)
+	}
+	if labels := normalizeLabels(filter.Labels); len(labels) > 0 {
+		q = q.Where("pull_requests.labels @> ?", labelsJSON(labels))
+	}
+	if filter.CreatedFrom != nil {
+		q = q.Where("pull_requests.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		q = q.Where("pull_requests.created_at < ?", *filter.CreatedTo)
+	}
+	if filter.MergedFrom != nil {
+		q = q.Where("pull_requests.merged_at >= ?", *filter.MergedFrom)
+	}
+	if filter.MergedTo != nil {
+		q = q.Where("pull_requests.merged_at < ?", *filter.MergedTo)
+	}
+
+	// sortBy и order выше сведены к известным значениям, так что их можно подставлять в SQL
+	column := "pull_requests." + sortBy
+	if filter.Cursor != "" {
+		var cursor *listCursor
+		cursor, err = decodeCursor(filter.Cursor)
+		if err != nil || cursor.SortBy != sortBy || cursor.Order != order {
+			repo.logger.Warnw("invalid cursor", "cursor", filter.Cursor, "sortBy", sortBy, "order", order)
+			err = ErrInvalidCursor
+			return nil, err
+		}
+
+		cmp := "<"
+		if order == SortAsc {
+			cmp = ">"
+		}
+		q = q.Where("("+column+", pull_requests.pull_request_id) "+cmp+" (?, ?)", cursor.Value, cursor.ID)
+	}
+
+	var prs []*PullRequest
+	err = q.
+		Preload("AssignedReviewers", func(tx2 *gorm.DB) *gorm.DB {
+			return tx2.Order("users.user_id ASC")
+		}).
+		Preload("Assignments").
+		Order(column + " " + order + ", pull_requests.pull_request_id " + order).
+		Limit(limit + 1).
+		Find(&prs).Error
+	if err != nil {
+		repo.logger.Errorw("error listing PRs", "err", err)
+		return nil, err
+	}
+
+	page := &PRPage{PullRequests: prs}
+	if len(prs) > limit {
+		page.PullRequests = prs[:limit]
+		last := page.PullRequests[limit-1]
+		value := last.CreatedAt
+		if sortBy == SortByUpdatedAt {
+			value = last.UpdatedAt
+		}
+		page.NextCursor = encodeCursor(listCursor{SortBy: sortBy, Order: order, Value: value, ID: last.PullRequestID})
+	}
+
+	repo.logger.Debugw("listed PRs", "count", len(page.PullRequests), "hasMore", page.NextCursor != "")
+	return page, nil
+}
@@ -0,0 +1,165 @@
+package pullrequest
+
+import (
+	"assignerPR/internal/metrics"
+	"assignerPR/pkg/team"
+	"assignerPR/pkg/user"
+	"errors"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AddReviewer - ручное назначение конкретного человека. Лимит открытых ревью и окна недоступности
+// тут не проверяются: это осознанное решение лида, в отличие от автоматического выбора
+func (repo *PullRequestsRepoPg) AddReviewer(prID, userID string) (*PullRequest, error) {
+	repo.logger.Debugw("AddReviewer()", "prID", prID, "userID", userID)
+
+	start := time.Now()
+	var err error
+
+	defer func() {
+		metrics.ObservePROp("add_reviewer", start, err)
+	}()
+
+	var pr PullRequest
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := repo.lockAndLoadEditablePR(tx, prID, &pr); err != nil {
+			return err
+		}
+
+		reviewer, err := repo.loadEligibleReviewerInTx(tx, &pr, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&pr).Association("AssignedReviewers").Append(reviewer); err != nil {
+			repo.logger.Errorw("error adding reviewer", "prID", prID, "userID", userID, "err", err)
+			return err
+		}
+
+		return repo.reloadPR(tx, prID, &pr)
+	})
+
+	if err != nil {
+		repo.logger.Errorw("error adding reviewer", "prID", prID, "userID", userID, "err", err)
+		return nil, err
+	}
+
+	return &pr, nil
+}
+
+// RemoveReviewer снимает ревьювера без замены, PR может остаться с недобором
+func (repo *PullRequestsRepoPg) RemoveReviewer(prID, userID string) (*PullRequest, error) {
+	repo.logger.Debugw("RemoveReviewer()", "prID", prID, "userID", userID)
+
+	start := time.Now()
+	var err error
+
+	defer func() {
+		metrics.ObservePROp("remove_reviewer", start, err)
+	}()
+
+	var pr PullRequest
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := repo.lockAndLoadEditablePR(tx, prID, &pr); err != nil {
+			return err
+		}
+
+		reviewer, ok := findReviewer(pr.AssignedReviewers, userID)
+		if !ok {
+			repo.logger.Warnw("no reviewer to remove", "prID", prID, "userID", userID)
+			return ErrNotAssigned
+		}
+
+		if err := tx.Model(&pr).Association("AssignedReviewers").Delete(reviewer); err != nil {
+			repo.logger.Errorw("error removing reviewer", "prID", prID, "userID", userID, "err", err)
+			return err
+		}
+
+		return repo.reloadPR(tx, prID, &pr)
+	})
+
+	if err != nil {
+		repo.logger.Errorw("error removing reviewer", "prID", prID, "userID", userID, "err", err)
+		return nil, err
+	}
+
+	return &pr, nil
+}
+
+// lockAndLoadEditablePR - lockAndLoadPR плюс проверка, что состав ревьюверов еще можно менять
+func (repo *PullRequestsRepoPg) lockAndLoadEditablePR(tx *gorm.DB, prID string, pr *PullRequest) error {
+	if err := repo.lockAndLoadPR(tx, prID, pr); err != nil {
+		return err
+	}
+
+	switch pr.Status {
+	case StatusMerged:
+		return ErrPRMerged
+	case StatusClosed:
+		return ErrPRClosed
+	case StatusDraft:
+		return ErrPRDraft
+	}
+
+	return nil
+}
+
+// loadEligibleReviewerInTx - загрузка выбранного вручную ревьювера (AddReviewer, Reassign с new_reviewer_id)
+// с проверкой, что его можно назначить на pr
+func (repo *PullRequestsRepoPg) loadEligibleReviewerInTx(tx *gorm.DB, pr *PullRequest, userID string) (*user.User, error) {
+	if _, ok := findReviewer(pr.AssignedReviewers, userID); ok {
+		repo.logger.Warnw("reviewer already assigned", "prID", pr.PullRequestID, "userID", userID)
+		return nil, ErrAlreadyAssigned
+	}
+
+	var reviewer user.User
+	if err := tx.First(&reviewer, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, user.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if err := repo.checkEligibleInTx(tx, pr, &reviewer); err != nil {
+		return nil, err
+	}
+
+	return &reviewer, nil
+}
+
+// checkEligibleInTx - ревьювер активен, не автор и состоит в команде автора или в одной из ее запасных
+func (repo *PullRequestsRepoPg) checkEligibleInTx(tx *gorm.DB, pr *PullRequest, reviewer *user.User) error {
+	if reviewer.UserID == pr.AuthorID {
+		repo.logger.Warnw("reviewer is the author", "prID", pr.PullRequestID, "userID", reviewer.UserID)
+		return ErrReviewerIsAuthor
+	}
+	if !reviewer.IsActive || reviewer.OffboardedAt != nil {
+		repo.logger.Warnw("reviewer is inactive", "prID", pr.PullRequestID, "userID", reviewer.UserID)
+		return ErrReviewerInactive
+	}
+
+	var author user.User
+	if err := tx.First(&author, "user_id = ?", pr.AuthorID).Error; err != nil {
+		return err
+	}
+	if reviewer.TeamName == author.TeamName {
+		return nil
+	}
+
+	var fallbackTeams []string
+	if err := tx.Model(&team.Fallback{}).
+		Where("team_name = ?", author.TeamName).
+		Pluck("fallback_team_name", &fallbackTeams).Error; err != nil {
+		return err
+	}
+	if slices.Contains(fallbackTeams, reviewer.TeamName) {
+		return nil
+	}
+
+	repo.logger.Warnw("reviewer is not in author's team", "prID", pr.PullRequestID, "userID", reviewer.UserID,
+		"reviewerTeam", reviewer.TeamName, "authorTeam", author.TeamName)
+	return ErrReviewerNotInTeam
+}
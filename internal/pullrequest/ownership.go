@@ -0,0 +1,173 @@
+package pullrequest
+
+import (
+	"assignerPR/pkg/team"
+	"assignerPR/pkg/user"
+	"path"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const anySegments = "**"
+
+// LabelPatternPrefix - правило вида "label:security" срабатывает не по путям, а по метке PR
+const LabelPatternPrefix = "label:"
+
+// MatchPathPattern - сопоставление пути с шаблоном в духе CODEOWNERS: "/" в начале или середине привязывает
+// шаблон к корню, "/" в конце - только директория, "**" - любое количество сегментов, "*" - внутри сегмента
+func MatchPathPattern(pattern, filePath string) bool {
+	trimmed := strings.Trim(pattern, "/")
+	if trimmed == "" {
+		return false
+	}
+
+	segments := strings.Split(trimmed, "/")
+	if !strings.HasPrefix(pattern, "/") && !strings.Contains(trimmed, "/") {
+		segments = append([]string{anySegments}, segments...)
+	}
+	if strings.HasSuffix(pattern, "/") {
+		segments = append(segments, "*")
+	}
+	// шаблон на директорию покрывает все внутри нее
+	segments = append(segments, anySegments)
+
+	return matchSegments(segments, strings.Split(strings.TrimPrefix(filePath, "/"), "/"))
+}
+
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == anySegments {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	ok, err := path.Match(pattern[0], segments[0])
+	return err == nil && ok && matchSegments(pattern[1:], segments[1:])
+}
+
+type ownershipLine struct {
+	pattern string
+	owners  []string
+}
+
+// ownershipLines - строки правил в порядке position, как строки файла CODEOWNERS
+func ownershipLines(rules []*team.PathRule) []*ownershipLine {
+	sorted := make([]*team.PathRule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	lines := make([]*ownershipLine, 0, len(sorted))
+	for i, r := range sorted {
+		if i == 0 || sorted[i-1].Position != r.Position {
+			lines = append(lines, &ownershipLine{pattern: r.Pattern})
+		}
+		last := lines[len(lines)-1]
+		last.owners = append(last.owners, r.UserID)
+	}
+
+	return lines
+}
+
+// matchLine - правило с меткой сопоставляется только с метками, остальные только с путями
+func matchLine(line *ownershipLine, target string, isLabel bool) bool {
+	label, labelRule := strings.CutPrefix(line.pattern, LabelPatternPrefix)
+	if isLabel {
+		return labelRule && label == target
+	}
+
+	return !labelRule && MatchPathPattern(line.pattern, target)
+}
+
+// ownersForPaths - владельцы по правилам команды. Как и в CODEOWNERS, для файла (или метки) действует последнее
+// подходящее правило. Первыми идут владельцы большего числа затронутых файлов и меток
+func ownersForPaths(rules []*team.PathRule, changedPaths, labels []string) []string {
+	lines := ownershipLines(rules)
+
+	type target struct {
+		value   string
+		isLabel bool
+	}
+	targets := make([]target, 0, len(changedPaths)+len(labels))
+	for _, p := range changedPaths {
+		targets = append(targets, target{value: p})
+	}
+	for _, l := range labels {
+		targets = append(targets, target{value: l, isLabel: true})
+	}
+
+	owned := make(map[string]int)
+	owners := make([]string, 0)
+	for _, t := range targets {
+		for i := len(lines) - 1; i >= 0; i-- {
+			if !matchLine(lines[i], t.value, t.isLabel) {
+				continue
+			}
+			for _, owner := range lines[i].owners {
+				if _, ok := owned[owner]; !ok {
+					owners = append(owners, owner)
+				}
+				owned[owner]++
+			}
+			break
+		}
+	}
+
+	sort.SliceStable(owners, func(i, j int) bool {
+		return owned[owners[i]] > owned[owners[j]]
+	})
+
+	return owners
+}
+
+// pickOwners - владельцы затронутых путей и меток среди подходящих кандидатов, не больше req.Count
+func pickOwners(tx *gorm.DB, req SelectionRequest, changedPaths, labels []string) ([]*user.User, error) {
+	if len(changedPaths)+len(labels) == 0 || req.Count <= 0 {
+		return []*user.User{}, nil
+	}
+
+	var rules []*team.PathRule
+	if err := tx.Where("team_name = ?", req.TeamName).Order("position ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	owners := ownersForPaths(rules, changedPaths, labels)
+	if len(owners) == 0 {
+		return []*user.User{}, nil
+	}
+
+	var eligible []*user.User
+	if err := candidatesQuery(tx, req).
+		Where("users.user_id IN ?", owners).
+		Find(&eligible).Error; err != nil {
+		return nil, err
+	}
+
+	rank := make(map[string]int, len(owners))
+	for i, owner := range owners {
+		rank[owner] = i
+	}
+	sort.SliceStable(eligible, func(i, j int) bool {
+		return rank[eligible[i].UserID] < rank[eligible[j].UserID]
+	})
+
+	if len(eligible) > req.Count {
+		eligible = eligible[:req.Count]
+	}
+
+	return eligible, nil
+}
@@ -2,7 +2,9 @@ package pullrequest_test
 
 import (
 	pullrequest2 "assignerPR/internal/pullrequest"
+	"assignerPR/pkg/team"
 	"assignerPR/pkg/user"
+	"database/sql/driver"
 	"errors"
 	"log"
 	"strings"
@@ -49,6 +51,7 @@ func TestPullRequestsRepoPg_CreatePR(t *testing.T) {
 		prID     string
 		prName   string
 		authorID string
+		opts     pullrequest2.CreatePROptions
 	}
 
 	tests := []struct {
@@ -68,8 +71,10 @@ func TestPullRequestsRepoPg_CreatePR(t *testing.T) {
 					AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
 				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
 
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+
 				m.ExpectExec(`INSERT INTO "pull_requests"`).
-					WithArgs("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+					WithArgs("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, 2, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 
 				candidateRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
@@ -83,8 +88,8 @@ func TestPullRequestsRepoPg_CreatePR(t *testing.T) {
 
 				m.ExpectExec(`INSERT INTO "users"`).
 					WithArgs(
-						"user-456", "reviewer1", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(),
-						"user-789", "reviewer2", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(),
+						"user-456", "reviewer1", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil,
+						"user-789", "reviewer2", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil,
 					).WillReturnResult(sqlmock.NewResult(2, 2))
 
 				m.ExpectExec(`INSERT INTO "pr_reviewers"`).
@@ -104,6 +109,7 @@ func TestPullRequestsRepoPg_CreatePR(t *testing.T) {
 					AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
 					AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime)
 				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
+				expectAssignments(m)
 
 				m.ExpectCommit()
 			},
@@ -118,6 +124,128 @@ func TestPullRequestsRepoPg_CreatePR(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "weighted strategy",
+			args: createPRArgs{prID: "pr-124", prName: "Fix bug", authorID: "user-123"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				authorRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
+
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyWeighted))
+
+				m.ExpectExec(`INSERT INTO "pull_requests"`).
+					WithArgs("pr-124", "Fix bug", "user-123", pullrequest2.StatusOpen, 2, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				candidateRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at", "load"}).
+					AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime, 0).
+					AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime, 3)
+				m.ExpectQuery(`SELECT users.*, COUNT(pr.pull_request_id) FILTER (WHERE pr.status = $1) AS load`).WillReturnRows(candidateRows)
+
+				m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).
+					WithArgs(sqlmock.AnyArg(), "pr-124").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`INSERT INTO "pr_reviewers"`).WillReturnResult(sqlmock.NewResult(2, 2))
+
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-124", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+
+				linkRows := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+					AddRow("pr-124", "user-456").
+					AddRow("pr-124", "user-789")
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(linkRows)
+
+				assignedRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+					AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
+				expectAssignments(m)
+
+				m.ExpectCommit()
+			},
+			wantPR: &pullrequest2.PullRequest{
+				PullRequestID:   "pr-124",
+				PullRequestName: "Fix bug",
+				AuthorID:        "user-123",
+				Status:          pullrequest2.StatusOpen,
+				AssignedReviewers: []*user.User{
+					{UserID: "user-456", Username: "reviewer1", TeamName: "backend", IsActive: true},
+					{UserID: "user-789", Username: "reviewer2", TeamName: "backend", IsActive: true},
+				},
+			},
+		},
+		{
+			name: "reviewers required override",
+			args: createPRArgs{
+				prID: "pr-125", prName: "Fix bug", authorID: "user-123",
+				opts: pullrequest2.CreatePROptions{ReviewersRequired: 3},
+			},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				authorRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
+
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+
+				m.ExpectExec(`INSERT INTO "pull_requests"`).
+					WithArgs("pr-125", "Fix bug", "user-123", pullrequest2.StatusOpen, 3, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				candidateRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+					AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime).
+					AddRow("user-999", "reviewer3", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT "users"."user_id"`).
+					WithArgs("backend", "user-123", pullrequest2.StatusOpen, pullrequest2.StatusOpen, int64(3)).
+					WillReturnRows(candidateRows)
+
+				m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(3, 3))
+				m.ExpectExec(`INSERT INTO "pr_reviewers"`).
+					WithArgs("pr-125", "user-456", "pr-125", "user-789", "pr-125", "user-999").
+					WillReturnResult(sqlmock.NewResult(3, 3))
+
+				prRows := sqlmock.NewRows([]string{
+					"pull_request_id", "pull_request_name", "author_id", "status", "reviewers_required",
+					"created_at", "updated_at", "merged_at",
+				}).AddRow("pr-125", "Fix bug", "user-123", pullrequest2.StatusOpen, 3, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+
+				linkRows := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+					AddRow("pr-125", "user-456").
+					AddRow("pr-125", "user-789").
+					AddRow("pr-125", "user-999")
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(linkRows)
+
+				assignedRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+					AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime).
+					AddRow("user-999", "reviewer3", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
+				expectAssignments(m)
+
+				m.ExpectCommit()
+			},
+			wantPR: &pullrequest2.PullRequest{
+				PullRequestID:     "pr-125",
+				PullRequestName:   "Fix bug",
+				AuthorID:          "user-123",
+				Status:            pullrequest2.StatusOpen,
+				ReviewersRequired: 3,
+				AssignedReviewers: []*user.User{
+					{UserID: "user-456", Username: "reviewer1", TeamName: "backend", IsActive: true},
+					{UserID: "user-789", Username: "reviewer2", TeamName: "backend", IsActive: true},
+					{UserID: "user-999", Username: "reviewer3", TeamName: "backend", IsActive: true},
+				},
+			},
+		},
 		{
 			name: "author not found",
 			args: createPRArgs{prID: "pr-404", prName: "Fix bug", authorID: "unknown"},
@@ -128,6 +256,18 @@ func TestPullRequestsRepoPg_CreatePR(t *testing.T) {
 			},
 			wantErr: pullrequest2.ErrPRNotFound,
 		},
+		{
+			name: "offboarded author",
+			args: createPRArgs{prID: "pr-123", prName: "Fix bug", authorID: "user-123"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				authorRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "offboarded_at"}).
+					AddRow("user-123", "author", "backend", false, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrAuthorInactive,
+		},
 		{
 			name: "pr already exists",
 			args: createPRArgs{prID: "pr-123", prName: "Fix bug", authorID: "user-123"},
@@ -138,14 +278,49 @@ func TestPullRequestsRepoPg_CreatePR(t *testing.T) {
 					AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
 				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
 
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+
 				m.ExpectExec(`INSERT INTO "pull_requests"`).
-					WithArgs("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+					WithArgs("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, 2, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil).
 					WillReturnError(errors.New("SQLSTATE 23505"))
 
 				m.ExpectRollback()
 			},
 			wantErr: pullrequest2.ErrPRExists,
 		},
+		{
+			name: "draft gets no reviewers",
+			args: createPRArgs{prID: "pr-126", prName: "WIP", authorID: "user-123",
+				opts: pullrequest2.CreatePROptions{IsDraft: true}},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				authorRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+
+				m.ExpectExec(`INSERT INTO "pull_requests"`).
+					WithArgs("pr-126", "WIP", "user-123", pullrequest2.StatusDraft, 2, nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-126", "WIP", "user-123", pullrequest2.StatusDraft, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+				expectAssignments(m)
+
+				m.ExpectCommit()
+			},
+			wantPR: &pullrequest2.PullRequest{
+				PullRequestID:     "pr-126",
+				PullRequestName:   "WIP",
+				AuthorID:          "user-123",
+				Status:            pullrequest2.StatusDraft,
+				AssignedReviewers: []*user.User{},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -158,7 +333,7 @@ func TestPullRequestsRepoPg_CreatePR(t *testing.T) {
 				tt.mockFunc(mock)
 			}
 
-			got, err := repo.CreatePR(tt.args.prID, tt.args.prName, tt.args.authorID)
+			got, err := repo.CreatePR(tt.args.prID, tt.args.prName, tt.args.authorID, tt.args.opts)
 
 			if tt.wantErr != nil {
 				require.ErrorIs(t, err, tt.wantErr)
@@ -174,9 +349,441 @@ func TestPullRequestsRepoPg_CreatePR(t *testing.T) {
 	}
 }
 
+func TestLoadFormula_Expr(t *testing.T) {
+	tests := []struct {
+		name     string
+		formula  pullrequest2.LoadFormula
+		wantSQL  string
+		wantVars []interface{}
+	}{
+		{
+			name:     "open only by default",
+			formula:  pullrequest2.LoadFormula{},
+			wantSQL:  "COUNT(pr.pull_request_id) FILTER (WHERE pr.status = ?)",
+			wantVars: []interface{}{pullrequest2.StatusOpen},
+		},
+		{
+			name:     "window without weight is ignored",
+			formula:  pullrequest2.LoadFormula{MergedWindow: time.Hour},
+			wantSQL:  "COUNT(pr.pull_request_id) FILTER (WHERE pr.status = ?)",
+			wantVars: []interface{}{pullrequest2.StatusOpen},
+		},
+		{
+			name:    "decaying merged window",
+			formula: pullrequest2.LoadFormula{MergedWindow: 72 * time.Hour, MergedWeight: 0.5},
+			wantSQL: "COUNT(pr.pull_request_id) FILTER (WHERE pr.status = ?) + ? * COALESCE(SUM(" +
+				"1 - EXTRACT(EPOCH FROM NOW() - pr.merged_at) / ?) FILTER (WHERE pr.status = ? " +
+				"AND pr.merged_at > NOW() - make_interval(secs => ?)), 0)",
+			wantVars: []interface{}{pullrequest2.StatusOpen, 0.5, 259200.0, pullrequest2.StatusMerged, 259200.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr := tt.formula.Expr()
+			require.Equal(t, tt.wantSQL, expr.SQL)
+			require.Equal(t, tt.wantVars, expr.Vars)
+		})
+	}
+}
+
+func TestPullRequestsRepoPg_CreatePR_LoadFormula(t *testing.T) {
+	fixedTime := time.Now()
+
+	tests := []struct {
+		name     string
+		formula  pullrequest2.LoadFormula
+		wantArgs []driver.Value
+	}{
+		{
+			name:     "open reviews only",
+			formula:  pullrequest2.LoadFormula{},
+			wantArgs: []driver.Value{"backend", "user-123", pullrequest2.StatusOpen, pullrequest2.StatusOpen, int64(2)},
+		},
+		{
+			name:    "with merged window",
+			formula: pullrequest2.LoadFormula{MergedWindow: time.Hour, MergedWeight: 0.25},
+			wantArgs: []driver.Value{
+				"backend", "user-123", pullrequest2.StatusOpen,
+				pullrequest2.StatusOpen, 0.25, 3600.0, pullrequest2.StatusMerged, 3600.0,
+				int64(2),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db, pullrequest2.WithLoadFormula(tt.formula))
+
+			mock.ExpectBegin()
+			authorRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+				AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
+			mock.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
+			mock.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+			mock.ExpectExec(`INSERT INTO "pull_requests"`).WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectQuery(`SELECT "users"."user_id"`).
+				WithArgs(tt.wantArgs...).
+				WillReturnError(errors.New("stop"))
+			mock.ExpectRollback()
+
+			_, err := repo.CreatePR("pr-1", "Fix bug", "user-123", pullrequest2.CreatePROptions{})
+			require.Error(t, err)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPullRequestsRepoPg_CreatePR_Rotation(t *testing.T) {
+	fixedTime := time.Now()
+
+	tests := []struct {
+		name          string
+		lastUserID    string
+		wantReviewers []string
+	}{
+		{
+			name:          "continues after cursor",
+			lastUserID:    "user-456",
+			wantReviewers: []string{"user-789", "user-999"},
+		},
+		{
+			name:          "wraps around",
+			lastUserID:    "user-789",
+			wantReviewers: []string{"user-999", "user-456"},
+		},
+		{
+			name:          "fresh cursor",
+			lastUserID:    "",
+			wantReviewers: []string{"user-456", "user-789"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+
+			mock.ExpectBegin()
+			authorRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+				AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
+			mock.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
+			mock.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyRotation))
+			mock.ExpectExec(`INSERT INTO "pull_requests"`).WillReturnResult(sqlmock.NewResult(1, 1))
+
+			mock.ExpectExec(`INSERT INTO "team_rotation_cursors"`).
+				WithArgs("backend", "", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			cursorRows := sqlmock.NewRows([]string{"team_name", "last_user_id", "updated_at"}).
+				AddRow("backend", tt.lastUserID, fixedTime)
+			mock.ExpectQuery(`SELECT * FROM "team_rotation_cursors"`).WillReturnRows(cursorRows)
+
+			candidateRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+				AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+				AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime).
+				AddRow("user-999", "reviewer3", "backend", true, fixedTime, fixedTime)
+			mock.ExpectQuery(`SELECT "users"."user_id"`).WillReturnRows(candidateRows)
+
+			mock.ExpectExec(`UPDATE "team_rotation_cursors" SET "last_user_id"=$1`).
+				WithArgs(tt.wantReviewers[len(tt.wantReviewers)-1], sqlmock.AnyArg(), "backend").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			mock.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+			mock.ExpectExec(`INSERT INTO "pr_reviewers"`).
+				WithArgs("pr-1", tt.wantReviewers[0], "pr-1", tt.wantReviewers[1]).
+				WillReturnResult(sqlmock.NewResult(2, 2))
+
+			prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+				AddRow("pr-1", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+			mock.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+			mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+				WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+			expectAssignments(mock)
+			mock.ExpectCommit()
+
+			_, err := repo.CreatePR("pr-1", "Fix bug", "user-123", pullrequest2.CreatePROptions{})
+			require.NoError(t, err)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Замена при переназначении идет по ротации, но курсор не трогает: ни INSERT, ни блокировки, ни UPDATE
+func TestPullRequestsRepoPg_Reassign_RotationKeepsCursor(t *testing.T) {
+	fixedTime := time.Now()
+
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "reviewers_required", "created_at", "updated_at", "merged_at"}).
+		AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, 2, fixedTime, fixedTime, nil)
+	mock.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+	mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+		AddRow("pr-123", "user-111").
+		AddRow("pr-123", "user-999"))
+	mock.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+		AddRow("user-111", "reviewerA", "backend", true, fixedTime, fixedTime).
+		AddRow("user-999", "reviewerB", "backend", true, fixedTime, fixedTime))
+	expectAssignments(mock)
+	mock.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyRotation))
+
+	mock.ExpectQuery(`SELECT * FROM "team_rotation_cursors" WHERE team_name = $1 LIMIT $2`).
+		WithArgs("backend", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"team_name", "last_user_id", "updated_at"}).
+			AddRow("backend", "user-300", fixedTime))
+	mock.ExpectQuery(`SELECT "users"."user_id"`).WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+		AddRow("user-222", "reviewerC", "backend", true, fixedTime, fixedTime).
+		AddRow("user-333", "reviewerD", "backend", true, fixedTime, fixedTime))
+
+	mock.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectExec(`INSERT INTO "pr_reviewers"`).
+		WithArgs("pr-123", "user-111", "pr-123", "user-333").
+		WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectExec(`DELETE FROM "pr_reviewers"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "status"}).
+		AddRow("pr-123", pullrequest2.StatusOpen))
+	mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+	expectAssignments(mock)
+	mock.ExpectCommit()
+
+	_, added, err := repo.Reassign("pr-123", "user-999", "")
+	require.NoError(t, err)
+	// следующий после курсора user-300
+	require.Equal(t, []string{"user-333"}, added)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMatchPathPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*", path: "internal/pullrequest/utils.go", want: true},
+		{pattern: "*.go", path: "internal/pullrequest/utils.go", want: true},
+		{pattern: "*.go", path: "README.md", want: false},
+		{pattern: "/README.md", path: "README.md", want: true},
+		{pattern: "/README.md", path: "docs/README.md", want: false},
+		{pattern: "README.md", path: "docs/README.md", want: true},
+		{pattern: "docs/", path: "docs/api/openapi.yml", want: true},
+		{pattern: "docs/", path: "docs", want: false},
+		{pattern: "pkg/user", path: "pkg/user/user.go", want: true},
+		{pattern: "pkg/user", path: "internal/pkg/user/user.go", want: false},
+		{pattern: "pkg/*/user.go", path: "pkg/team/user.go", want: true},
+		{pattern: "internal/**/apierr.go", path: "internal/handlers/apierr/apierr.go", want: true},
+		{pattern: "internal/**/apierr.go", path: "internal/apierr.go", want: true},
+		{pattern: "/", path: "README.md", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			require.Equal(t, tt.want, pullrequest2.MatchPathPattern(tt.pattern, tt.path))
+		})
+	}
+}
+
+func TestPullRequestsRepoPg_CreatePR_PathOwners(t *testing.T) {
+	fixedTime := time.Now()
+
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	authorRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+		AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
+	mock.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
+	mock.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+	mock.ExpectExec(`INSERT INTO "pull_requests"`).
+		WithArgs("pr-1", "Docs", "user-123", pullrequest2.StatusOpen, 2, `["docs/a.md","docs/b.md"]`, nil,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	ruleRows := sqlmock.NewRows([]string{"id", "team_name", "position", "pattern", "user_id", "created_at"}).
+		AddRow(1, "backend", 0, "*", "user-456", fixedTime).
+		AddRow(2, "backend", 1, "/docs/", "user-999", fixedTime)
+	mock.ExpectQuery(`SELECT * FROM "team_path_rules"`).WithArgs("backend").WillReturnRows(ruleRows)
+
+	ownerRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+		AddRow("user-999", "docsOwner", "backend", true, fixedTime, fixedTime)
+	mock.ExpectQuery(`SELECT "users"."user_id"`).
+		WithArgs("backend", "user-123", "user-999", pullrequest2.StatusOpen).
+		WillReturnRows(ownerRows)
+
+	restRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+		AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime)
+	mock.ExpectQuery(`SELECT "users"."user_id"`).
+		WithArgs("backend", "user-123", "user-999", pullrequest2.StatusOpen, pullrequest2.StatusOpen, int64(1)).
+		WillReturnRows(restRows)
+
+	mock.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectExec(`INSERT INTO "pr_reviewers"`).
+		WithArgs("pr-1", "user-999", "pr-1", "user-456").
+		WillReturnResult(sqlmock.NewResult(2, 2))
+
+	prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+		AddRow("pr-1", "Docs", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+	mock.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+	mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+	expectAssignments(mock)
+	mock.ExpectCommit()
+
+	_, err := repo.CreatePR("pr-1", "Docs", "user-123", pullrequest2.CreatePROptions{
+		ChangedPaths: []string{"docs/a.md", "docs/b.md"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPullRequestsRepoPg_CreatePR_LabelOwners(t *testing.T) {
+	fixedTime := time.Now()
+
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	authorRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+		AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
+	mock.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
+	mock.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+	mock.ExpectExec(`INSERT INTO "pull_requests"`).
+		WithArgs("pr-1", "Auth", "user-123", pullrequest2.StatusOpen, 1, nil, `["security"]`,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// путь "*" не должен совпасть с меткой, а "label:security" - с путем
+	ruleRows := sqlmock.NewRows([]string{"id", "team_name", "position", "pattern", "user_id", "created_at"}).
+		AddRow(1, "backend", 0, "*", "user-456", fixedTime).
+		AddRow(2, "backend", 1, "label:security", "user-777", fixedTime)
+	mock.ExpectQuery(`SELECT * FROM "team_path_rules"`).WithArgs("backend").WillReturnRows(ruleRows)
+
+	ownerRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+		AddRow("user-777", "secOwner", "backend", true, fixedTime, fixedTime)
+	mock.ExpectQuery(`SELECT "users"."user_id"`).
+		WithArgs("backend", "user-123", "user-777", pullrequest2.StatusOpen).
+		WillReturnRows(ownerRows)
+
+	mock.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "pr_reviewers"`).
+		WithArgs("pr-1", "user-777").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "labels", "created_at", "updated_at", "merged_at"}).
+		AddRow("pr-1", "Auth", "user-123", pullrequest2.StatusOpen, `["security"]`, fixedTime, fixedTime, nil)
+	mock.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+	mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+	expectAssignments(mock)
+	mock.ExpectCommit()
+
+	got, err := repo.CreatePR("pr-1", "Auth", "user-123", pullrequest2.CreatePROptions{
+		ReviewersRequired: 1,
+		Labels:            []string{"security", " security", ""},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"security"}, got.Labels)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPullRequestsRepoPg_CreatePR_Fallback(t *testing.T) {
+	fixedTime := time.Now()
+
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	authorRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+		AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
+	mock.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(authorRows)
+	mock.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+	mock.ExpectExec(`INSERT INTO "pull_requests"`).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	ownRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+		AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime)
+	mock.ExpectQuery(`SELECT "users"."user_id"`).
+		WithArgs("backend", "user-123", pullrequest2.StatusOpen, pullrequest2.StatusOpen, int64(2)).
+		WillReturnRows(ownRows)
+
+	fallbackRows := sqlmock.NewRows([]string{"team_name", "fallback_team_name", "position"}).
+		AddRow("backend", "platform", 0).
+		AddRow("backend", "sre", 1)
+	mock.ExpectQuery(`SELECT * FROM "team_fallbacks"`).WithArgs("backend").WillReturnRows(fallbackRows)
+
+	mock.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("platform", pullrequest2.StrategyRandom))
+	platformRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+		AddRow("user-777", "platformer", "platform", true, fixedTime, fixedTime)
+	mock.ExpectQuery(`SELECT "users"."user_id"`).
+		WithArgs("platform", "user-123", "user-456", pullrequest2.StatusOpen, int64(1)).
+		WillReturnRows(platformRows)
+
+	mock.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+	mock.ExpectExec(`INSERT INTO "pr_reviewers"`).
+		WithArgs("pr-1", "user-456", "pr-1", "user-777").
+		WillReturnResult(sqlmock.NewResult(2, 2))
+
+	prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+		AddRow("pr-1", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+	mock.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+	mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+	expectAssignments(mock)
+	mock.ExpectCommit()
+
+	_, err := repo.CreatePR("pr-1", "Fix bug", "user-123", pullrequest2.CreatePROptions{})
+	require.NoError(t, err)
+
+	// вторая запасная команда не понадобилась
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestPullRequestsRepoPg_Merge(t *testing.T) {
 	fixedTime := time.Now()
 
+	// PR с двумя ревьюверами в команде, где для мержа нужно одно одобрение
+	expectGatedPR := func(m sqlmock.Sqlmock) {
+		prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+			AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+			WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+				AddRow("pr-123", "user-456").
+				AddRow("pr-123", "user-789"))
+		m.ExpectQuery(`SELECT * FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+				AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+				AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime))
+		expectAssignments(m)
+
+		m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-123", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+				AddRow("user-123", "author", "backend", true, fixedTime, fixedTime))
+		m.ExpectQuery(`SELECT * FROM "teams"`).
+			WillReturnRows(sqlmock.NewRows([]string{"team_name", "selection_strategy", "reviewers_required", "required_approvals", "created_at", "updated_at"}).
+				AddRow("backend", pullrequest2.StrategyLeastLoaded, 2, 1, fixedTime, fixedTime))
+	}
+
 	tests := []struct {
 		name     string
 		prID     string
@@ -203,6 +810,12 @@ func TestPullRequestsRepoPg_Merge(t *testing.T) {
 					AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
 					AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime)
 				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
+				expectAssignments(m)
+
+				authorRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-123", 1).WillReturnRows(authorRows)
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
 
 				m.ExpectExec(`UPDATE "pull_requests"`).
 					WithArgs(pullrequest2.StatusMerged, sqlmock.AnyArg(), sqlmock.AnyArg(), "pr-123").
@@ -215,6 +828,53 @@ func TestPullRequestsRepoPg_Merge(t *testing.T) {
 				Status:        pullrequest2.StatusMerged,
 			},
 		},
+		{
+			name: "approved",
+			prID: "pr-123",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectGatedPR(m)
+				m.ExpectQuery(`SELECT DISTINCT ON (user_id) * FROM "pr_reviews"`).
+					WithArgs("pr-123", "user-456", "user-789").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "pull_request_id", "user_id", "verdict", "created_at"}).
+						AddRow(3, "pr-123", "user-456", pullrequest2.VerdictApproved, fixedTime).
+						AddRow(4, "pr-123", "user-789", pullrequest2.VerdictCommented, fixedTime))
+				m.ExpectExec(`UPDATE "pull_requests"`).
+					WithArgs(pullrequest2.StatusMerged, sqlmock.AnyArg(), sqlmock.AnyArg(), "pr-123").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectCommit()
+			},
+			wantPR: &pullrequest2.PullRequest{
+				PullRequestID: "pr-123",
+				Status:        pullrequest2.StatusMerged,
+			},
+		},
+		{
+			name: "changes requested",
+			prID: "pr-123",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectGatedPR(m)
+				m.ExpectQuery(`SELECT DISTINCT ON (user_id) * FROM "pr_reviews"`).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "pull_request_id", "user_id", "verdict", "created_at"}).
+						AddRow(3, "pr-123", "user-456", pullrequest2.VerdictApproved, fixedTime).
+						AddRow(4, "pr-123", "user-789", pullrequest2.VerdictChangesRequested, fixedTime))
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrMergeBlocked,
+		},
+		{
+			name: "not enough approvals",
+			prID: "pr-123",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectGatedPR(m)
+				m.ExpectQuery(`SELECT DISTINCT ON (user_id) * FROM "pr_reviews"`).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "pull_request_id", "user_id", "verdict", "created_at"}))
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrMergeBlocked,
+		},
 		{
 			name: "pr not found",
 			prID: "unknown",
@@ -242,6 +902,7 @@ func TestPullRequestsRepoPg_Merge(t *testing.T) {
 				assignedRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
 					AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime)
 				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
+				expectAssignments(m)
 
 				m.ExpectCommit()
 			},
@@ -250,6 +911,23 @@ func TestPullRequestsRepoPg_Merge(t *testing.T) {
 				Status:        pullrequest2.StatusMerged,
 			},
 		},
+		{
+			name: "closed",
+			prID: "pr-123",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusClosed, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+				expectAssignments(m)
+
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrPRClosed,
+		},
 	}
 
 	for _, tt := range tests {
@@ -281,124 +959,152 @@ func TestPullRequestsRepoPg_Merge(t *testing.T) {
 	}
 }
 
-func TestPullRequestsRepoPg_Reassign(t *testing.T) {
+func TestPullRequestsRepoPg_Ready(t *testing.T) {
 	fixedTime := time.Now()
-
-	type reassignArgs struct {
-		prID      string
-		oldUserID string
+	userCols := []string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}
+
+	expectLockedPR := func(m sqlmock.Sqlmock, status string) {
+		prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "reviewers_required", "created_at", "updated_at", "merged_at"}).
+			AddRow("pr-123", "Fix bug", "user-123", status, 2, fixedTime, fixedTime, nil)
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+			WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+		expectAssignments(m)
 	}
 
 	tests := []struct {
-		name         string
-		args         reassignArgs
-		mockFunc     func(sqlmock.Sqlmock)
-		wantErr      error
-		wantPR       *pullrequest2.PullRequest
-		wantReplaced string
+		name     string
+		mockFunc func(sqlmock.Sqlmock)
+		wantErr  error
 	}{
 		{
-			name: "success",
-			args: reassignArgs{prID: "pr-123", oldUserID: "user-999"},
+			name: "assigns reviewers",
 			mockFunc: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusDraft)
 
-				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
-					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
-				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WithArgs("user-123", 1).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-123", "author", "backend", true, fixedTime, fixedTime))
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
 
-				linkRows := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
-					AddRow("pr-123", "user-111").
-					AddRow("pr-123", "user-999")
-				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(linkRows)
+				m.ExpectExec(`UPDATE "pull_requests" SET "status"=$1,"updated_at"=$2`).
+					WithArgs(pullrequest2.StatusOpen, sqlmock.AnyArg(), "pr-123").
+					WillReturnResult(sqlmock.NewResult(1, 1))
 
-				assignedRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
-					AddRow("user-111", "reviewerA", "backend", true, fixedTime, fixedTime).
-					AddRow("user-999", "reviewerB", "backend", true, fixedTime, fixedTime)
-				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
+				m.ExpectQuery(`SELECT "users"."user_id"`).
+					WithArgs("backend", "user-123", pullrequest2.StatusOpen, pullrequest2.StatusOpen, int64(2)).
+					WillReturnRows(sqlmock.NewRows(userCols).
+						AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+						AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime))
+				m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`INSERT INTO "pr_reviewers"`).
+					WithArgs("pr-123", "user-456", "pr-123", "user-789").
+					WillReturnResult(sqlmock.NewResult(2, 2))
 
-				candidateRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
-					AddRow("user-222", "reviewerC", "backend", true, fixedTime, fixedTime)
-				m.ExpectQuery(`SELECT "users"."user_id"`).WillReturnRows(candidateRows)
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "reviewers_required", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, 2, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+						AddRow("pr-123", "user-456").
+						AddRow("pr-123", "user-789"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows(userCols).
+						AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+						AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime))
+				expectAssignments(m)
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "closed",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusClosed)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrPRClosed,
+		},
+	}
 
-				m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).
-					WithArgs(sqlmock.AnyArg(), "pr-123").
-					WillReturnResult(sqlmock.NewResult(1, 1))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
 
-				m.ExpectExec(`INSERT INTO "users"`).
-					WithArgs(
-						"user-111", "reviewerA", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(),
-						"user-222", "reviewerC", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(),
-					).
-					WillReturnResult(sqlmock.NewResult(2, 2))
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
 
-				m.ExpectExec(`INSERT INTO "pr_reviewers"`).
-					WithArgs("pr-123", "user-111", "pr-123", "user-222").
-					WillReturnResult(sqlmock.NewResult(2, 2))
+			got, err := repo.Ready("pr-123")
 
-				m.ExpectExec(`DELETE FROM "pr_reviewers"`).WillReturnResult(sqlmock.NewResult(2, 2))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, pullrequest2.StatusOpen, got.Status)
+				require.Len(t, got.AssignedReviewers, 2)
+			}
 
-				reloadPRRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
-					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
-				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(reloadPRRows)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
 
-				reloadLinks := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
-					AddRow("pr-123", "user-111").
-					AddRow("pr-123", "user-222")
-				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(reloadLinks)
+func TestPullRequestsRepoPg_SubmitReview(t *testing.T) {
+	fixedTime := time.Now()
 
-				reloadUsers := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
-					AddRow("user-111", "reviewerA", "backend", true, fixedTime, fixedTime).
-					AddRow("user-222", "reviewerC", "backend", true, fixedTime, fixedTime)
-				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(reloadUsers)
+	expectLockedPR := func(m sqlmock.Sqlmock, status string) {
+		prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+			AddRow("pr-123", "Fix bug", "user-123", status, fixedTime, fixedTime, nil)
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+			WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).AddRow("pr-123", "user-456"))
+		m.ExpectQuery(`SELECT * FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+				AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime))
+		expectAssignments(m)
+	}
 
+	tests := []struct {
+		name       string
+		reviewerID string
+		mockFunc   func(sqlmock.Sqlmock)
+		wantErr    error
+	}{
+		{
+			name:       "success",
+			reviewerID: "user-456",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`INSERT INTO "pr_reviews"`).
+					WithArgs("pr-123", "user-456", pullrequest2.VerdictApproved, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 				m.ExpectCommit()
 			},
-			wantPR: &pullrequest2.PullRequest{
-				PullRequestID:   "pr-123",
-				PullRequestName: "Fix bug",
-				AuthorID:        "user-123",
-				Status:          pullrequest2.StatusOpen,
-				AssignedReviewers: []*user.User{
-					{UserID: "user-111", Username: "reviewerA", TeamName: "backend", IsActive: true},
-					{UserID: "user-222", Username: "reviewerC", TeamName: "backend", IsActive: true},
-				},
-			},
-			wantReplaced: "user-222",
 		},
 		{
-			name: "pr not found",
-			args: reassignArgs{prID: "missing", oldUserID: "user-999"},
+			name:       "not assigned",
+			reviewerID: "user-999",
 			mockFunc: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
-				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnError(gorm.ErrRecordNotFound)
+				expectLockedPR(m, pullrequest2.StatusOpen)
 				m.ExpectRollback()
 			},
-			wantErr: pullrequest2.ErrPRNotFound,
+			wantErr: pullrequest2.ErrNotAssigned,
 		},
 		{
-			name: "no candidate",
-			args: reassignArgs{prID: "pr-123", oldUserID: "user-999"},
+			name:       "merged",
+			reviewerID: "user-456",
 			mockFunc: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
-
-				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
-					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
-				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
-
-				linkRows := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
-					AddRow("pr-123", "user-999")
-				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(linkRows)
-
-				assignedRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
-					AddRow("user-999", "reviewerB", "backend", true, fixedTime, fixedTime)
-				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
-
-				m.ExpectQuery(`SELECT "users"."user_id"`).WillReturnError(gorm.ErrRecordNotFound)
-
+				expectLockedPR(m, pullrequest2.StatusMerged)
 				m.ExpectRollback()
 			},
-			wantErr: pullrequest2.ErrNoCandidate,
+			wantErr: pullrequest2.ErrPRMerged,
 		},
 	}
 
@@ -408,19 +1114,17 @@ func TestPullRequestsRepoPg_Reassign(t *testing.T) {
 			defer cleanup()
 
 			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
-			if tt.mockFunc != nil {
-				tt.mockFunc(mock)
-			}
+			tt.mockFunc(mock)
 
-			got, replaced, err := repo.Reassign(tt.args.prID, tt.args.oldUserID)
+			got, err := repo.SubmitReview("pr-123", tt.reviewerID, pullrequest2.VerdictApproved)
 
 			if tt.wantErr != nil {
 				require.ErrorIs(t, err, tt.wantErr)
 				require.Nil(t, got)
 			} else {
 				require.NoError(t, err)
-				require.Equal(t, tt.wantReplaced, replaced)
-				assertPR(t, got, tt.wantPR)
+				require.Equal(t, uint64(1), got.ID)
+				require.Equal(t, pullrequest2.VerdictApproved, got.Verdict)
 			}
 
 			require.NoError(t, mock.ExpectationsWereMet())
@@ -428,49 +1132,58 @@ func TestPullRequestsRepoPg_Reassign(t *testing.T) {
 	}
 }
 
-func TestPullRequestsRepoPg_ListPRsByReviewer(t *testing.T) {
+func TestPullRequestsRepoPg_Close(t *testing.T) {
 	fixedTime := time.Now()
 
+	expectLockedPR := func(m sqlmock.Sqlmock, status string) {
+		prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+			AddRow("pr-123", "Fix bug", "user-123", status, fixedTime, fixedTime, nil)
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+			WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).AddRow("pr-123", "user-456"))
+		m.ExpectQuery(`SELECT * FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+				AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime))
+		expectAssignments(m)
+	}
+
 	tests := []struct {
-		name     string
-		userID   string
-		mockFunc func(sqlmock.Sqlmock)
-		wantErr  error
-		wantPRs  []*pullrequest2.PullRequest
+		name       string
+		mockFunc   func(sqlmock.Sqlmock)
+		wantErr    error
+		wantStatus string
+		wantDelta  float64
 	}{
 		{
-			name:   "success",
-			userID: "user-456",
+			name: "success",
 			mockFunc: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
-
-				pluckRows := sqlmock.NewRows([]string{"pull_request_id"}).
-					AddRow("pr-1").
-					AddRow("pr-2")
-				m.ExpectQuery(`SELECT "pull_request_id" FROM "pr_reviewers"`).WillReturnRows(pluckRows)
-
-				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
-					AddRow("pr-1", "Fix bug", "user-111", pullrequest2.StatusOpen, fixedTime, fixedTime, nil).
-					AddRow("pr-2", "Add feature", "user-222", pullrequest2.StatusMerged, fixedTime, fixedTime, fixedTime)
-				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
-
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectExec(`UPDATE "pull_requests" SET "status"=$1,"updated_at"=$2,"closed_at"=$3`).
+					WithArgs(pullrequest2.StatusClosed, sqlmock.AnyArg(), sqlmock.AnyArg(), "pr-123").
+					WillReturnResult(sqlmock.NewResult(1, 1))
 				m.ExpectCommit()
 			},
-			wantPRs: []*pullrequest2.PullRequest{
-				{PullRequestID: "pr-1", PullRequestName: "Fix bug"},
-				{PullRequestID: "pr-2", PullRequestName: "Add feature"},
-			},
+			wantStatus: pullrequest2.StatusClosed,
+			wantDelta:  -1,
 		},
 		{
-			name:   "no prs",
-			userID: "user-456",
+			name: "already closed",
 			mockFunc: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
-				pluckRows := sqlmock.NewRows([]string{"pull_request_id"})
-				m.ExpectQuery(`SELECT "pull_request_id" FROM "pr_reviewers" WHERE user_id`).WillReturnRows(pluckRows)
+				expectLockedPR(m, pullrequest2.StatusClosed)
 				m.ExpectCommit()
 			},
-			wantPRs: []*pullrequest2.PullRequest{},
+			wantStatus: pullrequest2.StatusClosed,
+		},
+		{
+			name: "merged",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusMerged)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrPRMerged,
 		},
 	}
 
@@ -479,58 +1192,156 @@ func TestPullRequestsRepoPg_ListPRsByReviewer(t *testing.T) {
 			db, mock, cleanup := newMockDB(t)
 			defer cleanup()
 
-			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
-			if tt.mockFunc != nil {
-				tt.mockFunc(mock)
-			}
+			var delta float64
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db,
+				pullrequest2.WithOpenPRMetric(func(d float64) { delta += d }))
+			tt.mockFunc(mock)
 
-			got, err := repo.ListPRsByReviewer(tt.userID)
+			got, err := repo.Close("pr-123")
 
 			if tt.wantErr != nil {
 				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
 			} else {
 				require.NoError(t, err)
+				require.Equal(t, tt.wantStatus, got.Status)
 			}
-
-			require.Equal(t, len(tt.wantPRs), len(got))
-			for i := range tt.wantPRs {
-				require.Equal(t, tt.wantPRs[i].PullRequestID, got[i].PullRequestID)
-			}
+			require.Equal(t, tt.wantDelta, delta)
 
 			require.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
 
-func TestPullRequestsRepoPg_GetTeamPRStats(t *testing.T) {
+func TestPullRequestsRepoPg_Reopen(t *testing.T) {
+	fixedTime := time.Now()
+	userCols := []string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}
+
+	expectLockedPR := func(m sqlmock.Sqlmock, status string, reviewers *sqlmock.Rows, links *sqlmock.Rows) {
+		prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "reviewers_required", "created_at", "updated_at", "merged_at"}).
+			AddRow("pr-123", "Fix bug", "user-123", status, 2, fixedTime, fixedTime, nil)
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(links)
+		m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(reviewers)
+		expectAssignments(m)
+	}
+
+	// прежние ревьюверы, которых сейчас можно назначить
+	expectAvailable := func(m sqlmock.Sqlmock, prevIDs []string, availableIDs ...string) {
+		args := make([]driver.Value, 0, len(prevIDs)+1)
+		for _, id := range prevIDs {
+			args = append(args, id)
+		}
+		rows := sqlmock.NewRows([]string{"user_id"})
+		for _, id := range availableIDs {
+			rows.AddRow(id)
+		}
+		m.ExpectQuery(`SELECT "users"."user_id" FROM "users" JOIN teams t ON t.team_name = users.team_name`).
+			WithArgs(append(args, pullrequest2.StatusOpen)...).
+			WillReturnRows(rows)
+	}
+
+	expectReload := func(m sqlmock.Sqlmock, reviewerIDs ...string) {
+		prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "reviewers_required", "created_at", "updated_at", "merged_at"}).
+			AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, 2, fixedTime, fixedTime, nil)
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+		links := sqlmock.NewRows([]string{"pull_request_id", "user_id"})
+		users := sqlmock.NewRows(userCols)
+		for _, id := range reviewerIDs {
+			links.AddRow("pr-123", id)
+			users.AddRow(id, id, "backend", true, fixedTime, fixedTime)
+		}
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(links)
+		m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(users)
+		expectAssignments(m)
+	}
+
 	tests := []struct {
-		name      string
-		teamName  string
-		mockFunc  func(sqlmock.Sqlmock)
-		wantErr   error
-		wantStats []*pullrequest2.UserStats
+		name          string
+		mockFunc      func(sqlmock.Sqlmock)
+		wantErr       error
+		wantReviewers []string
 	}{
 		{
-			name:     "success",
-			teamName: "backend",
+			name: "restores reviewers",
 			mockFunc: func(m sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"user_id", "open_count", "merged_count"}).
-					AddRow("user-456", int64(2), int64(1)).
-					AddRow("user-789", int64(1), int64(3))
-				m.ExpectQuery(`SELECT users.user_id`).WillReturnRows(rows)
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusClosed,
+					sqlmock.NewRows(userCols).
+						AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+						AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime),
+					sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+						AddRow("pr-123", "user-456").
+						AddRow("pr-123", "user-789"))
+				expectAvailable(m, []string{"user-456", "user-789"}, "user-456", "user-789")
+				m.ExpectExec(`UPDATE "pull_requests" SET "status"=$1,"updated_at"=$2,"closed_at"=$3`).
+					WithArgs(pullrequest2.StatusOpen, sqlmock.AnyArg(), nil, "pr-123").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectReload(m, "user-456", "user-789")
+				m.ExpectCommit()
 			},
-			wantStats: []*pullrequest2.UserStats{
-				{UserID: "user-456", OpenCount: 2, MergedCount: 1},
-				{UserID: "user-789", OpenCount: 1, MergedCount: 3},
+			wantReviewers: []string{"user-456", "user-789"},
+		},
+		{
+			// деактивирован, в отпуске или выбрал лимит - решает тот же запрос, что и для новых кандидатов
+			name: "re-picks unavailable reviewer",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusClosed,
+					sqlmock.NewRows(userCols).
+						AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+						AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime),
+					sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+						AddRow("pr-123", "user-456").
+						AddRow("pr-123", "user-789"))
+				expectAvailable(m, []string{"user-456", "user-789"}, "user-456")
+
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WithArgs("user-123", 1).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-123", "author", "backend", true, fixedTime, fixedTime))
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+				m.ExpectQuery(`SELECT "users"."user_id"`).
+					WithArgs("backend", "user-123", "user-456", "user-789", pullrequest2.StatusOpen, pullrequest2.StatusOpen, int64(1)).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-999", "reviewer3", "backend", true, fixedTime, fixedTime))
+
+				m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`INSERT INTO "pr_reviewers"`).
+					WithArgs("pr-123", "user-456", "pr-123", "user-999").
+					WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`DELETE FROM "pr_reviewers"`).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(`UPDATE "pull_requests" SET "status"=$1`).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				expectReload(m, "user-456", "user-999")
+				m.ExpectCommit()
 			},
+			wantReviewers: []string{"user-456", "user-999"},
 		},
 		{
-			name:     "sql error",
-			teamName: "backend",
+			name: "merged",
 			mockFunc: func(m sqlmock.Sqlmock) {
-				m.ExpectQuery(`SELECT users.user_id`).WillReturnError(errors.New("invalid db"))
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusMerged,
+					sqlmock.NewRows(userCols).AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime),
+					sqlmock.NewRows([]string{"pull_request_id", "user_id"}).AddRow("pr-123", "user-456"))
+				m.ExpectRollback()
 			},
-			wantErr: errors.New("invalid db"),
+			wantErr: pullrequest2.ErrPRMerged,
+		},
+		{
+			// черновик открывается через Ready, иначе ревьюверы назначатся в обход него
+			name: "draft",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "reviewers_required", "created_at", "updated_at", "merged_at"}).
+						AddRow("pr-123", "WIP", "user-123", pullrequest2.StatusDraft, 2, fixedTime, fixedTime, nil))
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+				expectAssignments(m)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrPRDraft,
 		},
 	}
 
@@ -540,22 +1351,21 @@ func TestPullRequestsRepoPg_GetTeamPRStats(t *testing.T) {
 			defer cleanup()
 
 			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
-			if tt.mockFunc != nil {
-				tt.mockFunc(mock)
-			}
+			tt.mockFunc(mock)
 
-			got, err := repo.GetTeamPRStats(tt.teamName)
+			got, err := repo.Reopen("pr-123")
 
 			if tt.wantErr != nil {
-				require.Error(t, err)
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
 			} else {
 				require.NoError(t, err)
-				require.Equal(t, len(tt.wantStats), len(got))
-				for i := range tt.wantStats {
-					require.Equal(t, tt.wantStats[i].UserID, got[i].UserID)
-					require.Equal(t, tt.wantStats[i].OpenCount, got[i].OpenCount)
-					require.Equal(t, tt.wantStats[i].MergedCount, got[i].MergedCount)
+				require.Equal(t, pullrequest2.StatusOpen, got.Status)
+				ids := make([]string, 0, len(got.AssignedReviewers))
+				for _, r := range got.AssignedReviewers {
+					ids = append(ids, r.UserID)
 				}
+				require.Equal(t, tt.wantReviewers, ids)
 			}
 
 			require.NoError(t, mock.ExpectationsWereMet())
@@ -563,15 +1373,1434 @@ func TestPullRequestsRepoPg_GetTeamPRStats(t *testing.T) {
 	}
 }
 
-func assertPR(t *testing.T, got, want *pullrequest2.PullRequest) {
-	require.NotNil(t, got)
-	require.NotNil(t, want)
-	require.Equal(t, want.PullRequestID, got.PullRequestID)
-	require.Equal(t, want.PullRequestName, got.PullRequestName)
-	require.Equal(t, want.AuthorID, got.AuthorID)
-	require.Equal(t, want.Status, got.Status)
-	require.Len(t, got.AssignedReviewers, len(want.AssignedReviewers))
-	for i := range want.AssignedReviewers {
-		require.Equal(t, want.AssignedReviewers[i].UserID, got.AssignedReviewers[i].UserID)
+func TestPullRequestsRepoPg_Reassign(t *testing.T) {
+	fixedTime := time.Now()
+
+	type reassignArgs struct {
+		prID      string
+		oldUserID string
+		newUserID string
 	}
+
+	tests := []struct {
+		name      string
+		args      reassignArgs
+		mockFunc  func(sqlmock.Sqlmock)
+		wantErr   error
+		wantPR    *pullrequest2.PullRequest
+		wantAdded []string
+	}{
+		{
+			name: "success",
+			args: reassignArgs{prID: "pr-123", oldUserID: "user-999"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+
+				linkRows := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+					AddRow("pr-123", "user-111").
+					AddRow("pr-123", "user-999")
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(linkRows)
+
+				assignedRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-111", "reviewerA", "backend", true, fixedTime, fixedTime).
+					AddRow("user-999", "reviewerB", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
+				expectAssignments(m)
+
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+
+				candidateRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-222", "reviewerC", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT "users"."user_id"`).WillReturnRows(candidateRows)
+
+				m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).
+					WithArgs(sqlmock.AnyArg(), "pr-123").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+
+				m.ExpectExec(`INSERT INTO "users"`).
+					WithArgs(
+						"user-111", "reviewerA", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil,
+						"user-222", "reviewerC", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil,
+					).
+					WillReturnResult(sqlmock.NewResult(2, 2))
+
+				m.ExpectExec(`INSERT INTO "pr_reviewers"`).
+					WithArgs("pr-123", "user-111", "pr-123", "user-222").
+					WillReturnResult(sqlmock.NewResult(2, 2))
+
+				m.ExpectExec(`DELETE FROM "pr_reviewers"`).WillReturnResult(sqlmock.NewResult(2, 2))
+
+				reloadPRRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(reloadPRRows)
+
+				reloadLinks := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+					AddRow("pr-123", "user-111").
+					AddRow("pr-123", "user-222")
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(reloadLinks)
+
+				reloadUsers := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-111", "reviewerA", "backend", true, fixedTime, fixedTime).
+					AddRow("user-222", "reviewerC", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(reloadUsers)
+				expectAssignments(m)
+
+				m.ExpectCommit()
+			},
+			wantPR: &pullrequest2.PullRequest{
+				PullRequestID:   "pr-123",
+				PullRequestName: "Fix bug",
+				AuthorID:        "user-123",
+				Status:          pullrequest2.StatusOpen,
+				AssignedReviewers: []*user.User{
+					{UserID: "user-111", Username: "reviewerA", TeamName: "backend", IsActive: true},
+					{UserID: "user-222", Username: "reviewerC", TeamName: "backend", IsActive: true},
+				},
+			},
+			wantAdded: []string{"user-222"},
+		},
+		{
+			name: "explicit new reviewer",
+			args: reassignArgs{prID: "pr-123", oldUserID: "user-999", newUserID: "user-333"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+
+				linkRows := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+					AddRow("pr-123", "user-111").
+					AddRow("pr-123", "user-999")
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(linkRows)
+
+				assignedRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-111", "reviewerA", "backend", true, fixedTime, fixedTime).
+					AddRow("user-999", "reviewerB", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
+				expectAssignments(m)
+
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WithArgs("user-333", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+						AddRow("user-333", "chosen", "backend", true, fixedTime, fixedTime))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WithArgs("user-123", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+						AddRow("user-123", "author", "backend", true, fixedTime, fixedTime))
+
+				m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).
+					WithArgs(sqlmock.AnyArg(), "pr-123").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`INSERT INTO "pr_reviewers"`).
+					WithArgs("pr-123", "user-111", "pr-123", "user-333").
+					WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`DELETE FROM "pr_reviewers"`).WillReturnResult(sqlmock.NewResult(1, 1))
+
+				reloadPRRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(reloadPRRows)
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+						AddRow("pr-123", "user-111").
+						AddRow("pr-123", "user-333"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+						AddRow("user-111", "reviewerA", "backend", true, fixedTime, fixedTime).
+						AddRow("user-333", "chosen", "backend", true, fixedTime, fixedTime))
+				expectAssignments(m)
+
+				m.ExpectCommit()
+			},
+			wantPR: &pullrequest2.PullRequest{
+				PullRequestID:   "pr-123",
+				PullRequestName: "Fix bug",
+				AuthorID:        "user-123",
+				Status:          pullrequest2.StatusOpen,
+				AssignedReviewers: []*user.User{
+					{UserID: "user-111", Username: "reviewerA", TeamName: "backend", IsActive: true},
+					{UserID: "user-333", Username: "chosen", TeamName: "backend", IsActive: true},
+				},
+			},
+			wantAdded: []string{"user-333"},
+		},
+		{
+			name: "explicit new reviewer already assigned",
+			args: reassignArgs{prID: "pr-123", oldUserID: "user-999", newUserID: "user-111"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+						AddRow("pr-123", "user-111").
+						AddRow("pr-123", "user-999"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+						AddRow("user-111", "reviewerA", "backend", true, fixedTime, fixedTime).
+						AddRow("user-999", "reviewerB", "backend", true, fixedTime, fixedTime))
+				expectAssignments(m)
+
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrAlreadyAssigned,
+		},
+		{
+			name: "explicit new reviewer inactive",
+			args: reassignArgs{prID: "pr-123", oldUserID: "user-999", newUserID: "user-444"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).AddRow("pr-123", "user-999"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+						AddRow("user-999", "reviewerB", "backend", true, fixedTime, fixedTime))
+				expectAssignments(m)
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WithArgs("user-444", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+						AddRow("user-444", "gone", "backend", false, fixedTime, fixedTime))
+
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrReviewerInactive,
+		},
+		{
+			name: "tops up short PR",
+			args: reassignArgs{prID: "pr-123", oldUserID: "user-999"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				prRows := sqlmock.NewRows([]string{
+					"pull_request_id", "pull_request_name", "author_id", "status", "reviewers_required",
+					"created_at", "updated_at", "merged_at",
+				}).AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, 2, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+
+				linkRows := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+					AddRow("pr-123", "user-999")
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(linkRows)
+
+				assignedRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-999", "reviewerB", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
+				expectAssignments(m)
+
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+
+				candidateRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-222", "reviewerC", "backend", true, fixedTime, fixedTime).
+					AddRow("user-333", "reviewerD", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT "users"."user_id"`).
+					WithArgs("backend", sqlmock.AnyArg(), sqlmock.AnyArg(), pullrequest2.StatusOpen, pullrequest2.StatusOpen, int64(2)).
+					WillReturnRows(candidateRows)
+
+				m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`INSERT INTO "pr_reviewers"`).
+					WithArgs("pr-123", "user-222", "pr-123", "user-333").
+					WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`DELETE FROM "pr_reviewers"`).WillReturnResult(sqlmock.NewResult(1, 1))
+
+				reloadPRRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(reloadPRRows)
+
+				reloadLinks := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+					AddRow("pr-123", "user-222").
+					AddRow("pr-123", "user-333")
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(reloadLinks)
+
+				reloadUsers := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-222", "reviewerC", "backend", true, fixedTime, fixedTime).
+					AddRow("user-333", "reviewerD", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(reloadUsers)
+				expectAssignments(m)
+
+				m.ExpectCommit()
+			},
+			wantPR: &pullrequest2.PullRequest{
+				PullRequestID:   "pr-123",
+				PullRequestName: "Fix bug",
+				AuthorID:        "user-123",
+				Status:          pullrequest2.StatusOpen,
+				AssignedReviewers: []*user.User{
+					{UserID: "user-222", Username: "reviewerC", TeamName: "backend", IsActive: true},
+					{UserID: "user-333", Username: "reviewerD", TeamName: "backend", IsActive: true},
+				},
+			},
+			wantAdded: []string{"user-222", "user-333"},
+		},
+		{
+			name: "pr not found",
+			args: reassignArgs{prID: "missing", oldUserID: "user-999"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnError(gorm.ErrRecordNotFound)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrPRNotFound,
+		},
+		{
+			name: "no candidate",
+			args: reassignArgs{prID: "pr-123", oldUserID: "user-999"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+
+				linkRows := sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+					AddRow("pr-123", "user-999")
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(linkRows)
+
+				assignedRows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+					AddRow("user-999", "reviewerB", "backend", true, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(assignedRows)
+				expectAssignments(m)
+
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+
+				m.ExpectQuery(`SELECT "users"."user_id"`).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}))
+				m.ExpectQuery(`SELECT * FROM "team_fallbacks"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name", "fallback_team_name", "position"}))
+
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrNoCandidate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+			if tt.mockFunc != nil {
+				tt.mockFunc(mock)
+			}
+
+			got, added, err := repo.Reassign(tt.args.prID, tt.args.oldUserID, tt.args.newUserID)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantAdded, added)
+				assertPR(t, got, tt.wantPR)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPullRequestsRepoPg_ListPRsByReviewer(t *testing.T) {
+	fixedTime := time.Now()
+	assigned1 := time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)
+	assigned2 := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	prCols := []string{"pull_request_id", "pull_request_name", "author_id", "status", "labels", "created_at", "updated_at", "merged_at"}
+
+	tests := []struct {
+		name           string
+		userID         string
+		filter         pullrequest2.ReviewerPRsFilter
+		mockFunc       func(sqlmock.Sqlmock)
+		wantErr        error
+		wantPRs        []*pullrequest2.PullRequest
+		wantNextCursor bool
+	}{
+		{
+			name:   "success",
+			userID: "user-456",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				linkRows := sqlmock.NewRows([]string{"pull_request_id", "created_at"}).
+					AddRow("pr-2", assigned1).
+					AddRow("pr-1", assigned2)
+				m.ExpectQuery(`SELECT pr_reviewers.pull_request_id, pr_reviewers.created_at FROM "pr_reviewers" `+
+					`JOIN pull_requests pr ON pr.pull_request_id = pr_reviewers.pull_request_id WHERE pr_reviewers.user_id = $1 `+
+					`ORDER BY pr_reviewers.created_at DESC, pr_reviewers.pull_request_id DESC LIMIT $2`).
+					WithArgs("user-456", pullrequest2.DefaultListLimit+1).
+					WillReturnRows(linkRows)
+
+				// порядок из БД не важен, важен порядок назначения
+				prRows := sqlmock.NewRows(prCols).
+					AddRow("pr-1", "Fix bug", "user-111", pullrequest2.StatusOpen, nil, fixedTime, fixedTime, nil).
+					AddRow("pr-2", "Add feature", "user-222", pullrequest2.StatusMerged, nil, fixedTime, fixedTime, fixedTime)
+				m.ExpectQuery(`SELECT * FROM "pull_requests" WHERE pull_request_id IN ($1,$2)`).
+					WithArgs("pr-2", "pr-1").
+					WillReturnRows(prRows)
+
+				m.ExpectCommit()
+			},
+			wantPRs: []*pullrequest2.PullRequest{
+				{PullRequestID: "pr-2", PullRequestName: "Add feature"},
+				{PullRequestID: "pr-1", PullRequestName: "Fix bug"},
+			},
+		},
+		{
+			name:   "status, label and limit",
+			userID: "user-456",
+			filter: pullrequest2.ReviewerPRsFilter{
+				Status: pullrequest2.StatusOpen,
+				Labels: []string{"security", " security "},
+				Limit:  1,
+			},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+
+				linkRows := sqlmock.NewRows([]string{"pull_request_id", "created_at"}).
+					AddRow("pr-2", assigned1).
+					AddRow("pr-1", assigned2)
+				m.ExpectQuery(`SELECT pr_reviewers.pull_request_id, pr_reviewers.created_at FROM "pr_reviewers" `+
+					`JOIN pull_requests pr ON pr.pull_request_id = pr_reviewers.pull_request_id `+
+					`WHERE pr_reviewers.user_id = $1 AND pr.status = $2 AND pr.labels @> $3 `+
+					`ORDER BY pr_reviewers.created_at DESC, pr_reviewers.pull_request_id DESC LIMIT $4`).
+					WithArgs("user-456", pullrequest2.StatusOpen, `["security"]`, 2).
+					WillReturnRows(linkRows)
+
+				prRows := sqlmock.NewRows(prCols).
+					AddRow("pr-2", "Add feature", "user-222", pullrequest2.StatusOpen, `["security"]`, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests" WHERE pull_request_id IN ($1)`).
+					WithArgs("pr-2").
+					WillReturnRows(prRows)
+
+				m.ExpectCommit()
+			},
+			wantPRs: []*pullrequest2.PullRequest{
+				{PullRequestID: "pr-2", PullRequestName: "Add feature", Labels: []string{"security"}},
+			},
+			wantNextCursor: true,
+		},
+		{
+			name:   "no prs",
+			userID: "user-456",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT pr_reviewers.pull_request_id, pr_reviewers.created_at FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "created_at"}))
+				m.ExpectCommit()
+			},
+			wantPRs: []*pullrequest2.PullRequest{},
+		},
+		{
+			name:    "invalid cursor",
+			userID:  "user-456",
+			filter:  pullrequest2.ReviewerPRsFilter{Cursor: "not a cursor"},
+			wantErr: pullrequest2.ErrInvalidCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+			if tt.mockFunc != nil {
+				tt.mockFunc(mock)
+			}
+
+			got, err := repo.ListPRsByReviewer(tt.userID, tt.filter)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, len(tt.wantPRs), len(got.PullRequests))
+				for i := range tt.wantPRs {
+					require.Equal(t, tt.wantPRs[i].PullRequestID, got.PullRequests[i].PullRequestID)
+					require.Equal(t, tt.wantPRs[i].Labels, got.PullRequests[i].Labels)
+				}
+				require.Equal(t, tt.wantNextCursor, got.NextCursor != "")
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPullRequestsRepoPg_ListPRsByReviewer_Cursor(t *testing.T) {
+	fixedTime := time.Now()
+	assigned1 := time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)
+	assigned2 := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	prCols := []string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}
+
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT pr_reviewers.pull_request_id, pr_reviewers.created_at FROM "pr_reviewers"`).
+		WithArgs("user-456", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "created_at"}).
+			AddRow("pr-3", assigned1).
+			AddRow("pr-2", assigned2))
+	mock.ExpectQuery(`SELECT * FROM "pull_requests"`).
+		WithArgs("pr-3").
+		WillReturnRows(sqlmock.NewRows(prCols).AddRow("pr-3", "c", "user-111", pullrequest2.StatusOpen, fixedTime, fixedTime, nil))
+	mock.ExpectCommit()
+
+	page, err := repo.ListPRsByReviewer("user-456", pullrequest2.ReviewerPRsFilter{Limit: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT pr_reviewers.pull_request_id, pr_reviewers.created_at FROM "pr_reviewers" `+
+		`JOIN pull_requests pr ON pr.pull_request_id = pr_reviewers.pull_request_id `+
+		`WHERE pr_reviewers.user_id = $1 AND (pr_reviewers.created_at, pr_reviewers.pull_request_id) < ($2, $3)`).
+		WithArgs("user-456", assigned1, "pr-3", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "created_at"}).AddRow("pr-2", assigned2))
+	mock.ExpectQuery(`SELECT * FROM "pull_requests"`).
+		WithArgs("pr-2").
+		WillReturnRows(sqlmock.NewRows(prCols).AddRow("pr-2", "b", "user-111", pullrequest2.StatusOpen, fixedTime, fixedTime, nil))
+	mock.ExpectCommit()
+
+	page, err = repo.ListPRsByReviewer("user-456", pullrequest2.ReviewerPRsFilter{Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.PullRequests, 1)
+	require.Equal(t, "pr-2", page.PullRequests[0].PullRequestID)
+	require.Empty(t, page.NextCursor)
+
+	// курсор общего списка PR сюда не подходит
+	mock.ExpectQuery(`SELECT * FROM "pull_requests"`).
+		WillReturnRows(sqlmock.NewRows(prCols).
+			AddRow("pr-1", "a", "user-111", pullrequest2.StatusOpen, fixedTime, fixedTime, nil).
+			AddRow("pr-2", "b", "user-111", pullrequest2.StatusOpen, fixedTime, fixedTime, nil))
+	mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+	expectAssignments(mock)
+	listPage, err := repo.ListPRs(pullrequest2.PRFilter{Limit: 1})
+	require.NoError(t, err)
+
+	_, err = repo.ListPRsByReviewer("user-456", pullrequest2.ReviewerPRsFilter{Cursor: listPage.NextCursor})
+	require.ErrorIs(t, err, pullrequest2.ErrInvalidCursor)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPullRequestsRepoPg_GetTeamPRStats(t *testing.T) {
+	tests := []struct {
+		name      string
+		teamName  string
+		mockFunc  func(sqlmock.Sqlmock)
+		wantErr   error
+		wantStats []*pullrequest2.UserStats
+	}{
+		{
+			name:     "success",
+			teamName: "backend",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT count(*) FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+				rows := sqlmock.NewRows([]string{"user_id", "open_count", "merged_count"}).
+					AddRow("user-456", int64(2), int64(1)).
+					AddRow("user-789", int64(1), int64(3))
+				m.ExpectQuery(`SELECT users.user_id`).WillReturnRows(rows)
+				m.ExpectCommit()
+			},
+			wantStats: []*pullrequest2.UserStats{
+				{UserID: "user-456", OpenCount: 2, MergedCount: 1},
+				{UserID: "user-789", OpenCount: 1, MergedCount: 3},
+			},
+		},
+		{
+			name:     "sql error",
+			teamName: "backend",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT count(*) FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+				m.ExpectQuery(`SELECT users.user_id`).WillReturnError(errors.New("invalid db"))
+				m.ExpectRollback()
+			},
+			wantErr: errors.New("invalid db"),
+		},
+		{
+			name:     "team not found",
+			teamName: "unknown",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT count(*) FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrPRNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+			if tt.mockFunc != nil {
+				tt.mockFunc(mock)
+			}
+
+			got, err := repo.GetTeamPRStats(tt.teamName)
+
+			if tt.wantErr != nil {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, len(tt.wantStats), len(got))
+				for i := range tt.wantStats {
+					require.Equal(t, tt.wantStats[i].UserID, got[i].UserID)
+					require.Equal(t, tt.wantStats[i].OpenCount, got[i].OpenCount)
+					require.Equal(t, tt.wantStats[i].MergedCount, got[i].MergedCount)
+				}
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// expectAssignments - preload назначений (pr_reviewers.team_name), идет после ревьюверов
+func expectAssignments(m sqlmock.Sqlmock, rows ...[]driver.Value) {
+	assignments := sqlmock.NewRows([]string{"pull_request_id", "user_id", "team_name"})
+	for _, r := range rows {
+		assignments.AddRow(r...)
+	}
+	m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(assignments)
+}
+
+func teamRows(teamName, strategy string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"team_name", "selection_strategy", "reviewers_required", "created_at", "updated_at"}).
+		AddRow(teamName, strategy, 2, time.Now(), time.Now())
+}
+
+func assertPR(t *testing.T, got, want *pullrequest2.PullRequest) {
+	require.NotNil(t, got)
+	require.NotNil(t, want)
+	require.Equal(t, want.PullRequestID, got.PullRequestID)
+	require.Equal(t, want.PullRequestName, got.PullRequestName)
+	require.Equal(t, want.AuthorID, got.AuthorID)
+	require.Equal(t, want.Status, got.Status)
+	if want.ReviewersRequired != 0 {
+		require.Equal(t, want.ReviewersRequired, got.ReviewersRequired)
+	}
+	require.Len(t, got.AssignedReviewers, len(want.AssignedReviewers))
+	for i := range want.AssignedReviewers {
+		require.Equal(t, want.AssignedReviewers[i].UserID, got.AssignedReviewers[i].UserID)
+	}
+}
+
+func TestPullRequestsRepoPg_ReleaseReviews(t *testing.T) {
+	fixedTime := time.Now()
+	userCols := []string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}
+
+	expectLockedPR := func(m sqlmock.Sqlmock, prID string, reviewers ...string) {
+		prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "reviewers_required", "created_at", "updated_at", "merged_at"}).
+			AddRow(prID, "Fix bug", "user-123", pullrequest2.StatusOpen, 2, fixedTime, fixedTime, nil)
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+
+		linkRows := sqlmock.NewRows([]string{"pull_request_id", "user_id"})
+		userRows := sqlmock.NewRows(userCols)
+		for _, r := range reviewers {
+			linkRows.AddRow(prID, r)
+			userRows.AddRow(r, r, "backend", r != "user-999", fixedTime, fixedTime)
+		}
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(linkRows)
+		m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(userRows)
+		expectAssignments(m)
+	}
+
+	tests := []struct {
+		name      string
+		mockFunc  func(sqlmock.Sqlmock)
+		wantMoved []pullrequest2.MovedReview
+		wantShort []pullrequest2.ShortPR
+	}{
+		{
+			name: "moved",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT DISTINCT pr_reviewers.pull_request_id FROM "pr_reviewers"`).
+					WithArgs("user-999", pullrequest2.StatusOpen).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id"}).AddRow("pr-1"))
+
+				expectLockedPR(m, "pr-1", "user-111", "user-999")
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+				m.ExpectQuery(`SELECT "users"."user_id"`).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-222", "reviewerC", "backend", true, fixedTime, fixedTime))
+
+				m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`INSERT INTO "pr_reviewers"`).
+					WithArgs("pr-1", "user-111", "pr-1", "user-222").
+					WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`DELETE FROM "pr_reviewers"`).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectCommit()
+			},
+			wantMoved: []pullrequest2.MovedReview{
+				{PullRequestID: "pr-1", FromUserID: "user-999", ReplacedBy: []string{"user-222"}},
+			},
+			wantShort: []pullrequest2.ShortPR{},
+		},
+		{
+			name: "left short",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT DISTINCT pr_reviewers.pull_request_id FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id"}).AddRow("pr-2"))
+
+				expectLockedPR(m, "pr-2", "user-999")
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+				m.ExpectQuery(`SELECT "users"."user_id"`).WillReturnRows(sqlmock.NewRows(userCols))
+				m.ExpectQuery(`SELECT * FROM "team_fallbacks"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name", "fallback_team_name", "position"}))
+
+				m.ExpectExec(`DELETE FROM "pr_reviewers"`).
+					WithArgs("pr-2").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectCommit()
+			},
+			wantMoved: []pullrequest2.MovedReview{
+				{PullRequestID: "pr-2", FromUserID: "user-999", ReplacedBy: []string{}},
+			},
+			wantShort: []pullrequest2.ShortPR{
+				{PullRequestID: "pr-2", Missing: 2, Reason: pullrequest2.ShortNoCandidates},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			report, err := repo.ReleaseReviews([]string{"user-999"})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantMoved, report.Moved)
+			require.Equal(t, tt.wantShort, report.Short)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPullRequestsRepoPg_AddReviewer(t *testing.T) {
+	fixedTime := time.Now()
+	userCols := []string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}
+
+	expectLockedPR := func(m sqlmock.Sqlmock, status string) {
+		prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+			AddRow("pr-123", "Fix bug", "user-123", status, fixedTime, fixedTime, nil)
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+			WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).AddRow("pr-123", "user-456"))
+		m.ExpectQuery(`SELECT * FROM "users"`).
+			WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime))
+		expectAssignments(m)
+	}
+
+	expectAppendAndReload := func(m sqlmock.Sqlmock, added string) {
+		m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		m.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(1, 1))
+		// Append пересохраняет уже загруженных ревьюверов, ON CONFLICT DO NOTHING
+		m.ExpectExec(`INSERT INTO "pr_reviewers"`).
+			WithArgs("pr-123", "user-456", "pr-123", added).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+			AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+			WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+				AddRow("pr-123", "user-456").
+				AddRow("pr-123", added))
+		m.ExpectQuery(`SELECT * FROM "users"`).
+			WillReturnRows(sqlmock.NewRows(userCols).
+				AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+				AddRow(added, "added", "backend", true, fixedTime, fixedTime))
+		expectAssignments(m)
+	}
+
+	authorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(userCols).AddRow("user-123", "author", "backend", true, fixedTime, fixedTime)
+	}
+
+	tests := []struct {
+		name       string
+		reviewerID string
+		mockFunc   func(sqlmock.Sqlmock)
+		wantErr    error
+	}{
+		{
+			name:       "same team",
+			reviewerID: "user-789",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-789", 1).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime))
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-123", 1).WillReturnRows(authorRows())
+				expectAppendAndReload(m, "user-789")
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:       "fallback team",
+			reviewerID: "user-777",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-777", 1).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-777", "platformer", "platform", true, fixedTime, fixedTime))
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-123", 1).WillReturnRows(authorRows())
+				m.ExpectQuery(`SELECT "fallback_team_name" FROM "team_fallbacks"`).
+					WithArgs("backend").
+					WillReturnRows(sqlmock.NewRows([]string{"fallback_team_name"}).AddRow("platform"))
+				expectAppendAndReload(m, "user-777")
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:       "other team",
+			reviewerID: "user-888",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-888", 1).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-888", "stranger", "frontend", true, fixedTime, fixedTime))
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-123", 1).WillReturnRows(authorRows())
+				m.ExpectQuery(`SELECT "fallback_team_name" FROM "team_fallbacks"`).
+					WillReturnRows(sqlmock.NewRows([]string{"fallback_team_name"}))
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrReviewerNotInTeam,
+		},
+		{
+			name:       "author",
+			reviewerID: "user-123",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-123", 1).WillReturnRows(authorRows())
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrReviewerIsAuthor,
+		},
+		{
+			name:       "inactive",
+			reviewerID: "user-789",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-789", 1).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-789", "reviewer2", "backend", false, fixedTime, fixedTime))
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrReviewerInactive,
+		},
+		{
+			name:       "already assigned",
+			reviewerID: "user-456",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrAlreadyAssigned,
+		},
+		{
+			name:       "user not found",
+			reviewerID: "unknown",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("unknown", 1).WillReturnError(gorm.ErrRecordNotFound)
+				m.ExpectRollback()
+			},
+			wantErr: user.ErrUserNotFound,
+		},
+		{
+			name:       "merged",
+			reviewerID: "user-789",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusMerged)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrPRMerged,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.AddReviewer("pr-123", tt.reviewerID)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, got.AssignedReviewers, 2)
+				require.Equal(t, tt.reviewerID, got.AssignedReviewers[1].UserID)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPullRequestsRepoPg_RemoveReviewer(t *testing.T) {
+	fixedTime := time.Now()
+	userCols := []string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}
+
+	expectLockedPR := func(m sqlmock.Sqlmock) {
+		prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+			AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+			WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+				AddRow("pr-123", "user-456").
+				AddRow("pr-123", "user-789"))
+		m.ExpectQuery(`SELECT * FROM "users"`).
+			WillReturnRows(sqlmock.NewRows(userCols).
+				AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+				AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime))
+		expectAssignments(m)
+	}
+
+	tests := []struct {
+		name       string
+		reviewerID string
+		mockFunc   func(sqlmock.Sqlmock)
+		wantErr    error
+	}{
+		{
+			name:       "success",
+			reviewerID: "user-789",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m)
+				m.ExpectExec(`DELETE FROM "pr_reviewers"`).
+					WithArgs("pr-123", "user-789").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnRows(prRows)
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).AddRow("pr-123", "user-456"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime))
+				expectAssignments(m)
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:       "not assigned",
+			reviewerID: "user-999",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrNotAssigned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.RemoveReviewer("pr-123", tt.reviewerID)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, got.AssignedReviewers, 1)
+				require.Equal(t, "user-456", got.AssignedReviewers[0].UserID)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPullRequestsRepoPg_Update(t *testing.T) {
+	fixedTime := time.Now()
+	userCols := []string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}
+	prCols := []string{"pull_request_id", "pull_request_name", "author_id", "status", "labels", "created_at", "updated_at", "merged_at"}
+
+	strPtr := func(s string) *string { return &s }
+
+	expectLockedPR := func(m sqlmock.Sqlmock, status string) {
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).
+			WillReturnRows(sqlmock.NewRows(prCols).AddRow("pr-123", "Fix bug", "user-123", status, nil, fixedTime, fixedTime, nil))
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+			WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).
+				AddRow("pr-123", "user-456").
+				AddRow("pr-123", "user-789"))
+		m.ExpectQuery(`SELECT * FROM "users"`).
+			WillReturnRows(sqlmock.NewRows(userCols).
+				AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime).
+				AddRow("user-789", "reviewer2", "backend", true, fixedTime, fixedTime))
+		expectAssignments(m)
+	}
+
+	expectReload := func(m sqlmock.Sqlmock, name, author, labels string, reviewers ...string) {
+		m.ExpectQuery(`SELECT * FROM "pull_requests"`).
+			WillReturnRows(sqlmock.NewRows(prCols).AddRow("pr-123", name, author, pullrequest2.StatusOpen, labels, fixedTime, fixedTime, nil))
+		links := sqlmock.NewRows([]string{"pull_request_id", "user_id"})
+		users := sqlmock.NewRows(userCols)
+		for _, r := range reviewers {
+			links.AddRow("pr-123", r)
+			users.AddRow(r, r, "backend", true, fixedTime, fixedTime)
+		}
+		m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).WillReturnRows(links)
+		m.ExpectQuery(`SELECT * FROM "users"`).WillReturnRows(users)
+		expectAssignments(m)
+	}
+
+	tests := []struct {
+		name          string
+		upd           pullrequest2.PRUpdate
+		mockFunc      func(sqlmock.Sqlmock)
+		wantErr       error
+		wantName      string
+		wantAuthor    string
+		wantLabels    []string
+		wantReviewers []string
+	}{
+		{
+			name: "rename and labels",
+			upd: pullrequest2.PRUpdate{
+				Name:   strPtr("Fix auth bug"),
+				Labels: &[]string{"security", "security", " backend "},
+			},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectExec(`UPDATE "pull_requests" SET "pull_request_name"=$1,"labels"=$2,"updated_at"=$3`).
+					WithArgs("Fix auth bug", `["security","backend"]`, sqlmock.AnyArg(), "pr-123").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectReload(m, "Fix auth bug", "user-123", `["security","backend"]`, "user-456", "user-789")
+				m.ExpectCommit()
+			},
+			wantName:      "Fix auth bug",
+			wantAuthor:    "user-123",
+			wantLabels:    []string{"security", "backend"},
+			wantReviewers: []string{"user-456", "user-789"},
+		},
+		{
+			name: "new author was a reviewer",
+			upd:  pullrequest2.PRUpdate{AuthorID: strPtr("user-456")},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-456", 1).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime))
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+				m.ExpectQuery(`SELECT "users"."user_id"`).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-222", "reviewer3", "backend", true, fixedTime, fixedTime))
+
+				m.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`INSERT INTO "pr_reviewers"`).
+					WithArgs("pr-123", "user-789", "pr-123", "user-222").
+					WillReturnResult(sqlmock.NewResult(2, 2))
+				m.ExpectExec(`DELETE FROM "pr_reviewers"`).WillReturnResult(sqlmock.NewResult(1, 1))
+
+				m.ExpectExec(`UPDATE "pull_requests" SET "author_id"=$1,"updated_at"=$2`).
+					WithArgs("user-456", sqlmock.AnyArg(), "pr-123").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectReload(m, "Fix bug", "user-456", "null", "user-222", "user-789")
+				m.ExpectCommit()
+			},
+			wantName:      "Fix bug",
+			wantAuthor:    "user-456",
+			wantReviewers: []string{"user-222", "user-789"},
+		},
+		{
+			name: "new author not a reviewer",
+			upd:  pullrequest2.PRUpdate{AuthorID: strPtr("user-999")},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-999", 1).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-999", "newAuthor", "backend", true, fixedTime, fixedTime))
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+				m.ExpectExec(`UPDATE "pull_requests" SET "author_id"=$1,"updated_at"=$2`).
+					WithArgs("user-999", sqlmock.AnyArg(), "pr-123").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectReload(m, "Fix bug", "user-999", "null", "user-456", "user-789")
+				m.ExpectCommit()
+			},
+			wantName:      "Fix bug",
+			wantAuthor:    "user-999",
+			wantReviewers: []string{"user-456", "user-789"},
+		},
+		{
+			name: "unknown author",
+			upd:  pullrequest2.PRUpdate{AuthorID: strPtr("ghost")},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("ghost", 1).WillReturnError(gorm.ErrRecordNotFound)
+				m.ExpectRollback()
+			},
+			wantErr: user.ErrUserNotFound,
+		},
+		{
+			name: "inactive author",
+			upd:  pullrequest2.PRUpdate{AuthorID: strPtr("user-999")},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-999", 1).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-999", "newAuthor", "backend", false, fixedTime, fixedTime))
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrAuthorInactive,
+		},
+		{
+			name: "author without team",
+			upd:  pullrequest2.PRUpdate{AuthorID: strPtr("user-999")},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusOpen)
+				m.ExpectQuery(`SELECT * FROM "users"`).WithArgs("user-999", 1).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-999", "newAuthor", "gone", true, fixedTime, fixedTime))
+				m.ExpectQuery(`SELECT * FROM "teams"`).WithArgs("gone", 1).WillReturnError(gorm.ErrRecordNotFound)
+				m.ExpectRollback()
+			},
+			wantErr: team.ErrTeamNotFound,
+		},
+		{
+			name: "author change on merged PR",
+			upd:  pullrequest2.PRUpdate{AuthorID: strPtr("user-999")},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusMerged)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrPRMerged,
+		},
+		{
+			name: "rename merged PR",
+			upd: pullrequest2.PRUpdate{
+				Name:   strPtr("Fix auth bug"),
+				Labels: &[]string{"security"},
+			},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectLockedPR(m, pullrequest2.StatusMerged)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrPRMerged,
+		},
+		{
+			name: "not found",
+			upd:  pullrequest2.PRUpdate{Name: strPtr("x")},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnError(gorm.ErrRecordNotFound)
+				m.ExpectRollback()
+			},
+			wantErr: pullrequest2.ErrPRNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.Update("pr-123", tt.upd)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantName, got.PullRequestName)
+				require.Equal(t, tt.wantAuthor, got.AuthorID)
+				require.Equal(t, tt.wantLabels, got.Labels)
+
+				reviewers := make([]string, 0, len(got.AssignedReviewers))
+				for _, r := range got.AssignedReviewers {
+					reviewers = append(reviewers, r.UserID)
+				}
+				require.Equal(t, tt.wantReviewers, reviewers)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPullRequestsRepoPg_GetPR(t *testing.T) {
+	fixedTime := time.Now()
+
+	tests := []struct {
+		name     string
+		mockFunc func(sqlmock.Sqlmock)
+		wantErr  error
+		wantTeam string
+	}{
+		{
+			// назначен из запасной команды payments, а потом переведен в backend
+			name: "success",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests" WHERE pull_request_id = $1`).
+					WithArgs("pr-123", 1).
+					WillReturnRows(prRows)
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).AddRow("pr-123", "user-456"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+						AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime))
+				expectAssignments(m, []driver.Value{"pr-123", "user-456", "payments"})
+			},
+			wantTeam: "payments",
+		},
+		{
+			// назначение сделано до появления pr_reviewers.team_name или команду удалили
+			name: "assignment without team",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				prRows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}).
+					AddRow("pr-123", "Fix bug", "user-123", pullrequest2.StatusOpen, fixedTime, fixedTime, nil)
+				m.ExpectQuery(`SELECT * FROM "pull_requests" WHERE pull_request_id = $1`).
+					WithArgs("pr-123", 1).
+					WillReturnRows(prRows)
+				m.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).AddRow("pr-123", "user-456"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}).
+						AddRow("user-456", "reviewer1", "backend", true, fixedTime, fixedTime))
+				expectAssignments(m, []driver.Value{"pr-123", "user-456", nil})
+			},
+			wantTeam: "backend",
+		},
+		{
+			name: "not found",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "pull_requests"`).WillReturnError(gorm.ErrRecordNotFound)
+			},
+			wantErr: pullrequest2.ErrPRNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.GetPR("pr-123")
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "pr-123", got.PullRequestID)
+				require.Len(t, got.AssignedReviewers, 1)
+				require.Equal(t, tt.wantTeam, got.ReviewerTeam(got.AssignedReviewers[0]))
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPullRequestsRepoPg_ListPRs_Pagination(t *testing.T) {
+	t1 := time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)
+	t2 := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	t3 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	prCols := []string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}
+
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+
+	filter := pullrequest2.PRFilter{
+		Status:      pullrequest2.StatusOpen,
+		AuthorID:    "user-123",
+		ReviewerID:  "user-456",
+		TeamName:    "backend",
+		Labels:      []string{"security"},
+		CreatedFrom: &from,
+		Limit:       2,
+	}
+
+	// лишняя строка сверх limit означает, что есть следующая страница
+	mock.ExpectQuery(`SELECT * FROM "pull_requests" WHERE pull_requests.status = $1 AND pull_requests.author_id = $2 `+
+		`AND (EXISTS (SELECT 1 FROM pr_reviewers prr WHERE prr.pull_request_id = pull_requests.pull_request_id AND prr.user_id = $3)) `+
+		`AND (EXISTS (SELECT 1 FROM users a WHERE a.user_id = pull_requests.author_id AND a.team_name = $4)) `+
+		`AND pull_requests.labels @> $5 AND pull_requests.created_at >= $6 `+
+		`ORDER BY pull_requests.created_at desc, pull_requests.pull_request_id desc LIMIT $7`).
+		WithArgs(pullrequest2.StatusOpen, "user-123", "user-456", "backend", `["security"]`, from, 3).
+		WillReturnRows(sqlmock.NewRows(prCols).
+			AddRow("pr-3", "c", "user-123", pullrequest2.StatusOpen, t1, t1, nil).
+			AddRow("pr-2", "b", "user-123", pullrequest2.StatusOpen, t2, t2, nil).
+			AddRow("pr-1", "a", "user-123", pullrequest2.StatusOpen, t3, t3, nil))
+	mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+	expectAssignments(mock)
+
+	page, err := repo.ListPRs(filter)
+	require.NoError(t, err)
+	require.Len(t, page.PullRequests, 2)
+	require.Equal(t, "pr-3", page.PullRequests[0].PullRequestID)
+	require.Equal(t, "pr-2", page.PullRequests[1].PullRequestID)
+	require.NotEmpty(t, page.NextCursor)
+
+	filter.Cursor = page.NextCursor
+	mock.ExpectQuery(`SELECT * FROM "pull_requests" WHERE pull_requests.status = $1`).
+		WithArgs(pullrequest2.StatusOpen, "user-123", "user-456", "backend", `["security"]`, from, t2, "pr-2", 3).
+		WillReturnRows(sqlmock.NewRows(prCols).
+			AddRow("pr-1", "a", "user-123", pullrequest2.StatusOpen, t3, t3, nil))
+	mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+	expectAssignments(mock)
+
+	page, err = repo.ListPRs(filter)
+	require.NoError(t, err)
+	require.Len(t, page.PullRequests, 1)
+	require.Equal(t, "pr-1", page.PullRequests[0].PullRequestID)
+	require.Empty(t, page.NextCursor)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPullRequestsRepoPg_ListPRs_InvalidCursor(t *testing.T) {
+	now := time.Now()
+	prCols := []string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "updated_at", "merged_at"}
+
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectQuery(`SELECT * FROM "pull_requests" ORDER BY pull_requests.updated_at asc, pull_requests.pull_request_id asc LIMIT $1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(prCols).
+			AddRow("pr-1", "a", "user-123", pullrequest2.StatusOpen, now, now, nil).
+			AddRow("pr-2", "b", "user-123", pullrequest2.StatusOpen, now, now, nil))
+	mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}))
+	expectAssignments(mock)
+
+	page, err := repo.ListPRs(pullrequest2.PRFilter{SortBy: pullrequest2.SortByUpdatedAt, Order: pullrequest2.SortAsc, Limit: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	tests := []struct {
+		name   string
+		filter pullrequest2.PRFilter
+	}{
+		{name: "garbage", filter: pullrequest2.PRFilter{Cursor: "not a cursor"}},
+		{name: "other sort", filter: pullrequest2.PRFilter{Cursor: page.NextCursor}},
+		{name: "other order", filter: pullrequest2.PRFilter{SortBy: pullrequest2.SortByUpdatedAt, Cursor: page.NextCursor}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.ListPRs(tt.filter)
+			require.ErrorIs(t, err, pullrequest2.ErrInvalidCursor)
+			require.Nil(t, got)
+		})
+	}
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPullRequestsRepoPg_ReleaseTeamReviews(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	fixedTime := time.Now()
+	userCols := []string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}
+	repo := pullrequest2.NewPullRequestsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	// снимаются только ревью PR авторов из старой команды
+	mock.ExpectQuery(`SELECT DISTINCT pr_reviewers.pull_request_id FROM "pr_reviewers" JOIN pull_requests pr`).
+		WithArgs("user-999", pullrequest2.StatusOpen, "backend").
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id"}).AddRow("pr-1"))
+
+	mock.ExpectQuery(`SELECT * FROM "pull_requests"`).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "reviewers_required", "created_at", "updated_at", "merged_at"}).
+			AddRow("pr-1", "Fix bug", "user-123", pullrequest2.StatusOpen, 1, fixedTime, fixedTime, nil))
+	mock.ExpectQuery(`SELECT * FROM "pr_reviewers"`).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "user_id"}).AddRow("pr-1", "user-999"))
+	// пользователь уже в новой команде, но замена ищется в старой
+	mock.ExpectQuery(`SELECT * FROM "users"`).
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-999", "mover", "frontend", true, fixedTime, fixedTime))
+	expectAssignments(mock)
+	mock.ExpectQuery(`SELECT * FROM "teams"`).
+		WithArgs("backend", 1).
+		WillReturnRows(teamRows("backend", pullrequest2.StrategyLeastLoaded))
+	mock.ExpectQuery(`SELECT "users"."user_id"`).
+		WillReturnRows(sqlmock.NewRows(userCols).AddRow("user-222", "reviewerC", "backend", true, fixedTime, fixedTime))
+
+	mock.ExpectExec(`UPDATE "pull_requests" SET "updated_at"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO "pr_reviewers"`).
+		WithArgs("pr-1", "user-222").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`DELETE FROM "pr_reviewers"`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	report, err := repo.ReleaseTeamReviews("user-999", "backend")
+	require.NoError(t, err)
+	require.Equal(t, []pullrequest2.MovedReview{
+		{PullRequestID: "pr-1", FromUserID: "user-999", ReplacedBy: []string{"user-222"}},
+	}, report.Moved)
+	require.Empty(t, report.Short)
+
+	require.NoError(t, mock.ExpectationsWereMet())
 }
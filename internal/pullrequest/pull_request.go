@@ -9,20 +9,32 @@ import (
 const (
 	StatusOpen   = "OPEN"
 	StatusMerged = "MERGED"
+	StatusClosed = "CLOSED"
+	// StatusDraft - ревьюверы не назначаются до перехода в OPEN через Ready
+	StatusDraft = "DRAFT"
 )
 
-// MaxReviewersPerPR Можно бы было вынести в .env для легкой модификации, но тогда вообще уже лучше
-// раскатывать удаленный сервис для хранения секретов / конфигов по типу vault для автономности таких изменений
-// и мгновенного вступления в силу.
-// В текущей ситуации считаю достаточным оставить константой
-const MaxReviewersPerPR = 2
-
 var (
 	ErrPRExists    = errors.New("PR_EXISTS")
 	ErrPRMerged    = errors.New("PR_MERGED")
+	ErrPRClosed    = errors.New("PR_CLOSED")
+	ErrPRDraft     = errors.New("PR_DRAFT")
 	ErrPRNotFound  = errors.New("PR_NOT_FOUND")
 	ErrNotAssigned = errors.New("PR_NOT_ASSIGNED")
 	ErrNoCandidate = errors.New("PR_NO_CANDIDATE")
+
+	// ErrMergeBlocked - не хватает одобрений или есть неснятый CHANGES_REQUESTED
+	ErrMergeBlocked = errors.New("PR_MERGE_BLOCKED")
+	// ErrAlreadyAssigned - ручное добавление уже назначенного ревьювера
+	ErrAlreadyAssigned = errors.New("PR_ALREADY_ASSIGNED")
+	// Почему выбранного вручную ревьювера нельзя назначить
+	ErrReviewerIsAuthor  = errors.New("PR_REVIEWER_IS_AUTHOR")
+	ErrReviewerInactive  = errors.New("PR_REVIEWER_INACTIVE")
+	ErrReviewerNotInTeam = errors.New("PR_REVIEWER_NOT_IN_TEAM")
+	// ErrAuthorInactive - PR передается деактивированному пользователю или заводится от имени уволенного
+	ErrAuthorInactive = errors.New("PR_AUTHOR_INACTIVE")
+	// ErrInvalidCursor - курсор не декодируется или выдан для другой сортировки
+	ErrInvalidCursor = errors.New("PR_INVALID_CURSOR")
 )
 
 type PullRequest struct {
@@ -30,10 +42,32 @@ type PullRequest struct {
 	PullRequestName   string       `gorm:"type:varchar(255);not null;column:pull_request_name"`
 	AuthorID          string       `gorm:"type:varchar(64);index;not null;column:author_id"`
 	Status            string       `gorm:"type:pull_request_status;not null;default:OPEN;index"`
+	ReviewersRequired int          `gorm:"not null;default:2;column:reviewers_required"`
+	ChangedPaths      []string     `gorm:"serializer:json;type:jsonb;column:changed_paths"`
+	Labels            []string     `gorm:"serializer:json;type:jsonb;column:labels"`
 	AssignedReviewers []*user.User `gorm:"many2many:pr_reviewers;joinForeignKey:PullRequestID;joinReferences:UserID"`
 	CreatedAt         time.Time    `gorm:"column:created_at"`
 	UpdatedAt         time.Time    `gorm:"column:updated_at"`
 	MergedAt          *time.Time   `gorm:"column:merged_at"`
+	ClosedAt          *time.Time   `gorm:"column:closed_at"`
+	// Assignments - те же строки pr_reviewers, нужны ради команды, из которой назначен каждый ревьювер
+	Assignments []*PRReviewer `gorm:"foreignKey:PullRequestID;references:PullRequestID"`
+}
+
+// MissingReviewers - сколько ревьюверов не хватает до ReviewersRequired
+func (pr *PullRequest) MissingReviewers() int {
+	return max(0, pr.ReviewersRequired-len(pr.AssignedReviewers))
+}
+
+// ReviewerTeam - команда, из которой назначен ревьювер (своя или запасная). Если назначение ее не помнит,
+// то текущая команда пользователя
+func (pr *PullRequest) ReviewerTeam(reviewer *user.User) string {
+	for _, a := range pr.Assignments {
+		if a.UserID == reviewer.UserID && a.TeamName != nil {
+			return *a.TeamName
+		}
+	}
+	return reviewer.TeamName
 }
 
 // UserStats - статистика для юзера, относится к дополнительному заданию - сделал статистику PR для членов команды
@@ -43,10 +77,36 @@ type UserStats struct {
 	MergedCount int
 }
 
+// CreatePROptions - необязательные параметры создания PR, нулевые значения = настройки команды автора
+type CreatePROptions struct {
+	ReviewersRequired int
+	ChangedPaths      []string
+	Labels            []string
+	IsDraft           bool
+}
+
+// PRUpdate - частичное обновление метаданных PR, nil поля не трогаются
+type PRUpdate struct {
+	Name     *string
+	Labels   *[]string
+	AuthorID *string
+}
+
 type PullRequestsRepo interface {
-	CreatePR(prID, prName, authorID string) (*PullRequest, error)
+	CreatePR(prID, prName, authorID string, opts CreatePROptions) (*PullRequest, error)
 	Merge(prID string) (*PullRequest, error)
-	Reassign(prID, oldUserID string) (pr *PullRequest, replacedBy string, err error)
-	ListPRsByReviewer(userID string) ([]*PullRequest, error)
+	Close(prID string) (*PullRequest, error)
+	Ready(prID string) (*PullRequest, error)
+	Reopen(prID string) (*PullRequest, error)
+	SubmitReview(prID, reviewerID, verdict string) (*Review, error)
+	Update(prID string, upd PRUpdate) (*PullRequest, error)
+	Reassign(prID, oldUserID, newUserID string) (pr *PullRequest, added []string, err error)
+	AddReviewer(prID, userID string) (*PullRequest, error)
+	RemoveReviewer(prID, userID string) (*PullRequest, error)
+	GetPR(prID string) (*PullRequest, error)
+	ListPRs(filter PRFilter) (*PRPage, error)
+	ListPRsByReviewer(userID string, filter ReviewerPRsFilter) (*PRPage, error)
 	GetTeamPRStats(teamName string) ([]*UserStats, error)
+	ReleaseReviews(userIDs []string) (*ReleaseReport, error)
+	ReleaseTeamReviews(userID, teamName string) (*ReleaseReport, error)
 }
@@ -2,8 +2,10 @@ package pullrequest
 
 import (
 	"assignerPR/internal/metrics"
+	"assignerPR/pkg/team"
 	"assignerPR/pkg/user"
 	"errors"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -16,34 +18,66 @@ import (
 type PRReviewer struct {
 	PullRequestID string `gorm:"column:pull_request_id"`
 	UserID        string `gorm:"column:user_id"`
+	// CreatedAt - время назначения, проставляется БД (DEFAULT NOW()), поэтому только на чтение
+	CreatedAt time.Time `gorm:"column:created_at;->"`
+	// TeamName - команда, из которой назначен ревьювер, проставляется триггером БД при назначении.
+	// nil, если команду с тех пор удалили
+	TeamName *string `gorm:"column:team_name;->"`
 }
 
 type PullRequestsRepoPg struct {
-	logger *zap.SugaredLogger
-	db     *gorm.DB
+	logger      *zap.SugaredLogger
+	db          *gorm.DB
+	loadFormula LoadFormula
+	selectors   map[string]ReviewerSelector
+	addOpenPR   func(delta float64)
 }
 
-func NewPullRequestsRepoPg(logger *zap.SugaredLogger, db *gorm.DB) *PullRequestsRepoPg {
-	return &PullRequestsRepoPg{
-		logger: logger,
-		db:     db,
+type Option func(repo *PullRequestsRepoPg)
+
+func WithLoadFormula(formula LoadFormula) Option {
+	return func(repo *PullRequestsRepoPg) {
+		repo.loadFormula = formula
+	}
+}
+
+// WithOpenPRMetric - куда писать изменения open_prs, когда репозиторий работает внутри чужой транзакции
+func WithOpenPRMetric(add func(delta float64)) Option {
+	return func(repo *PullRequestsRepoPg) {
+		repo.addOpenPR = add
+	}
+}
+
+func NewPullRequestsRepoPg(logger *zap.SugaredLogger, db *gorm.DB, opts ...Option) *PullRequestsRepoPg {
+	repo := &PullRequestsRepoPg{
+		logger:    logger,
+		db:        db,
+		addOpenPR: metrics.AddOpenPR,
+	}
+
+	for _, opt := range opts {
+		opt(repo)
 	}
+	repo.selectors = defaultSelectors(repo.loadFormula)
+
+	return repo
 }
 
-func (repo *PullRequestsRepoPg) CreatePR(prID, prName, authorID string) (*PullRequest, error) {
+func (repo *PullRequestsRepoPg) CreatePR(prID, prName, authorID string, opts CreatePROptions) (*PullRequest, error) {
 	repo.logger.Debugw("CreatePR()", "prID", prID, "authorID", authorID)
 
 	var dbTxErr error
 
 	start := time.Now()
+	var pr *PullRequest
+
 	defer func() {
 		metrics.ObservePROp("create_pr", start, dbTxErr)
-		if dbTxErr == nil {
-			metrics.AddOpenPR(1)
+		if dbTxErr == nil && pr.Status == StatusOpen {
+			repo.addOpenPR(1)
 		}
 	}()
 
-	var pr *PullRequest
 	dbTxErr = repo.db.Transaction(func(tx *gorm.DB) error {
 		var author user.User
 		if err := tx.First(&author, "user_id = ?", authorID).Error; err != nil {
@@ -56,12 +90,36 @@ func (repo *PullRequestsRepoPg) CreatePR(prID, prName, authorID string) (*PullRe
 			repo.logger.Errorw("Error finding author", "prID", prID, "authorID", authorID)
 			return err
 		}
+		// надгробие уволенного остается ради истории, новых PR у него быть не может
+		if author.OffboardedAt != nil {
+			repo.logger.Warnw("Author is offboarded", "prID", prID, "authorID", authorID)
+			return ErrAuthorInactive
+		}
+
+		authorTeam, err := repo.loadTeamInTx(tx, author.TeamName)
+		if err != nil {
+			repo.logger.Errorw("Error loading author team", "prID", prID, "authorID", authorID)
+			return err
+		}
+
+		reviewersRequired := authorTeam.ReviewersRequired
+		if opts.ReviewersRequired > 0 {
+			reviewersRequired = opts.ReviewersRequired
+		}
+
+		status := StatusOpen
+		if opts.IsDraft {
+			status = StatusDraft
+		}
 
 		pr = &PullRequest{
-			PullRequestID:   prID,
-			PullRequestName: prName,
-			AuthorID:        authorID,
-			Status:          StatusOpen,
+			PullRequestID:     prID,
+			PullRequestName:   prName,
+			AuthorID:          authorID,
+			Status:            status,
+			ReviewersRequired: reviewersRequired,
+			ChangedPaths:      opts.ChangedPaths,
+			Labels:            normalizeLabels(opts.Labels),
 		}
 
 		if err := tx.Create(pr).Error; err != nil {
@@ -75,22 +133,15 @@ func (repo *PullRequestsRepoPg) CreatePR(prID, prName, authorID string) (*PullRe
 			return err
 		}
 
-		reviewers, err := repo.pickInitialReviewersInTx(tx, author.TeamName, authorID)
-		if err != nil {
-			repo.logger.Errorw("Error picking initial reviewers", "prID", prID, "authorID", authorID)
-			return err
-		}
-
-		if len(reviewers) > 0 {
-			if err := tx.Model(pr).Association("AssignedReviewers").Append(reviewers); err != nil {
-				repo.logger.Errorw("Error appending reviewers", "prID", prID, "authorID", authorID)
+		if !opts.IsDraft {
+			if err := repo.assignInitialReviewersInTx(tx, authorTeam, pr); err != nil {
 				return err
 			}
 		}
 
 		return tx.Preload("AssignedReviewers", func(tx2 *gorm.DB) *gorm.DB {
 			return tx2.Order("users.user_id ASC")
-		}).First(pr, "pull_request_id = ?", prID).Error
+		}).Preload("Assignments").First(pr, "pull_request_id = ?", prID).Error
 	})
 
 	if dbTxErr != nil {
@@ -98,30 +149,149 @@ func (repo *PullRequestsRepoPg) CreatePR(prID, prName, authorID string) (*PullRe
 		return nil, dbTxErr
 	}
 
+	if missing := pr.MissingReviewers(); missing > 0 && pr.Status == StatusOpen {
+		repo.logger.Warnw("PR created with partial assignment", "prID", prID, "missing", missing)
+	}
+
 	repo.logger.Debugw("PR created", "prID", prID, "authorID", authorID)
 	return pr, nil
 }
 
-func (repo *PullRequestsRepoPg) pickInitialReviewersInTx(tx *gorm.DB, teamName, authorID string) ([]*user.User, error) {
-	repo.logger.Debugw("pickInitialReviewersInTx()", "teamName", teamName, "authorID", authorID)
-
-	var reviewers []*user.User
-	// Maybe тут можно было как-то покрасивее написать запрос с использованием самих моделей,
-	// но я до красивого варианта не дошел.
-	err := tx.
-		Joins("LEFT JOIN pr_reviewers prr ON prr.user_id = users.user_id").
-		Joins("LEFT JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id AND pr.status = ?", StatusOpen).
-		Where("users.team_name = ? AND users.is_active = TRUE AND users.user_id <> ?", teamName, authorID).
-		Group("users.user_id").
-		Order("COUNT(prr.user_id) ASC").
-		Order("RANDOM()").
-		Limit(MaxReviewersPerPR).
-		Find(&reviewers).Error
+func (repo *PullRequestsRepoPg) assignInitialReviewersInTx(tx *gorm.DB, authorTeam *team.Team, pr *PullRequest) error {
+	reviewers, err := repo.pickInitialReviewersInTx(tx, authorTeam, pr)
+	if err != nil {
+		repo.logger.Errorw("Error picking initial reviewers", "prID", pr.PullRequestID, "authorID", pr.AuthorID)
+		return err
+	}
+
+	if len(reviewers) > 0 {
+		if err := tx.Model(pr).Association("AssignedReviewers").Append(reviewers); err != nil {
+			repo.logger.Errorw("Error appending reviewers", "prID", pr.PullRequestID, "authorID", pr.AuthorID)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *PullRequestsRepoPg) pickInitialReviewersInTx(
+	tx *gorm.DB,
+	authorTeam *team.Team,
+	pr *PullRequest,
+) ([]*user.User, error) {
+	repo.logger.Debugw("pickInitialReviewersInTx()", "teamName", authorTeam.TeamName, "authorID", pr.AuthorID)
+
+	reviewers, err := repo.selectReviewersInTx(tx, authorTeam, SelectionRequest{
+		TeamName: authorTeam.TeamName,
+		Exclude:  []string{pr.AuthorID},
+		Count:    pr.ReviewersRequired,
+	}, pr.ChangedPaths, pr.Labels)
 
 	repo.logger.Debugw("pickInitialReviewersInTx()", "err", err)
 	return reviewers, err
 }
 
+// selectReviewersInTx - общий путь выбора для создания и переназначения: сначала владельцы затронутых путей и меток,
+// оставшиеся места добирает стратегия команды, а если в команде не хватило людей - запасные команды по порядку
+func (repo *PullRequestsRepoPg) selectReviewersInTx(
+	tx *gorm.DB,
+	t *team.Team,
+	req SelectionRequest,
+	changedPaths []string,
+	labels []string,
+) ([]*user.User, error) {
+	reviewers, err := pickOwners(tx, req, changedPaths, labels)
+	if err != nil {
+		repo.logger.Errorw("error picking path owners", "teamName", t.TeamName, "err", err)
+		return nil, err
+	}
+
+	if len(reviewers) < req.Count {
+		picked, err := repo.selectorFor(t).Select(tx, remainderOf(req, req.TeamName, reviewers))
+		if err != nil {
+			return nil, err
+		}
+		reviewers = append(reviewers, picked...)
+	}
+
+	if len(reviewers) < req.Count {
+		return repo.spillToFallbacksInTx(tx, t.TeamName, req, reviewers)
+	}
+
+	return reviewers, nil
+}
+
+func (repo *PullRequestsRepoPg) spillToFallbacksInTx(
+	tx *gorm.DB,
+	teamName string,
+	req SelectionRequest,
+	reviewers []*user.User,
+) ([]*user.User, error) {
+	var fallbacks []*team.Fallback
+	if err := tx.Where("team_name = ?", teamName).Order("position ASC").Find(&fallbacks).Error; err != nil {
+		repo.logger.Errorw("error loading fallback teams", "teamName", teamName, "err", err)
+		return nil, err
+	}
+
+	for _, fb := range fallbacks {
+		if len(reviewers) >= req.Count {
+			break
+		}
+
+		fallbackTeam, err := repo.loadTeamInTx(tx, fb.FallbackTeamName)
+		if err != nil {
+			return nil, err
+		}
+
+		picked, err := repo.selectorFor(fallbackTeam).Select(tx, remainderOf(req, fb.FallbackTeamName, reviewers))
+		if err != nil {
+			return nil, err
+		}
+
+		repo.logger.Debugw("spilled to fallback team", "teamName", teamName,
+			"fallback", fb.FallbackTeamName, "picked", len(picked))
+		reviewers = append(reviewers, picked...)
+	}
+
+	return reviewers, nil
+}
+
+// remainderOf - запрос на оставшиеся места в команде teamName без уже выбранных
+func remainderOf(req SelectionRequest, teamName string, picked []*user.User) SelectionRequest {
+	exclude := make([]string, 0, len(req.Exclude)+len(picked))
+	exclude = append(exclude, req.Exclude...)
+	for _, u := range picked {
+		exclude = append(exclude, u.UserID)
+	}
+
+	return SelectionRequest{
+		TeamName:    teamName,
+		Exclude:     exclude,
+		Count:       req.Count - len(picked),
+		Replacement: req.Replacement,
+	}
+}
+
+func (repo *PullRequestsRepoPg) loadTeamInTx(tx *gorm.DB, teamName string) (*team.Team, error) {
+	var t team.Team
+	if err := tx.First(&t, "team_name = ?", teamName).Error; err != nil {
+		repo.logger.Errorw("error loading team", "teamName", teamName, "err", err)
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// selectorFor - стратегия выбирается по настройке команды, неизвестная стратегия = LEAST_LOADED
+func (repo *PullRequestsRepoPg) selectorFor(t *team.Team) ReviewerSelector {
+	if selector, ok := repo.selectors[t.SelectionStrategy]; ok {
+		return selector
+	}
+
+	repo.logger.Warnw("unknown selection strategy", "teamName", t.TeamName, "strategy", t.SelectionStrategy)
+	return repo.selectors[StrategyLeastLoaded]
+}
+
 func (repo *PullRequestsRepoPg) Merge(prID string) (*PullRequest, error) {
 	repo.logger.Debugw("Merge()", "prID", prID)
 
@@ -133,7 +303,7 @@ func (repo *PullRequestsRepoPg) Merge(prID string) (*PullRequest, error) {
 	defer func() {
 		metrics.ObservePROp("merge_pr", start, err)
 		if err == nil && preStatus != StatusMerged && pr.Status == StatusMerged {
-			metrics.AddOpenPR(-1)
+			repo.addOpenPR(-1)
 		}
 	}()
 
@@ -148,6 +318,18 @@ func (repo *PullRequestsRepoPg) Merge(prID string) (*PullRequest, error) {
 			repo.logger.Warnw("PR already merged", "prID", prID)
 			return nil
 		}
+		if pr.Status == StatusClosed {
+			repo.logger.Warnw("PR is closed, reopen it first", "prID", prID)
+			return ErrPRClosed
+		}
+		if pr.Status == StatusDraft {
+			repo.logger.Warnw("PR is a draft, mark it ready first", "prID", prID)
+			return ErrPRDraft
+		}
+
+		if err := repo.mergeGateInTx(tx, &pr); err != nil {
+			return err
+		}
 
 		now := time.Now().UTC()
 		pr.Status = StatusMerged
@@ -174,91 +356,333 @@ func (repo *PullRequestsRepoPg) Merge(prID string) (*PullRequest, error) {
 	return &pr, nil
 }
 
-func (repo *PullRequestsRepoPg) Reassign(prID, oldUserID string) (*PullRequest, string, error) {
-	repo.logger.Debugw("Reassign()", "prID", prID, "oldUserID", oldUserID)
+// Ready переводит черновик в OPEN и только сейчас выбирает ревьюверов - по нагрузке и доступности
+// на момент готовности, а не на момент создания
+func (repo *PullRequestsRepoPg) Ready(prID string) (*PullRequest, error) {
+	repo.logger.Debugw("Ready()", "prID", prID)
 
 	start := time.Now()
+	var preStatus string
+	var pr PullRequest
 	var err error
 
 	defer func() {
-		metrics.ObservePROp("reassign_pr", start, err)
+		metrics.ObservePROp("ready_pr", start, err)
+		if err == nil && preStatus == StatusDraft {
+			repo.addOpenPR(1)
+		}
 	}()
 
-	if prID == "" || oldUserID == "" {
-		repo.logger.Warnw("No PR ID or oldUserID found")
-		return nil, "", ErrNotAssigned
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := repo.lockAndLoadPR(tx, prID, &pr); err != nil {
+			repo.logger.Errorw("Error loading PR", "prID", prID, "err", err)
+			return err
+		}
+
+		preStatus = pr.Status
+		switch pr.Status {
+		case StatusMerged:
+			return ErrPRMerged
+		case StatusClosed:
+			return ErrPRClosed
+		case StatusOpen:
+			repo.logger.Warnw("PR already ready", "prID", prID)
+			return nil
+		}
+
+		var author user.User
+		if err := tx.First(&author, "user_id = ?", pr.AuthorID).Error; err != nil {
+			repo.logger.Errorw("Error finding author", "prID", prID, "authorID", pr.AuthorID)
+			return err
+		}
+
+		authorTeam, err := repo.loadTeamInTx(tx, author.TeamName)
+		if err != nil {
+			return err
+		}
+
+		pr.Status = StatusOpen
+		pr.UpdatedAt = time.Now().UTC()
+		if err := tx.Model(&pr).
+			Select("status", "updated_at").
+			Updates(&pr).Error; err != nil {
+			repo.logger.Errorw("Error updating PR ready", "prID", prID)
+			return err
+		}
+
+		if err := repo.assignInitialReviewersInTx(tx, authorTeam, &pr); err != nil {
+			return err
+		}
+
+		return repo.reloadPR(tx, prID, &pr)
+	})
+
+	if err != nil {
+		repo.logger.Errorw("Error marking PR ready", "prID", prID, "err", err)
+		return nil, err
 	}
 
-	var updatedPR *PullRequest
-	var replacedBy string
+	repo.logger.Debugw("PR ready", "prID", prID, "reviewers", len(pr.AssignedReviewers))
+	return &pr, nil
+}
+
+// Close закрывает PR без мержа. Ревьюверы остаются привязаны, чтобы при переоткрытии их можно было вернуть,
+// но в нагрузку закрытые PR не идут
+func (repo *PullRequestsRepoPg) Close(prID string) (*PullRequest, error) {
+	repo.logger.Debugw("Close()", "prID", prID)
+
+	start := time.Now()
+	var preStatus string
+	var pr PullRequest
+	var err error
+
+	defer func() {
+		metrics.ObservePROp("close_pr", start, err)
+		if err == nil && preStatus == StatusOpen {
+			repo.addOpenPR(-1)
+		}
+	}()
 
 	err = repo.db.Transaction(func(tx *gorm.DB) error {
-		var pr PullRequest
 		if err := repo.lockAndLoadPR(tx, prID, &pr); err != nil {
 			repo.logger.Errorw("Error loading PR", "prID", prID, "err", err)
 			return err
 		}
 
-		if pr.Status == StatusMerged {
+		preStatus = pr.Status
+		switch pr.Status {
+		case StatusMerged:
 			repo.logger.Warnw("PR already merged", "prID", prID)
 			return ErrPRMerged
+		case StatusClosed:
+			repo.logger.Warnw("PR already closed", "prID", prID)
+			return nil
 		}
 
-		oldReviewer, ok := findReviewer(pr.AssignedReviewers, oldUserID)
-		if !ok {
-			repo.logger.Warnw("no reviewer to reassign", "prID", prID, "oldUserID", oldUserID)
-			return ErrNotAssigned
+		now := time.Now().UTC()
+		pr.Status = StatusClosed
+		pr.ClosedAt = &now
+		pr.UpdatedAt = now
+
+		if err := tx.Model(&pr).
+			Select("status", "closed_at", "updated_at").
+			Updates(&pr).Error; err != nil {
+			repo.logger.Errorw("Error updating PR closed", "prID", prID)
+			return err
 		}
 
-		// для линтера, избегание magic numbers
-		authorExclusion := 1
+		return nil
+	})
+
+	if err != nil {
+		repo.logger.Errorw("Error closing PR", "prID", prID, "err", err)
+		return nil, err
+	}
+
+	repo.logger.Debugw("Closed", "prID", prID)
+	return &pr, nil
+}
+
+// Reopen возвращает закрытый PR в OPEN. Прежние ревьюверы восстанавливаются, выбывшие (деактивированные,
+// в отпуске, выбравшие лимит) снимаются, а недостающие добираются заново из команды автора
+func (repo *PullRequestsRepoPg) Reopen(prID string) (*PullRequest, error) {
+	repo.logger.Debugw("Reopen()", "prID", prID)
+
+	start := time.Now()
+	var preStatus string
+	var pr PullRequest
+	var err error
 
-		excludeSet := make(map[string]struct{}, len(pr.AssignedReviewers)+authorExclusion)
-		excludeSet[oldUserID] = struct{}{}
-		if pr.AuthorID != "" {
-			excludeSet[pr.AuthorID] = struct{}{}
+	defer func() {
+		metrics.ObservePROp("reopen_pr", start, err)
+		if err == nil && preStatus == StatusClosed {
+			repo.addOpenPR(1)
 		}
-		for _, r := range pr.AssignedReviewers {
-			excludeSet[r.UserID] = struct{}{}
+	}()
+
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := repo.lockAndLoadPR(tx, prID, &pr); err != nil {
+			repo.logger.Errorw("Error loading PR", "prID", prID, "err", err)
+			return err
 		}
-		exclude := make([]string, 0, len(excludeSet))
-		for id := range excludeSet {
-			exclude = append(exclude, id)
+
+		preStatus = pr.Status
+		switch pr.Status {
+		case StatusMerged:
+			repo.logger.Warnw("PR already merged", "prID", prID)
+			return ErrPRMerged
+		case StatusOpen:
+			repo.logger.Warnw("PR already open", "prID", prID)
+			return nil
+		case StatusDraft:
+			repo.logger.Warnw("PR is a draft, mark it ready instead", "prID", prID)
+			return ErrPRDraft
 		}
 
-		var candidate user.User
-		query := tx.Model(&user.User{}).
-			Joins("LEFT JOIN pr_reviewers prr ON prr.user_id = users.user_id").
-			Joins("LEFT JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id AND pr.status = ?", StatusOpen).
-			Where("users.team_name = ? AND users.is_active = TRUE", oldReviewer.TeamName).
-			Group("users.user_id")
+		// ревьюверы подбираются, пока PR еще закрыт: иначе он сам засчитается в лимит прежних ревьюверов
+		if err := repo.restoreReviewersInTx(tx, &pr); err != nil {
+			repo.logger.Errorw("Error restoring reviewers", "prID", prID, "err", err)
+			return err
+		}
+
+		pr.Status = StatusOpen
+		pr.ClosedAt = nil
+		pr.UpdatedAt = time.Now().UTC()
 
-		if len(exclude) > 0 {
-			query = query.Where("users.user_id NOT IN ?", exclude)
+		if err := tx.Model(&pr).
+			Select("status", "closed_at", "updated_at").
+			Updates(&pr).Error; err != nil {
+			repo.logger.Errorw("Error updating PR reopened", "prID", prID)
+			return err
 		}
 
-		if err := query.
-			Order("COUNT(prr.user_id) ASC").
-			Order("RANDOM()").
-			Limit(1).
-			First(&candidate).Error; err != nil {
-			// На сложных операциях, (как пример, транзакция), gorm не всегда отлавливает и оборачивает ошибки,
-			// возвращая просто ошибку бд
-			if errors.Is(err, gorm.ErrRecordNotFound) || strings.Contains(err.Error(), "SQLSTATE 23505") {
-				repo.logger.Errorw("no candidates for reassign", "prID", prID, "oldUserID", oldUserID)
-				return ErrNoCandidate
+		return repo.reloadPR(tx, prID, &pr)
+	})
+
+	if err != nil {
+		repo.logger.Errorw("Error reopening PR", "prID", prID, "err", err)
+		return nil, err
+	}
+
+	repo.logger.Debugw("Reopened", "prID", prID)
+	return &pr, nil
+}
+
+// restoreReviewersInTx - прежние ревьюверы остаются, только если их сейчас можно назначить по тем же правилам,
+// что и новых (availableQuery), остальные места добираются заново
+func (repo *PullRequestsRepoPg) restoreReviewersInTx(tx *gorm.DB, pr *PullRequest) error {
+	kept := make([]*user.User, 0, len(pr.AssignedReviewers))
+	if len(pr.AssignedReviewers) > 0 {
+		prevIDs := make([]string, 0, len(pr.AssignedReviewers))
+		for _, r := range pr.AssignedReviewers {
+			prevIDs = append(prevIDs, r.UserID)
+		}
+
+		var available []string
+		if err := availableQuery(tx, "users.user_id IN ?", prevIDs).
+			Pluck("users.user_id", &available).Error; err != nil {
+			repo.logger.Errorw("error checking previous reviewers", "prID", pr.PullRequestID, "err", err)
+			return err
+		}
+
+		for _, r := range pr.AssignedReviewers {
+			if slices.Contains(available, r.UserID) {
+				kept = append(kept, r)
 			}
-			repo.logger.Errorw("error reassigning PR", "prID", prID, "err", err)
+		}
+	}
+
+	missing := pr.ReviewersRequired - len(kept)
+	if missing <= 0 && len(kept) == len(pr.AssignedReviewers) {
+		return nil
+	}
+
+	reviewers := kept
+	if missing > 0 {
+		var author user.User
+		if err := tx.First(&author, "user_id = ?", pr.AuthorID).Error; err != nil {
+			return err
+		}
+
+		authorTeam, err := repo.loadTeamInTx(tx, author.TeamName)
+		if err != nil {
 			return err
 		}
 
+		exclude := make([]string, 0, len(pr.AssignedReviewers)+1)
+		exclude = append(exclude, pr.AuthorID)
+		for _, r := range pr.AssignedReviewers {
+			exclude = append(exclude, r.UserID)
+		}
+
+		picked, err := repo.selectReviewersInTx(tx, authorTeam, SelectionRequest{
+			TeamName:    authorTeam.TeamName,
+			Exclude:     exclude,
+			Count:       missing,
+			Replacement: true,
+		}, pr.ChangedPaths, pr.Labels)
+		if err != nil {
+			return err
+		}
+		reviewers = append(reviewers, picked...)
+	}
+
+	association := tx.Model(pr).Association("AssignedReviewers")
+	if len(reviewers) == 0 {
+		return association.Clear()
+	}
+	return association.Replace(reviewers)
+}
+
+// Reassign заменяет oldUserID. Если newUserID пустой, замена выбирается стратегией команды,
+// иначе назначается именно newUserID после проверки, что его можно назначить.
+// added - все добавленные ревьюверы: первый встал на место oldUserID, остальные добрали недостающих
+func (repo *PullRequestsRepoPg) Reassign(prID, oldUserID, newUserID string) (*PullRequest, []string, error) {
+	repo.logger.Debugw("Reassign()", "prID", prID, "oldUserID", oldUserID, "newUserID", newUserID)
+
+	start := time.Now()
+	var err error
+
+	defer func() {
+		metrics.ObservePROp("reassign_pr", start, err)
+	}()
+
+	if prID == "" || oldUserID == "" {
+		repo.logger.Warnw("No PR ID or oldUserID found")
+		return nil, nil, ErrNotAssigned
+	}
+
+	var updatedPR *PullRequest
+	var added []string
+
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		var pr PullRequest
+		if err := repo.lockAndLoadPR(tx, prID, &pr); err != nil {
+			repo.logger.Errorw("Error loading PR", "prID", prID, "err", err)
+			return err
+		}
+
+		if pr.Status == StatusMerged {
+			repo.logger.Warnw("PR already merged", "prID", prID)
+			return ErrPRMerged
+		}
+		if pr.Status == StatusClosed {
+			repo.logger.Warnw("PR is closed", "prID", prID)
+			return ErrPRClosed
+		}
+
+		oldReviewer, ok := findReviewer(pr.AssignedReviewers, oldUserID)
+		if !ok {
+			repo.logger.Warnw("no reviewer to reassign", "prID", prID, "oldUserID", oldUserID)
+			return ErrNotAssigned
+		}
+
+		var candidates []*user.User
+		if newUserID != "" {
+			chosen, err := repo.loadEligibleReviewerInTx(tx, &pr, newUserID)
+			if err != nil {
+				return err
+			}
+			candidates = []*user.User{chosen}
+		} else {
+			picked, err := repo.replacementsInTx(tx, &pr, oldReviewer)
+			if err != nil {
+				repo.logger.Errorw("error reassigning PR", "prID", prID, "err", err)
+				return err
+			}
+			candidates = picked
+		}
+		if len(candidates) == 0 {
+			repo.logger.Errorw("no candidates for reassign", "prID", prID, "oldUserID", oldUserID)
+			return ErrNoCandidate
+		}
 		newReviewers := make([]*user.User, 0, len(pr.AssignedReviewers))
 		for _, r := range pr.AssignedReviewers {
 			if r.UserID != oldReviewer.UserID {
 				newReviewers = append(newReviewers, r)
 			}
 		}
-		newReviewers = append(newReviewers, &candidate)
+		newReviewers = append(newReviewers, candidates...)
 
 		if err := tx.Model(&pr).Association("AssignedReviewers").Replace(newReviewers); err != nil {
 			repo.logger.Errorw("error reassigning PR", "prID", prID, "err", err)
@@ -271,7 +695,10 @@ func (repo *PullRequestsRepoPg) Reassign(prID, oldUserID string) (*PullRequest,
 		}
 
 		updatedPR = &pr
-		replacedBy = candidate.UserID
+		added = make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			added = append(added, c.UserID)
+		}
 		repo.logger.Debugw("Reassigned PR", "prID", prID)
 
 		return nil
@@ -279,11 +706,45 @@ func (repo *PullRequestsRepoPg) Reassign(prID, oldUserID string) (*PullRequest,
 
 	if err != nil {
 		repo.logger.Errorw("Error reassigning PR", "prID", prID)
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	repo.logger.Debugw("Reassigned PR", "prID", prID, "oldUserID", oldUserID, "added", added)
+	return updatedPR, added, nil
+}
+
+// replacementsInTx - кандидаты на место oldReviewer тем же путем, что и при создании PR.
+// Если PR недобрал ревьюверов, заодно добираем до нужного количества
+func (repo *PullRequestsRepoPg) replacementsInTx(tx *gorm.DB, pr *PullRequest, oldReviewer *user.User) ([]*user.User, error) {
+	// для линтера, избегание magic numbers
+	authorExclusion := 1
+
+	excludeSet := make(map[string]struct{}, len(pr.AssignedReviewers)+authorExclusion)
+	excludeSet[oldReviewer.UserID] = struct{}{}
+	if pr.AuthorID != "" {
+		excludeSet[pr.AuthorID] = struct{}{}
+	}
+	for _, r := range pr.AssignedReviewers {
+		excludeSet[r.UserID] = struct{}{}
 	}
+	exclude := make([]string, 0, len(excludeSet))
+	for id := range excludeSet {
+		exclude = append(exclude, id)
+	}
+
+	reviewerTeam, err := repo.loadTeamInTx(tx, oldReviewer.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	needed := max(1, pr.ReviewersRequired-len(pr.AssignedReviewers)+1)
 
-	repo.logger.Debugw("Reassigned PR", "prID", prID, "oldUserID", oldUserID, "replacedBy", replacedBy)
-	return updatedPR, replacedBy, nil
+	return repo.selectReviewersInTx(tx, reviewerTeam, SelectionRequest{
+		TeamName:    oldReviewer.TeamName,
+		Exclude:     exclude,
+		Count:       needed,
+		Replacement: true,
+	}, pr.ChangedPaths, pr.Labels)
 }
 
 func (repo *PullRequestsRepoPg) lockAndLoadPR(tx *gorm.DB, prID string, pr *PullRequest) error {
@@ -294,6 +755,7 @@ func (repo *PullRequestsRepoPg) lockAndLoadPR(tx *gorm.DB, prID string, pr *Pull
 		Preload("AssignedReviewers", func(tx2 *gorm.DB) *gorm.DB {
 			return tx2.Order("users.user_id ASC")
 		}).
+		Preload("Assignments").
 		First(pr, "pull_request_id = ?", prID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			repo.logger.Warnw("PR does not exist", "prID", prID)
@@ -315,11 +777,14 @@ func (repo *PullRequestsRepoPg) reloadPR(tx *gorm.DB, prID string, pr *PullReque
 		Preload("AssignedReviewers", func(tx2 *gorm.DB) *gorm.DB {
 			return tx2.Order("users.user_id ASC")
 		}).
+		Preload("Assignments").
 		First(pr, "pull_request_id = ?", prID).Error
 }
 
-func (repo *PullRequestsRepoPg) ListPRsByReviewer(userID string) ([]*PullRequest, error) {
-	repo.logger.Debugw("ListPRsByReviewer()", "userID", userID)
+// ListPRsByReviewer - PR, где userID ревьювер, от последних назначений к первым.
+// Keyset-пагинация по (pr_reviewers.created_at, pull_request_id)
+func (repo *PullRequestsRepoPg) ListPRsByReviewer(userID string, filter ReviewerPRsFilter) (*PRPage, error) {
+	repo.logger.Debugw("ListPRsByReviewer()", "userID", userID, "filter", filter)
 
 	start := time.Now()
 	var err error
@@ -328,30 +793,72 @@ func (repo *PullRequestsRepoPg) ListPRsByReviewer(userID string) ([]*PullRequest
 		metrics.ObservePROp("list_prs_by_reviewer", start, err)
 	}()
 
+	page := &PRPage{PullRequests: []*PullRequest{}}
 	if userID == "" {
 		repo.logger.Warnw("userID is empty", "userID", userID)
-		return []*PullRequest{}, nil
+		return page, nil
 	}
 
-	var prShort []*PullRequest
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+
+	var cursor *listCursor
+	if filter.Cursor != "" {
+		cursor, err = decodeCursor(filter.Cursor)
+		if err != nil || cursor.SortBy != sortByAssignedAt {
+			repo.logger.Warnw("invalid cursor", "userID", userID, "cursor", filter.Cursor)
+			err = ErrInvalidCursor
+			return nil, err
+		}
+	}
 
 	err = repo.db.Transaction(func(tx *gorm.DB) error {
-		var ids []string
-		if err := tx.Model(&PRReviewer{}).
-			Where("user_id = ?", userID).
-			Pluck("pull_request_id", &ids).Error; err != nil {
-			repo.logger.Errorw("error plucking pr ids", "userID", userID, "err", err)
+		q := tx.Model(&PRReviewer{}).
+			Joins("JOIN pull_requests pr ON pr.pull_request_id = pr_reviewers.pull_request_id").
+			Where("pr_reviewers.user_id = ?", userID)
+		if filter.Status != "" {
+			q = q.Where("pr.status = ?", filter.Status)
+		}
+		if labels := normalizeLabels(filter.Labels); len(labels) > 0 {
+			q = q.Where("pr.labels @> ?", labelsJSON(labels))
+		}
+		if cursor != nil {
+			q = q.Where("(pr_reviewers.created_at, pr_reviewers.pull_request_id) < (?, ?)", cursor.Value, cursor.ID)
+		}
+
+		var links []*PRReviewer
+		if err := q.
+			Select("pr_reviewers.pull_request_id, pr_reviewers.created_at").
+			Order("pr_reviewers.created_at DESC, pr_reviewers.pull_request_id DESC").
+			Limit(limit + 1).
+			Find(&links).Error; err != nil {
+			repo.logger.Errorw("error loading assignments", "userID", userID, "err", err)
 			return err
 		}
-		if len(ids) == 0 {
-			prShort = []*PullRequest{}
-			repo.logger.Warnw("no PR reviewer", "userID", userID)
+		if len(links) == 0 {
+			repo.logger.Debugw("no PR reviewer", "userID", userID)
 			return nil
 		}
 
-		order := make(map[string]int, len(ids))
-		for i, id := range ids {
-			order[id] = i
+		if len(links) > limit {
+			links = links[:limit]
+			last := links[limit-1]
+			page.NextCursor = encodeCursor(listCursor{
+				SortBy: sortByAssignedAt,
+				Order:  SortDesc,
+				Value:  last.CreatedAt,
+				ID:     last.PullRequestID,
+			})
+		}
+
+		ids := make([]string, 0, len(links))
+		order := make(map[string]int, len(links))
+		for i, l := range links {
+			ids = append(ids, l.PullRequestID)
+			order[l.PullRequestID] = i
 		}
 		fallback := len(ids)
 
@@ -369,7 +876,7 @@ func (repo *PullRequestsRepoPg) ListPRsByReviewer(userID string) ([]*PullRequest
 			return oi < oj
 		})
 
-		prShort = rows
+		page.PullRequests = rows
 		return nil
 	})
 
@@ -378,8 +885,8 @@ func (repo *PullRequestsRepoPg) ListPRsByReviewer(userID string) ([]*PullRequest
 		return nil, err
 	}
 
-	repo.logger.Debugw("listed PRs by reviewer")
-	return prShort, nil
+	repo.logger.Debugw("listed PRs by reviewer", "count", len(page.PullRequests), "hasMore", page.NextCursor != "")
+	return page, nil
 }
 
 func (repo *PullRequestsRepoPg) GetTeamPRStats(teamName string) ([]*UserStats, error) {
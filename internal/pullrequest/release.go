@@ -0,0 +1,200 @@
+package pullrequest
+
+import (
+	"assignerPR/internal/metrics"
+	"assignerPR/pkg/user"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Причины, по которым PR остался без нужного числа ревьюверов после снятия
+const (
+	ShortNoCandidates    = "NO_CANDIDATES"
+	ShortNotEnoughPeople = "NOT_ENOUGH_CANDIDATES"
+)
+
+// MovedReview - ревьювер снят с открытого PR, ReplacedBy может быть пустым, если замены не нашлось
+type MovedReview struct {
+	PullRequestID string
+	FromUserID    string
+	ReplacedBy    []string
+}
+
+// ShortPR - PR, которому после снятия не хватает Missing ревьюверов
+type ShortPR struct {
+	PullRequestID string
+	Missing       int
+	Reason        string
+}
+
+type ReleaseReport struct {
+	Moved []MovedReview
+	Short []ShortPR
+}
+
+// ReleaseReviews снимает пользователей со всех открытых ревью и переназначает их тем же путем, что и Reassign.
+// Вызывается в одной транзакции с деактивацией: сами пользователи к этому моменту уже не кандидаты
+func (repo *PullRequestsRepoPg) ReleaseReviews(userIDs []string) (*ReleaseReport, error) {
+	repo.logger.Debugw("ReleaseReviews()", "userIDs", userIDs)
+
+	start := time.Now()
+	var err error
+
+	defer func() {
+		metrics.ObservePROp("release_reviews", start, err)
+	}()
+
+	var report *ReleaseReport
+	report, err = repo.releaseReviews(userIDs, "")
+	return report, err
+}
+
+// ReleaseTeamReviews - то же для перевода в другую команду: снимаются только ревью PR авторов из teamName,
+// замена ищется в teamName, а не в новой команде пользователя
+func (repo *PullRequestsRepoPg) ReleaseTeamReviews(userID, teamName string) (*ReleaseReport, error) {
+	repo.logger.Debugw("ReleaseTeamReviews()", "userID", userID, "teamName", teamName)
+
+	start := time.Now()
+	var err error
+
+	defer func() {
+		metrics.ObservePROp("release_team_reviews", start, err)
+	}()
+
+	var report *ReleaseReport
+	report, err = repo.releaseReviews([]string{userID}, teamName)
+	return report, err
+}
+
+// releaseReviews - authorTeam пустой = все открытые ревью пользователей
+func (repo *PullRequestsRepoPg) releaseReviews(userIDs []string, authorTeam string) (*ReleaseReport, error) {
+	report := &ReleaseReport{
+		Moved: []MovedReview{},
+		Short: []ShortPR{},
+	}
+	if len(userIDs) == 0 {
+		return report, nil
+	}
+
+	released := make(map[string]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		released[id] = struct{}{}
+	}
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&PRReviewer{}).
+			Joins("JOIN pull_requests pr ON pr.pull_request_id = pr_reviewers.pull_request_id").
+			Where("pr_reviewers.user_id IN ? AND pr.status = ?", userIDs, StatusOpen)
+		if authorTeam != "" {
+			q = q.Joins("JOIN users a ON a.user_id = pr.author_id").Where("a.team_name = ?", authorTeam)
+		}
+
+		var prIDs []string
+		if err := q.
+			Distinct("pr_reviewers.pull_request_id").
+			Order("pr_reviewers.pull_request_id ASC").
+			Pluck("pr_reviewers.pull_request_id", &prIDs).Error; err != nil {
+			repo.logger.Errorw("error finding open reviews", "userIDs", userIDs, "err", err)
+			return err
+		}
+
+		for _, prID := range prIDs {
+			if err := repo.releaseFromPRInTx(tx, prID, released, authorTeam, report); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		repo.logger.Errorw("error releasing reviews", "userIDs", userIDs, "err", err)
+		return nil, err
+	}
+
+	repo.logger.Debugw("released reviews", "userIDs", userIDs,
+		"moved", len(report.Moved), "short", len(report.Short))
+	return report, nil
+}
+
+func (repo *PullRequestsRepoPg) releaseFromPRInTx(
+	tx *gorm.DB,
+	prID string,
+	released map[string]struct{},
+	fromTeam string,
+	report *ReleaseReport,
+) error {
+	var pr PullRequest
+	if err := repo.lockAndLoadPR(tx, prID, &pr); err != nil {
+		return err
+	}
+
+	lastFound := 0
+	for _, old := range append([]*user.User(nil), pr.AssignedReviewers...) {
+		if _, ok := released[old.UserID]; !ok {
+			continue
+		}
+
+		// после перевода пользователь уже числится в новой команде, а замену надо искать в прежней
+		replaced := old
+		if fromTeam != "" {
+			moved := *old
+			moved.TeamName = fromTeam
+			replaced = &moved
+		}
+
+		candidates, err := repo.replacementsInTx(tx, &pr, replaced)
+		if err != nil {
+			repo.logger.Errorw("error selecting replacements", "prID", prID, "userID", old.UserID, "err", err)
+			return err
+		}
+		lastFound = len(candidates)
+
+		// Держим pr.AssignedReviewers актуальным, чтобы следующий снятый ревьювер исключал уже выбранных
+		remaining := make([]*user.User, 0, len(pr.AssignedReviewers)+len(candidates))
+		for _, r := range pr.AssignedReviewers {
+			if r.UserID != old.UserID {
+				remaining = append(remaining, r)
+			}
+		}
+		pr.AssignedReviewers = append(remaining, candidates...)
+
+		replacedBy := make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			replacedBy = append(replacedBy, c.UserID)
+		}
+		report.Moved = append(report.Moved, MovedReview{
+			PullRequestID: prID,
+			FromUserID:    old.UserID,
+			ReplacedBy:    replacedBy,
+		})
+	}
+
+	association := tx.Model(&pr).Association("AssignedReviewers")
+	var err error
+	if len(pr.AssignedReviewers) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(pr.AssignedReviewers)
+	}
+	if err != nil {
+		repo.logger.Errorw("error replacing reviewers", "prID", prID, "err", err)
+		return err
+	}
+
+	if missing := pr.MissingReviewers(); missing > 0 {
+		reason := ShortNotEnoughPeople
+		if lastFound == 0 {
+			reason = ShortNoCandidates
+		}
+		repo.logger.Warnw("PR left short of reviewers", "prID", prID, "missing", missing, "reason", reason)
+		report.Short = append(report.Short, ShortPR{
+			PullRequestID: prID,
+			Missing:       missing,
+			Reason:        reason,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package pullrequest
+
+import (
+	"assignerPR/internal/metrics"
+	"assignerPR/pkg/user"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Вердикты ревьювера, действует последний поданный
+const (
+	VerdictApproved         = "APPROVED"
+	VerdictChangesRequested = "CHANGES_REQUESTED"
+	VerdictCommented        = "COMMENTED"
+)
+
+func KnownVerdict(verdict string) bool {
+	switch verdict {
+	case VerdictApproved, VerdictChangesRequested, VerdictCommented:
+		return true
+	default:
+		return false
+	}
+}
+
+// Review - поданный вердикт. Хранится история, для гейта мержа берется последний вердикт каждого
+// из текущих ревьюверов
+type Review struct {
+	ID            uint64    `gorm:"primaryKey;column:id"`
+	PullRequestID string    `gorm:"type:varchar(64);index;not null;column:pull_request_id"`
+	UserID        string    `gorm:"type:varchar(64);not null;column:user_id"`
+	Verdict       string    `gorm:"type:varchar(32);not null;column:verdict"`
+	CreatedAt     time.Time `gorm:"column:created_at"`
+}
+
+func (Review) TableName() string {
+	return "pr_reviews"
+}
+
+func (repo *PullRequestsRepoPg) SubmitReview(prID, reviewerID, verdict string) (*Review, error) {
+	repo.logger.Debugw("SubmitReview()", "prID", prID, "reviewerID", reviewerID, "verdict", verdict)
+
+	start := time.Now()
+	var err error
+
+	defer func() {
+		metrics.ObservePROp("submit_review", start, err)
+	}()
+
+	review := &Review{
+		PullRequestID: prID,
+		UserID:        reviewerID,
+		Verdict:       verdict,
+	}
+
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		var pr PullRequest
+		if err := repo.lockAndLoadPR(tx, prID, &pr); err != nil {
+			return err
+		}
+
+		switch pr.Status {
+		case StatusMerged:
+			return ErrPRMerged
+		case StatusClosed:
+			return ErrPRClosed
+		case StatusDraft:
+			return ErrPRDraft
+		}
+
+		if _, ok := findReviewer(pr.AssignedReviewers, reviewerID); !ok {
+			repo.logger.Warnw("reviewer is not assigned", "prID", prID, "reviewerID", reviewerID)
+			return ErrNotAssigned
+		}
+
+		return tx.Create(review).Error
+	})
+
+	if err != nil {
+		repo.logger.Errorw("error submitting review", "prID", prID, "reviewerID", reviewerID, "err", err)
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// mergeGateInTx - если у команды автора задан required_approvals, мержить можно только при достаточном
+// числе одобрений текущих ревьюверов и без висящих CHANGES_REQUESTED
+func (repo *PullRequestsRepoPg) mergeGateInTx(tx *gorm.DB, pr *PullRequest) error {
+	var author user.User
+	if err := tx.First(&author, "user_id = ?", pr.AuthorID).Error; err != nil {
+		return err
+	}
+
+	authorTeam, err := repo.loadTeamInTx(tx, author.TeamName)
+	if err != nil {
+		return err
+	}
+	if authorTeam.RequiredApprovals <= 0 {
+		return nil
+	}
+
+	reviewerIDs := make([]string, 0, len(pr.AssignedReviewers))
+	for _, r := range pr.AssignedReviewers {
+		reviewerIDs = append(reviewerIDs, r.UserID)
+	}
+
+	var latest []*Review
+	if len(reviewerIDs) > 0 {
+		if err := tx.Model(&Review{}).
+			Select("DISTINCT ON (user_id) *").
+			Where("pull_request_id = ? AND user_id IN ?", pr.PullRequestID, reviewerIDs).
+			Order("user_id, created_at DESC, id DESC").
+			Find(&latest).Error; err != nil {
+			return err
+		}
+	}
+
+	approvals := 0
+	for _, r := range latest {
+		switch r.Verdict {
+		case VerdictChangesRequested:
+			repo.logger.Warnw("merge blocked by change request", "prID", pr.PullRequestID, "reviewerID", r.UserID)
+			return ErrMergeBlocked
+		case VerdictApproved:
+			approvals++
+		}
+	}
+
+	if approvals < authorTeam.RequiredApprovals {
+		repo.logger.Warnw("merge blocked: not enough approvals", "prID", pr.PullRequestID,
+			"approvals", approvals, "required", authorTeam.RequiredApprovals)
+		return ErrMergeBlocked
+	}
+
+	return nil
+}
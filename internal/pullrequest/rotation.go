@@ -0,0 +1,78 @@
+package pullrequest
+
+import (
+	"assignerPR/pkg/user"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RotationCursor - последний получивший ревью по ротации член команды
+type RotationCursor struct {
+	TeamName   string `gorm:"primaryKey;type:varchar(64);column:team_name"`
+	LastUserID string `gorm:"type:varchar(64);not null;default:'';column:last_user_id"`
+	UpdatedAt  time.Time
+}
+
+func (RotationCursor) TableName() string {
+	return "team_rotation_cursors"
+}
+
+// rotationSelector - строгая ротация по user_id с курсором в БД (ROTATION и ROUND_ROBIN). Строка курсора
+// блокируется до конца транзакции, поэтому параллельные создания PR одной команды не получат один и тот же слот.
+// Замены при переназначении берут следующего по очереди без сдвига курсора
+type rotationSelector struct{}
+
+func (rotationSelector) Select(tx *gorm.DB, req SelectionRequest) ([]*user.User, error) {
+	var cursor RotationCursor
+	if req.Replacement {
+		if err := tx.Where("team_name = ?", req.TeamName).Limit(1).Find(&cursor).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&RotationCursor{TeamName: req.TeamName}).Error; err != nil {
+			return nil, err
+		}
+
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&cursor, "team_name = ?", req.TeamName).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var candidates []*user.User
+	if err := candidatesQuery(tx, req).
+		Order("users.user_id ASC").
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	reviewers := rotateFrom(candidates, cursor.LastUserID, req.Count)
+	if len(reviewers) == 0 || req.Replacement {
+		return reviewers, nil
+	}
+
+	err := tx.Model(&RotationCursor{}).
+		Where("team_name = ?", req.TeamName).
+		Update("last_user_id", reviewers[len(reviewers)-1].UserID).Error
+
+	return reviewers, err
+}
+
+// rotateFrom - следующие count кандидатов после lastUserID по кругу, кандидаты отсортированы по user_id
+func rotateFrom(candidates []*user.User, lastUserID string, count int) []*user.User {
+	start := 0
+	for start < len(candidates) && candidates[start].UserID <= lastUserID {
+		start++
+	}
+
+	reviewers := make([]*user.User, 0, min(count, len(candidates)))
+	for i := 0; i < len(candidates) && len(reviewers) < count; i++ {
+		reviewers = append(reviewers, candidates[(start+i)%len(candidates)])
+	}
+
+	return reviewers
+}
@@ -0,0 +1,187 @@
+package pullrequest
+
+import (
+	"assignerPR/pkg/user"
+	"math/rand/v2"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Стратегии выбора ревьюверов, хранятся в teams.selection_strategy
+const (
+	StrategyLeastLoaded = "LEAST_LOADED"
+	// StrategyRoundRobin - то же, что StrategyRotation: имя осталось у команд, настроенных до появления курсора
+	StrategyRoundRobin = "ROUND_ROBIN"
+	StrategyRandom     = "RANDOM"
+	StrategyWeighted   = "WEIGHTED"
+	StrategyRotation   = "ROTATION"
+)
+
+// SelectionRequest - кого и сколько выбрать. Exclude - автор, уже назначенные и заменяемый ревьюверы.
+// Replacement - выбор замены при переназначении: ротация отдает следующего по очереди, но не сдвигает курсор,
+// чтобы замены не сбивали порядок назначений на новые PR
+type SelectionRequest struct {
+	TeamName    string
+	Exclude     []string
+	Count       int
+	Replacement bool
+}
+
+// ReviewerSelector - политика выбора ревьюверов. И создание PR, и переназначение идут через нее,
+// чтобы эти два пути не разъезжались
+type ReviewerSelector interface {
+	Select(tx *gorm.DB, req SelectionRequest) ([]*user.User, error)
+}
+
+func KnownStrategy(strategy string) bool {
+	switch strategy {
+	case StrategyLeastLoaded, StrategyRoundRobin, StrategyRandom, StrategyWeighted, StrategyRotation:
+		return true
+	default:
+		return false
+	}
+}
+
+func defaultSelectors(formula LoadFormula) map[string]ReviewerSelector {
+	return map[string]ReviewerSelector{
+		StrategyLeastLoaded: leastLoadedSelector{formula: formula},
+		StrategyRoundRobin:  rotationSelector{},
+		StrategyRandom:      randomSelector{},
+		StrategyWeighted:    weightedSelector{formula: formula},
+		StrategyRotation:    rotationSelector{},
+	}
+}
+
+// LoadFormula - как считается нагрузка ревьювера. Всегда учитываются только открытые ревью,
+// смерженные добавляются опционально: каждое за последние MergedWindow весит MergedWeight и линейно
+// затухает до нуля к концу окна. Нулевое значение - только открытые.
+type LoadFormula struct {
+	MergedWindow time.Duration
+	MergedWeight float64
+}
+
+// Expr - выражение нагрузки над pr (pull_requests), приджойненной к кандидату через prr (pr_reviewers)
+func (f LoadFormula) Expr() clause.Expr {
+	if f.MergedWindow <= 0 || f.MergedWeight <= 0 {
+		return clause.Expr{
+			SQL:  "COUNT(pr.pull_request_id) FILTER (WHERE pr.status = ?)",
+			Vars: []interface{}{StatusOpen},
+		}
+	}
+
+	windowSecs := f.MergedWindow.Seconds()
+	return clause.Expr{
+		SQL: "COUNT(pr.pull_request_id) FILTER (WHERE pr.status = ?) + ? * COALESCE(SUM(" +
+			"1 - EXTRACT(EPOCH FROM NOW() - pr.merged_at) / ?) FILTER (WHERE pr.status = ? " +
+			"AND pr.merged_at > NOW() - make_interval(secs => ?)), 0)",
+		Vars: []interface{}{StatusOpen, f.MergedWeight, windowSecs, StatusMerged, windowSecs},
+	}
+}
+
+// candidatesQuery - общий для всех стратегий набор кандидатов: члены команды вне Exclude, которым можно дать ревью
+func candidatesQuery(tx *gorm.DB, req SelectionRequest) *gorm.DB {
+	query := availableQuery(tx, "users.team_name = ?", req.TeamName)
+
+	if len(req.Exclude) > 0 {
+		query = query.Where("users.user_id NOT IN ?", req.Exclude)
+	}
+
+	return query
+}
+
+// availableQuery - пользователи под условием cond, которым сейчас можно дать ревью: активные, не уволенные,
+// без окна недоступности и не выбравшие лимит открытых ревью (личный, иначе командный)
+func availableQuery(tx *gorm.DB, cond string, args ...interface{}) *gorm.DB {
+	return tx.Model(&user.User{}).
+		Joins("JOIN teams t ON t.team_name = users.team_name").
+		Joins("LEFT JOIN pr_reviewers prr ON prr.user_id = users.user_id").
+		Joins("LEFT JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id").
+		Where(cond+" AND users.is_active = TRUE AND users.offboarded_at IS NULL", args...).
+		Where("NOT EXISTS (SELECT 1 FROM user_unavailabilities ua "+
+			"WHERE ua.user_id = users.user_id AND ua.starts_at <= NOW() AND ua.ends_at > NOW())").
+		Group("users.user_id, t.team_name").
+		Having("COALESCE(users.max_open_reviews, NULLIF(t.max_open_reviews, 0)) IS NULL OR "+
+			"COUNT(pr.pull_request_id) FILTER (WHERE pr.status = ?) < "+
+			"COALESCE(users.max_open_reviews, NULLIF(t.max_open_reviews, 0))", StatusOpen)
+}
+
+// leastLoadedSelector - наименее загруженные, при равенстве случайно
+type leastLoadedSelector struct {
+	formula LoadFormula
+}
+
+func (s leastLoadedSelector) Select(tx *gorm.DB, req SelectionRequest) ([]*user.User, error) {
+	// Одним выражением: gorm при слиянии ORDER BY теряет Expression
+	orderBy := clause.OrderBy{
+		Expression: clause.Expr{SQL: "? ASC, RANDOM()", Vars: []interface{}{s.formula.Expr()}},
+	}
+
+	var reviewers []*user.User
+	err := candidatesQuery(tx, req).
+		Order(orderBy).
+		Limit(req.Count).
+		Find(&reviewers).Error
+
+	return reviewers, err
+}
+
+type randomSelector struct{}
+
+func (randomSelector) Select(tx *gorm.DB, req SelectionRequest) ([]*user.User, error) {
+	var reviewers []*user.User
+	err := candidatesQuery(tx, req).
+		Order("RANDOM()").
+		Limit(req.Count).
+		Find(&reviewers).Error
+
+	return reviewers, err
+}
+
+type weightedCandidate struct {
+	user.User `gorm:"embedded"`
+	Load      float64 `gorm:"column:load"`
+}
+
+// weightedSelector - случайный выбор с весом 1 / (1 + нагрузка): загруженные тоже могут попасть, но реже
+type weightedSelector struct {
+	formula LoadFormula
+}
+
+func (s weightedSelector) Select(tx *gorm.DB, req SelectionRequest) ([]*user.User, error) {
+	var candidates []*weightedCandidate
+	if err := candidatesQuery(tx, req).
+		Select("users.*, ? AS load", s.formula.Expr()).
+		Scan(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	return pickWeighted(candidates, req.Count), nil
+}
+
+func pickWeighted(candidates []*weightedCandidate, count int) []*user.User {
+	reviewers := make([]*user.User, 0, min(count, len(candidates)))
+
+	for len(reviewers) < count && len(candidates) > 0 {
+		total := 0.0
+		for _, c := range candidates {
+			total += 1 / (1 + c.Load)
+		}
+
+		point := rand.Float64() * total
+		idx := len(candidates) - 1
+		for i, c := range candidates {
+			point -= 1 / (1 + c.Load)
+			if point < 0 {
+				idx = i
+				break
+			}
+		}
+
+		reviewers = append(reviewers, &candidates[idx].User)
+		candidates = append(candidates[:idx], candidates[idx+1:]...)
+	}
+
+	return reviewers
+}
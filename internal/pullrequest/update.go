@@ -0,0 +1,135 @@
+package pullrequest
+
+import (
+	"assignerPR/internal/metrics"
+	"assignerPR/pkg/team"
+	"assignerPR/pkg/user"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Update меняет название, метки и автора PR. Если новый автор был ревьювером, он снимается,
+// а у открытого PR его место занимает замена тем же путем, что и в Reassign. Влитый PR не меняется, как и в Reassign
+// и AddReviewer: это уже история
+func (repo *PullRequestsRepoPg) Update(prID string, upd PRUpdate) (*PullRequest, error) {
+	repo.logger.Debugw("Update()", "prID", prID)
+
+	start := time.Now()
+	var err error
+
+	defer func() {
+		metrics.ObservePROp("update_pr", start, err)
+	}()
+
+	var pr PullRequest
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := repo.lockAndLoadPR(tx, prID, &pr); err != nil {
+			return err
+		}
+		if pr.Status == StatusMerged {
+			repo.logger.Warnw("cannot update merged PR", "prID", prID)
+			return ErrPRMerged
+		}
+
+		columns := make([]string, 0, 3)
+		if upd.Name != nil {
+			pr.PullRequestName = *upd.Name
+			columns = append(columns, "pull_request_name")
+		}
+		if upd.Labels != nil {
+			pr.Labels = normalizeLabels(*upd.Labels)
+			columns = append(columns, "labels")
+		}
+		if upd.AuthorID != nil && *upd.AuthorID != pr.AuthorID {
+			if err := repo.changeAuthorInTx(tx, &pr, *upd.AuthorID); err != nil {
+				return err
+			}
+			columns = append(columns, "author_id")
+		}
+
+		if len(columns) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&pr).Select(append(columns, "updated_at")).Updates(&pr).Error; err != nil {
+			repo.logger.Errorw("error updating PR", "prID", prID, "err", err)
+			return err
+		}
+
+		return repo.reloadPR(tx, prID, &pr)
+	})
+
+	if err != nil {
+		repo.logger.Errorw("error updating PR", "prID", prID, "err", err)
+		return nil, err
+	}
+
+	return &pr, nil
+}
+
+// changeAuthorInTx - проверка нового автора и снятие его с ревью собственного PR. Автор должен быть активен
+// и состоять в существующей команде: по ней подбираются ревьюверы
+func (repo *PullRequestsRepoPg) changeAuthorInTx(tx *gorm.DB, pr *PullRequest, authorID string) error {
+	var author user.User
+	if err := tx.First(&author, "user_id = ?", authorID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user.ErrUserNotFound
+		}
+		return err
+	}
+	if !author.IsActive || author.OffboardedAt != nil {
+		repo.logger.Warnw("new author is inactive", "prID", pr.PullRequestID, "authorID", authorID)
+		return ErrAuthorInactive
+	}
+	if _, err := repo.loadTeamInTx(tx, author.TeamName); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return team.ErrTeamNotFound
+		}
+		return err
+	}
+	pr.AuthorID = author.UserID
+
+	reviewer, ok := findReviewer(pr.AssignedReviewers, author.UserID)
+	if !ok {
+		return nil
+	}
+
+	var candidates []*user.User
+	if pr.Status == StatusOpen {
+		picked, err := repo.replacementsInTx(tx, pr, reviewer)
+		if err != nil {
+			repo.logger.Errorw("error selecting replacements", "prID", pr.PullRequestID, "err", err)
+			return err
+		}
+		candidates = picked
+	}
+
+	reviewers := make([]*user.User, 0, len(pr.AssignedReviewers)+len(candidates))
+	for _, r := range pr.AssignedReviewers {
+		if r.UserID != author.UserID {
+			reviewers = append(reviewers, r)
+		}
+	}
+	reviewers = append(reviewers, candidates...)
+
+	association := tx.Model(pr).Association("AssignedReviewers")
+	var err error
+	if len(reviewers) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(reviewers)
+	}
+	if err != nil {
+		repo.logger.Errorw("error replacing reviewers", "prID", pr.PullRequestID, "err", err)
+		return err
+	}
+
+	if len(candidates) == 0 && pr.Status == StatusOpen {
+		repo.logger.Warnw("new author removed from reviewers without replacement", "prID", pr.PullRequestID,
+			"authorID", author.UserID)
+	}
+	pr.AssignedReviewers = reviewers
+	return nil
+}
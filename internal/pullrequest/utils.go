@@ -1,6 +1,10 @@
 package pullrequest
 
-import "assignerPR/pkg/user"
+import (
+	"assignerPR/pkg/user"
+	"encoding/json"
+	"strings"
+)
 
 // можно было бы для корректного логирования сделать это методами репозитория, но я решил сделать их здесь
 
@@ -19,3 +23,32 @@ func orderIndex(order map[string]int, id string, fallback int) int {
 	}
 	return fallback
 }
+
+// normalizeLabels - метки без пробелов по краям, пустых и повторов, порядок сохраняется
+func normalizeLabels(labels []string) []string {
+	if labels == nil {
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(labels))
+	out := make([]string, 0, len(labels))
+	for _, l := range labels {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		if _, ok := seen[l]; ok {
+			continue
+		}
+		seen[l] = struct{}{}
+		out = append(out, l)
+	}
+	return out
+}
+
+// labelsJSON - метки как jsonb-массив для фильтра "labels @> ?"
+func labelsJSON(labels []string) string {
+	// маршалинг []string не падает
+	raw, _ := json.Marshal(labels)
+	return string(raw)
+}
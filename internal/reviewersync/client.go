@@ -0,0 +1,85 @@
+package reviewersync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHTTPTimeout = 10 * time.Second
+	// maxErrorBody - сколько тела ответа с ошибкой оставляем в LastError
+	maxErrorBody = 512
+)
+
+func defaultHTTPClient() *http.Client {
+	return &http.Client{Timeout: defaultHTTPTimeout}
+}
+
+// doJSON - запрос с JSON-телом, out может быть nil. Ответ не 2xx превращается в HostError
+func doJSON(ctx context.Context, client *http.Client, method, url string, header http.Header, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &HostError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(raw))}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// diff - элементы a, которых нет в b, без учета регистра: логины у обоих провайдеров регистронезависимы
+func diff(a, b []string) []string {
+	in := make(map[string]struct{}, len(b))
+	for _, s := range b {
+		in[strings.ToLower(s)] = struct{}{}
+	}
+
+	var out []string
+	for _, s := range a {
+		if _, ok := in[strings.ToLower(s)]; !ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
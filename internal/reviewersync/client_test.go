@@ -0,0 +1,173 @@
+package reviewersync_test
+
+import (
+	"assignerPR/internal/reviewersync"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// hostRequest - запрос, дошедший до подставного API хоста
+type hostRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   map[string]any
+}
+
+// fakeHost - httptest вместо API код-хоста: запоминает запросы и отвечает через handle
+type fakeHost struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []hostRequest
+}
+
+func newFakeHost(t *testing.T, handle func(w http.ResponseWriter, r *http.Request)) *fakeHost {
+	t.Helper()
+
+	h := &fakeHost{}
+	h.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := hostRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Header: r.Header.Clone()}
+		if raw, _ := io.ReadAll(r.Body); len(raw) > 0 {
+			require.NoError(t, json.Unmarshal(raw, &req.Body))
+		}
+
+		h.mu.Lock()
+		h.requests = append(h.requests, req)
+		h.mu.Unlock()
+
+		handle(w, r)
+	}))
+	t.Cleanup(h.Close)
+
+	return h
+}
+
+func (h *fakeHost) Requests() []hostRequest {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]hostRequest(nil), h.requests...)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body)
+}
+
+func TestGitHubClient_SetReviewers(t *testing.T) {
+	host := newFakeHost(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{}`)
+	})
+	client := reviewersync.NewGitHubClient(host.URL, "gh-token", nil)
+
+	err := client.SetReviewers(context.Background(), "octo-org/assigner", 42,
+		[]string{"alice", "carol"}, []string{"Alice", "bob"})
+	require.NoError(t, err)
+
+	reqs := host.Requests()
+	require.Len(t, reqs, 2)
+
+	// снимается только наш bob, alice остается (логины без учета регистра)
+	require.Equal(t, http.MethodDelete, reqs[0].Method)
+	require.Equal(t, "/repos/octo-org/assigner/pulls/42/requested_reviewers", reqs[0].Path)
+	require.Equal(t, map[string]any{"reviewers": []any{"bob"}}, reqs[0].Body)
+
+	require.Equal(t, http.MethodPost, reqs[1].Method)
+	require.Equal(t, "/repos/octo-org/assigner/pulls/42/requested_reviewers", reqs[1].Path)
+	require.Equal(t, map[string]any{"reviewers": []any{"alice", "carol"}}, reqs[1].Body)
+
+	for _, r := range reqs {
+		require.Equal(t, "Bearer gh-token", r.Header.Get("Authorization"))
+		require.Equal(t, "application/vnd.github+json", r.Header.Get("Accept"))
+	}
+}
+
+func TestGitHubClient_SetReviewers_Error(t *testing.T) {
+	host := newFakeHost(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusUnprocessableEntity,
+			`{"message":"Reviews may only be requested from collaborators."}`)
+	})
+	client := reviewersync.NewGitHubClient(host.URL, "gh-token", nil)
+
+	err := client.SetReviewers(context.Background(), "octo-org/assigner", 42, []string{"stranger"}, nil)
+
+	var hostErr *reviewersync.HostError
+	require.ErrorAs(t, err, &hostErr)
+	require.Equal(t, http.StatusUnprocessableEntity, hostErr.StatusCode)
+	require.Contains(t, hostErr.Body, "collaborators")
+	require.Len(t, host.Requests(), 1)
+}
+
+// gitlabHost - MR 57 в проекте 14 с ревьюверами reviewers, пользователи ищутся по users
+func gitlabHost(t *testing.T, reviewers string, users map[string]string) *fakeHost {
+	return newFakeHost(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/14/merge_requests/57":
+			writeJSON(w, http.StatusOK, `{"id":98213,"iid":57,"reviewers":`+reviewers+`}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v4/users":
+			u, ok := users[r.URL.Query().Get("username")]
+			if !ok {
+				writeJSON(w, http.StatusOK, `[]`)
+				return
+			}
+			writeJSON(w, http.StatusOK, `[`+u+`]`)
+		case r.Method == http.MethodPut && r.URL.Path == "/api/v4/projects/14/merge_requests/57":
+			writeJSON(w, http.StatusOK, `{"id":98213,"iid":57}`)
+		default:
+			writeJSON(w, http.StatusNotFound, `{"message":"404 Not Found"}`)
+		}
+	})
+}
+
+func TestGitLabClient_SetReviewers(t *testing.T) {
+	host := gitlabHost(t,
+		`[{"id":90,"username":"qa.lead"},{"id":31,"username":"jane.ops"},{"id":44,"username":"old.rev"}]`,
+		map[string]string{"petr.lead": `{"id":7,"username":"petr.lead"}`},
+	)
+	client := reviewersync.NewGitLabClient(host.URL+"/api/v4/", "gl-token", nil)
+
+	// old.rev назначали мы и он снят, qa.lead добавлен в самом GitLab, jane.ops уже стоит
+	err := client.SetReviewers(context.Background(), "14", 57,
+		[]string{"jane.ops", "petr.lead"}, []string{"jane.ops", "old.rev"})
+	require.NoError(t, err)
+
+	reqs := host.Requests()
+	require.Len(t, reqs, 3)
+	require.Equal(t, http.MethodGet, reqs[0].Method)
+	require.Equal(t, "/api/v4/users", reqs[1].Path)
+	require.Equal(t, "username=petr.lead", reqs[1].Query)
+	require.Equal(t, http.MethodPut, reqs[2].Method)
+	require.Equal(t, map[string]any{"reviewer_ids": []any{float64(90), float64(31), float64(7)}}, reqs[2].Body)
+
+	for _, r := range reqs {
+		require.Equal(t, "gl-token", r.Header.Get("PRIVATE-TOKEN"))
+	}
+
+	// состав уже совпадает - только чтение MR
+	host2 := gitlabHost(t, `[{"id":90,"username":"qa.lead"},{"id":31,"username":"jane.ops"},{"id":7,"username":"petr.lead"}]`, nil)
+	client2 := reviewersync.NewGitLabClient(host2.URL+"/api/v4", "gl-token", nil)
+	require.NoError(t, client2.SetReviewers(context.Background(), "14", 57,
+		[]string{"jane.ops", "petr.lead"}, []string{"jane.ops", "petr.lead"}))
+	require.Len(t, host2.Requests(), 1)
+}
+
+func TestGitLabClient_SetReviewers_UnknownLogin(t *testing.T) {
+	host := gitlabHost(t, `[]`, nil)
+	client := reviewersync.NewGitLabClient(host.URL+"/api/v4", "gl-token", nil)
+
+	err := client.SetReviewers(context.Background(), "14", 57, []string{"ghost"}, nil)
+	require.ErrorIs(t, err, reviewersync.ErrUnknownLogin)
+
+	for _, r := range host.Requests() {
+		require.NotEqual(t, http.MethodPut, r.Method)
+	}
+}
@@ -0,0 +1,63 @@
+package reviewersync
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHubClient - requested_reviewers PR. Запрос ревью у GitHub только добавляет, поэтому снятые нами
+// ревьюверы удаляются отдельным DELETE
+type GitHubClient struct {
+	baseURL string
+	header  http.Header
+	http    *http.Client
+}
+
+// NewGitHubClient - baseURL пустой для github.com, для GitHub Enterprise https://host/api/v3.
+// httpClient nil - клиент с таймаутом по умолчанию
+func NewGitHubClient(baseURL, token string, httpClient *http.Client) *GitHubClient {
+	if baseURL == "" {
+		baseURL = DefaultGitHubAPIURL
+	}
+	if httpClient == nil {
+		httpClient = defaultHTTPClient()
+	}
+
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github+json")
+	header.Set("Authorization", "Bearer "+token)
+	header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	return &GitHubClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		header:  header,
+		http:    httpClient,
+	}
+}
+
+type githubReviewersReq struct {
+	Reviewers []string `json:"reviewers"`
+}
+
+func (c *GitHubClient) SetReviewers(ctx context.Context, repo string, number int64, want, previous []string) error {
+	url := fmt.Sprintf("%s/repos/%s/pulls/%d/requested_reviewers", c.baseURL, repo, number)
+
+	if remove := diff(previous, want); len(remove) > 0 {
+		if err := doJSON(ctx, c.http, http.MethodDelete, url, c.header, githubReviewersReq{remove}, nil); err != nil {
+			return fmt.Errorf("removing reviewers: %w", err)
+		}
+	}
+
+	// повторный запрос уже запрошенного ревьювера GitHub принимает, так что отправляем всех
+	if len(want) > 0 {
+		if err := doJSON(ctx, c.http, http.MethodPost, url, c.header, githubReviewersReq{want}, nil); err != nil {
+			return fmt.Errorf("requesting reviewers: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package reviewersync
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// GitLabClient - reviewer_ids MR. GitLab принимает только полный список id, поэтому сначала читаем текущих
+// ревьюверов MR и заменяем в нем только своих
+type GitLabClient struct {
+	baseURL string
+	header  http.Header
+	http    *http.Client
+
+	mu sync.Mutex
+	// ids - username -> id пользователя GitLab, id не меняются, кэш живет весь процесс
+	ids map[string]int64
+}
+
+// NewGitLabClient - baseURL вида https://gitlab.example.com/api/v4. httpClient nil - клиент с таймаутом по умолчанию
+func NewGitLabClient(baseURL, token string, httpClient *http.Client) *GitLabClient {
+	if httpClient == nil {
+		httpClient = defaultHTTPClient()
+	}
+
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", token)
+
+	return &GitLabClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		header:  header,
+		http:    httpClient,
+		ids:     map[string]int64{},
+	}
+}
+
+type gitlabUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type gitlabMR struct {
+	Reviewers []gitlabUser `json:"reviewers"`
+}
+
+type gitlabReviewersReq struct {
+	ReviewerIDs []int64 `json:"reviewer_ids"`
+}
+
+func (c *GitLabClient) SetReviewers(ctx context.Context, repo string, number int64, want, previous []string) error {
+	mrURL := fmt.Sprintf("%s/projects/%s/merge_requests/%d", c.baseURL, url.PathEscape(repo), number)
+
+	var mr gitlabMR
+	if err := doJSON(ctx, c.http, http.MethodGet, mrURL, c.header, nil, &mr); err != nil {
+		return fmt.Errorf("loading merge request: %w", err)
+	}
+
+	current := make([]string, 0, len(mr.Reviewers))
+	for _, r := range mr.Reviewers {
+		current = append(current, r.Username)
+		c.remember(r)
+	}
+
+	// чужие ревьюверы остаются, наши снятые уходят, недостающие добавляются
+	remove := diff(previous, want)
+	ids := make([]int64, 0, len(mr.Reviewers)+len(want))
+	for _, r := range mr.Reviewers {
+		if !containsFold(remove, r.Username) {
+			ids = append(ids, r.ID)
+		}
+	}
+	added := diff(want, current)
+	for _, login := range added {
+		id, err := c.userID(ctx, login)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	if len(added) == 0 && len(ids) == len(mr.Reviewers) {
+		return nil
+	}
+
+	if err := doJSON(ctx, c.http, http.MethodPut, mrURL, c.header, gitlabReviewersReq{ids}, nil); err != nil {
+		return fmt.Errorf("updating reviewers: %w", err)
+	}
+	return nil
+}
+
+func (c *GitLabClient) remember(u gitlabUser) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids[strings.ToLower(u.Username)] = u.ID
+}
+
+func (c *GitLabClient) userID(ctx context.Context, login string) (int64, error) {
+	c.mu.Lock()
+	id, ok := c.ids[strings.ToLower(login)]
+	c.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	var users []gitlabUser
+	usersURL := c.baseURL + "/users?username=" + url.QueryEscape(login)
+	if err := doJSON(ctx, c.http, http.MethodGet, usersURL, c.header, nil, &users); err != nil {
+		return 0, fmt.Errorf("looking up %s: %w", login, err)
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownLogin, login)
+	}
+
+	c.remember(users[0])
+	return users[0].ID, nil
+}
@@ -0,0 +1,79 @@
+package reviewersync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Статус синхронизации ревьюверов PR с код-хостом
+const (
+	// StatusPending - изменения в очереди или идут повторные попытки
+	StatusPending = "PENDING"
+	StatusSynced  = "SYNCED"
+	// StatusFailed - попытки кончились или хост ответил ошибкой, которую повторять бесполезно
+	StatusFailed = "FAILED"
+	// StatusSkipped - PR закрыт или влит, запрашивать на нем ревью не нужно. После переоткрытия синхронизируется снова
+	StatusSkipped = "SKIPPED"
+)
+
+var (
+	ErrSyncNotFound = errors.New("REVIEWER_SYNC_NOT_FOUND")
+	// ErrUnknownLogin - логин из user_identities не найден на код-хосте
+	ErrUnknownLogin = errors.New("REVIEWER_SYNC_UNKNOWN_LOGIN")
+)
+
+// PRSync - где PR живет на код-хосте и чем закончилась последняя синхронизация ревьюверов.
+// Есть только у PR, пришедших через вебхук: созданные через API синхронизировать некуда
+type PRSync struct {
+	PullRequestID string `gorm:"primaryKey;type:varchar(64);column:pull_request_id"`
+	Provider      string `gorm:"type:varchar(32);not null"`
+	// Repo - owner/name у GitHub, id проекта у GitLab
+	Repo string `gorm:"type:varchar(255);not null"`
+	// Number - номер PR у GitHub, iid MR у GitLab
+	Number   int64  `gorm:"not null"`
+	Status   string `gorm:"type:varchar(16);not null;default:PENDING"`
+	Attempts int    `gorm:"not null;default:0"`
+	// LastError - пустая после успешной синхронизации
+	LastError string `gorm:"type:text;not null;default:''"`
+	// Reviewers - логины, выставленные нами в последний раз, чтобы при следующей синхронизации снять только их
+	Reviewers []string `gorm:"serializer:json;type:jsonb"`
+	// Unmapped - назначенные ревьюверы без логина у провайдера, на хост они не попали
+	Unmapped  []string   `gorm:"serializer:json;type:jsonb"`
+	SyncedAt  *time.Time `gorm:"column:synced_at"`
+	UpdatedAt time.Time  `gorm:"column:updated_at"`
+}
+
+func (PRSync) TableName() string {
+	return "pr_reviewer_syncs"
+}
+
+// ReviewerSync - клиент API код-хоста. previous - логины, выставленные нами в прошлый раз: те из них, что больше
+// не назначены, надо снять, а запросы ревью, сделанные людьми в самом хосте, не трогать
+type ReviewerSync interface {
+	SetReviewers(ctx context.Context, repo string, number int64, want, previous []string) error
+}
+
+// HostError - неуспешный ответ API код-хоста
+type HostError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HostError) Error() string {
+	return fmt.Sprintf("host responded %d: %s", e.StatusCode, e.Body)
+}
+
+// retryable - 5xx, 429 и сетевые ошибки повторяем, остальные 4xx (нет доступа, не коллаборатор) - нет
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrUnknownLogin) {
+		return false
+	}
+
+	var hostErr *HostError
+	if errors.As(err, &hostErr) {
+		return hostErr.StatusCode >= 500 || hostErr.StatusCode == 429
+	}
+	return true
+}
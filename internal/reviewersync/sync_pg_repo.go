@@ -0,0 +1,120 @@
+package reviewersync
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SyncRepo interface {
+	SaveRef(prID, provider, repo string, number int64) error
+	GetSync(prID string) (*PRSync, error)
+	SaveState(s *PRSync) error
+	MarkPending(prIDs ...string) error
+	ListPending(providers []string) ([]string, error)
+}
+
+type SyncRepoPg struct {
+	logger *zap.SugaredLogger
+	db     *gorm.DB
+}
+
+func NewSyncRepoPg(logger *zap.SugaredLogger, db *gorm.DB) *SyncRepoPg {
+	return &SyncRepoPg{
+		logger: logger,
+		db:     db,
+	}
+}
+
+// SaveRef - привязка PR к код-хосту. Статус существующей записи не трогается
+func (repo *SyncRepoPg) SaveRef(prID, provider, repoName string, number int64) error {
+	repo.logger.Debugw("SaveRef()", "prID", prID, "provider", provider, "repo", repoName, "number", number)
+
+	ref := &PRSync{
+		PullRequestID: prID,
+		Provider:      provider,
+		Repo:          repoName,
+		Number:        number,
+		Status:        StatusPending,
+	}
+	if err := repo.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "pull_request_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"provider", "repo", "number"}),
+	}).Create(ref).Error; err != nil {
+		repo.logger.Errorw("error saving host ref", "prID", prID, "err", err)
+		return err
+	}
+
+	return nil
+}
+
+func (repo *SyncRepoPg) GetSync(prID string) (*PRSync, error) {
+	repo.logger.Debugw("GetSync()", "prID", prID)
+
+	var s PRSync
+	if err := repo.db.First(&s, "pull_request_id = ?", prID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSyncNotFound
+		}
+		repo.logger.Errorw("error loading reviewer sync", "prID", prID, "err", err)
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// SaveState - результат попытки синхронизации, привязка к хосту не меняется
+func (repo *SyncRepoPg) SaveState(s *PRSync) error {
+	repo.logger.Debugw("SaveState()", "prID", s.PullRequestID, "status", s.Status, "attempts", s.Attempts)
+
+	s.UpdatedAt = time.Now()
+	if err := repo.db.Model(&PRSync{PullRequestID: s.PullRequestID}).
+		Select("status", "attempts", "last_error", "reviewers", "unmapped", "synced_at", "updated_at").
+		Updates(s).Error; err != nil {
+		repo.logger.Errorw("error saving reviewer sync", "prID", s.PullRequestID, "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// MarkPending - PR, которые не успели синхронизироваться в этом процессе, ListPending вернет их после рестарта
+func (repo *SyncRepoPg) MarkPending(prIDs ...string) error {
+	repo.logger.Debugw("MarkPending()", "prIDs", prIDs)
+
+	if len(prIDs) == 0 {
+		return nil
+	}
+
+	if err := repo.db.Model(&PRSync{}).
+		Where("pull_request_id IN ? AND status <> ?", prIDs, StatusPending).
+		Updates(map[string]any{"status": StatusPending, "updated_at": time.Now()}).Error; err != nil {
+		repo.logger.Errorw("error marking reviewer syncs pending", "prIDs", prIDs, "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// ListPending - PR в PENDING у провайдеров из списка, по давности изменения
+func (repo *SyncRepoPg) ListPending(providers []string) ([]string, error) {
+	repo.logger.Debugw("ListPending()", "providers", providers)
+
+	if len(providers) == 0 {
+		return nil, nil
+	}
+
+	var prIDs []string
+	if err := repo.db.Model(&PRSync{}).
+		Where("status = ? AND provider IN ?", StatusPending, providers).
+		Order("updated_at").
+		Pluck("pull_request_id", &prIDs).Error; err != nil {
+		repo.logger.Errorw("error listing pending reviewer syncs", "err", err)
+		return nil, err
+	}
+
+	return prIDs, nil
+}
@@ -0,0 +1,170 @@
+package reviewersync_test
+
+import (
+	"assignerPR/internal/reviewersync"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, func()) {
+	t.Helper()
+
+	mockDB, mock, err := sqlmock.New(
+		sqlmock.QueryMatcherOption(prefixMatcher()),
+	)
+	require.NoError(t, err)
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{
+		Conn:       mockDB,
+		DriverName: "postgres",
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	return gdb, mock, func() { mockDB.Close() }
+}
+
+// prefixMatcher - запрос должен начинаться с ожидаемого, пробелы не важны
+func prefixMatcher() sqlmock.QueryMatcher {
+	return sqlmock.QueryMatcherFunc(func(expected, actual string) error {
+		normalize := func(s string) string {
+			return strings.Join(strings.Fields(s), " ")
+		}
+
+		if strings.HasPrefix(normalize(actual), normalize(expected)) {
+			return nil
+		}
+		return sqlmock.ErrCancelled
+	})
+}
+
+func TestSyncRepoPg_SaveRef(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := reviewersync.NewSyncRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "pr_reviewer_syncs"`).
+		WithArgs("gitlab-98213", "gitlab", "14", int64(57), reviewersync.StatusPending, 0, "",
+			sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.SaveRef("gitlab-98213", "gitlab", "14", 57))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncRepoPg_GetSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		mockFunc func(sqlmock.Sqlmock)
+		wantErr  error
+	}{
+		{
+			name: "success",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "pr_reviewer_syncs" WHERE pull_request_id = $1`).
+					WithArgs("gitlab-98213", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "provider", "repo", "number", "status", "reviewers"}).
+						AddRow("gitlab-98213", "gitlab", "14", 57, reviewersync.StatusSynced, []byte(`["jane.ops"]`)))
+			},
+		},
+		{
+			name: "not tracked",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "pr_reviewer_syncs" WHERE pull_request_id = $1`).
+					WithArgs("gitlab-98213", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"pull_request_id"}))
+			},
+			wantErr: reviewersync.ErrSyncNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := reviewersync.NewSyncRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.GetSync("gitlab-98213")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, reviewersync.StatusSynced, got.Status)
+				require.Equal(t, []string{"jane.ops"}, got.Reviewers)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSyncRepoPg_SaveState(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := reviewersync.NewSyncRepoPg(zap.NewNop().Sugar(), db)
+	syncedAt := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "pr_reviewer_syncs" SET "status"=$1,"attempts"=$2,"last_error"=$3,"reviewers"=$4,"unmapped"=$5,"synced_at"=$6,"updated_at"=$7 WHERE "pull_request_id" = $8`).
+		WithArgs(reviewersync.StatusSynced, 2, "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "gitlab-98213").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.SaveState(&reviewersync.PRSync{
+		PullRequestID: "gitlab-98213",
+		Status:        reviewersync.StatusSynced,
+		Attempts:      2,
+		Reviewers:     []string{"jane.ops"},
+		SyncedAt:      &syncedAt,
+	}))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncRepoPg_MarkPending(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := reviewersync.NewSyncRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "pr_reviewer_syncs" SET "status"=$1,"updated_at"=$2 WHERE pull_request_id IN ($3,$4) AND status <> $5`).
+		WithArgs(reviewersync.StatusPending, sqlmock.AnyArg(), "gitlab-98213", "github-1873322456", reviewersync.StatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.MarkPending("gitlab-98213", "github-1873322456"))
+	// пустой список в базу не ходит
+	require.NoError(t, repo.MarkPending())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncRepoPg_ListPending(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := reviewersync.NewSyncRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectQuery(`SELECT "pull_request_id" FROM "pr_reviewer_syncs" WHERE status = $1 AND provider IN ($2,$3) ORDER BY updated_at`).
+		WithArgs(reviewersync.StatusPending, "github", "gitlab").
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id"}).
+			AddRow("gitlab-98213").
+			AddRow("github-1873322456"))
+
+	got, err := repo.ListPending([]string{"github", "gitlab"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"gitlab-98213", "github-1873322456"}, got)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
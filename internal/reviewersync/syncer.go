@@ -0,0 +1,374 @@
+package reviewersync
+
+import (
+	"assignerPR/internal/metrics"
+	"assignerPR/internal/pullrequest"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultAttempts = 5
+	DefaultBackoff  = time.Second
+	maxBackoff      = time.Minute
+	defaultWorkers  = 4
+	queueSize       = 1024
+	// sweepInterval - как часто PENDING из базы снова ставятся в очередь (например, выпавшие из полной очереди)
+	sweepInterval = time.Minute
+)
+
+// LoginResolver - user_id -> логин у провайдера, реализуется UsersRepo
+type LoginResolver interface {
+	LoginsByUsers(provider string, userIDs []string) (map[string]string, error)
+}
+
+// Состояние PR в очереди: queued - ждет воркера, running - синхронизируется,
+// dirty - за время синхронизации ревьюверы снова поменялись, после нее PR встанет в очередь еще раз
+const (
+	prQueued = iota
+	prRunning
+	prDirty
+)
+
+// Syncer - фоновая отправка назначенных ревьюверов на код-хост. Синхронизируется всегда текущее состояние PR,
+// а не конкретное изменение, поэтому несколько изменений подряд схлопываются в одну отправку
+type Syncer struct {
+	logger  *zap.SugaredLogger
+	prRepo  pullrequest.PullRequestsRepo
+	store   SyncRepo
+	logins  LoginResolver
+	clients map[string]ReviewerSync
+
+	attempts int
+	backoff  time.Duration
+	workers  int
+
+	queue chan string
+	mu    sync.Mutex
+	state map[string]int
+}
+
+type Option func(s *Syncer)
+
+// WithClient - клиент для провайдера (webhook.ProviderGitHub и т.д.), без него PR провайдера не синхронизируются
+func WithClient(provider string, client ReviewerSync) Option {
+	return func(s *Syncer) {
+		s.clients[provider] = client
+	}
+}
+
+// WithRetry - попыток всего, включая первую; пауза между ними удваивается от backoff до минуты
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(s *Syncer) {
+		if attempts > 0 {
+			s.attempts = attempts
+		}
+		if backoff > 0 {
+			s.backoff = backoff
+		}
+	}
+}
+
+func WithWorkers(workers int) Option {
+	return func(s *Syncer) {
+		if workers > 0 {
+			s.workers = workers
+		}
+	}
+}
+
+func NewSyncer(
+	logger *zap.SugaredLogger,
+	prRepo pullrequest.PullRequestsRepo,
+	store SyncRepo,
+	logins LoginResolver,
+	opts ...Option,
+) *Syncer {
+	s := &Syncer{
+		logger:   logger,
+		prRepo:   prRepo,
+		store:    store,
+		logins:   logins,
+		clients:  map[string]ReviewerSync{},
+		attempts: DefaultAttempts,
+		backoff:  DefaultBackoff,
+		workers:  defaultWorkers,
+		queue:    make(chan string, queueSize),
+		state:    map[string]int{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// TrackHostPR - привязка PR к код-хосту из вебхука (реализует webhook.HostTracker). Сразу ставит PR в очередь:
+// ревьюверы могли назначиться раньше, чем появилась привязка
+func (s *Syncer) TrackHostPR(prID, provider, repo string, number int64) error {
+	if _, ok := s.clients[provider]; !ok {
+		return nil
+	}
+
+	if err := s.store.SaveRef(prID, provider, repo, number); err != nil {
+		return err
+	}
+
+	s.Notify(prID)
+	return nil
+}
+
+func (s *Syncer) GetSync(prID string) (*PRSync, error) {
+	return s.store.GetSync(prID)
+}
+
+// Notify ставит PR в очередь. PR без привязки к хосту воркер просто пропустит
+func (s *Syncer) Notify(prIDs ...string) {
+	var dropped []string
+
+	s.mu.Lock()
+	for _, prID := range prIDs {
+		st, ok := s.state[prID]
+		switch {
+		case !ok:
+			if !s.enqueueLocked(prID) {
+				dropped = append(dropped, prID)
+			}
+		case st == prRunning:
+			s.state[prID] = prDirty
+		}
+	}
+	s.mu.Unlock()
+
+	s.markPending(dropped)
+}
+
+// enqueueLocked - false, если очередь полна. Такой PR надо пометить PENDING, его поднимет sweep
+func (s *Syncer) enqueueLocked(prID string) bool {
+	select {
+	case s.queue <- prID:
+		s.state[prID] = prQueued
+		return true
+	default:
+		delete(s.state, prID)
+		s.logger.Warnw("reviewer sync queue is full, postponing", "prID", prID)
+		return false
+	}
+}
+
+// markPending - PENDING в базе переживает рестарт, очередь в памяти - нет
+func (s *Syncer) markPending(prIDs []string) {
+	if len(prIDs) == 0 {
+		return
+	}
+	if err := s.store.MarkPending(prIDs...); err != nil {
+		s.logger.Errorw("error marking reviewer syncs pending", "prIDs", prIDs, "err", err)
+	}
+}
+
+// Run - воркеры очереди, блокируется до отмены ctx. При старте и потом раз в sweepInterval ставит в очередь
+// PENDING из базы: недоотправленные до рестарта и выпавшие из полной очереди
+func (s *Syncer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(s.workers)
+	for range s.workers {
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+
+	s.sweep()
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			s.markPending(s.unfinished())
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+func (s *Syncer) sweep() {
+	providers := slices.Sorted(maps.Keys(s.clients))
+	prIDs, err := s.store.ListPending(providers)
+	if err != nil {
+		s.logger.Errorw("error listing pending reviewer syncs", "err", err)
+		return
+	}
+	s.Notify(prIDs...)
+}
+
+// unfinished - PR, оставшиеся в очереди после остановки воркеров
+func (s *Syncer) unfinished() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prIDs := slices.Collect(maps.Keys(s.state))
+	clear(s.state)
+	for len(s.queue) > 0 {
+		<-s.queue
+	}
+	slices.Sort(prIDs)
+	return prIDs
+}
+
+func (s *Syncer) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case prID := <-s.queue:
+			s.mu.Lock()
+			s.state[prID] = prRunning
+			s.mu.Unlock()
+
+			if _, err := s.Sync(ctx, prID); err != nil && !errors.Is(err, ErrSyncNotFound) {
+				s.logger.Warnw("reviewer sync failed", "prID", prID, "err", err)
+			}
+
+			s.mu.Lock()
+			requeued := true
+			if s.state[prID] == prDirty {
+				requeued = s.enqueueLocked(prID)
+			} else {
+				delete(s.state, prID)
+			}
+			s.mu.Unlock()
+
+			if !requeued {
+				s.markPending([]string{prID})
+			}
+		}
+	}
+}
+
+// Sync отправляет текущих ревьюверов PR на хост с повторами. Закрытые и влитые PR на хост не отправляются
+// и получают SKIPPED: запрашивать на них ревью бессмысленно, а GitHub еще и отвечает ошибкой
+func (s *Syncer) Sync(ctx context.Context, prID string) (*PRSync, error) {
+	state, err := s.store.GetSync(prID)
+	if err != nil {
+		return nil, err
+	}
+
+	client, ok := s.clients[state.Provider]
+	if !ok {
+		return state, nil
+	}
+
+	pr, err := s.prRepo.GetPR(prID)
+	if err != nil {
+		return state, err
+	}
+	if pr.Status != pullrequest.StatusOpen && pr.Status != pullrequest.StatusDraft {
+		if state.Status == StatusSkipped {
+			return state, nil
+		}
+		state.Status, state.Attempts, state.LastError = StatusSkipped, 0, ""
+		return state, s.store.SaveState(state)
+	}
+
+	want, unmapped, err := s.wantedLogins(state.Provider, pr)
+	if err != nil {
+		return state, err
+	}
+	if state.Status == StatusSynced && equalFold(want, state.Reviewers) && slices.Equal(unmapped, state.Unmapped) {
+		return state, nil
+	}
+
+	state.Attempts = 0
+	for {
+		state.Attempts++
+		err = client.SetReviewers(ctx, state.Repo, state.Number, want, state.Reviewers)
+		if err == nil {
+			now := time.Now()
+			state.Status, state.LastError = StatusSynced, ""
+			state.Reviewers, state.Unmapped, state.SyncedAt = want, unmapped, &now
+			metrics.ObserveReviewerSync(state.Provider, "synced")
+			return state, s.store.SaveState(state)
+		}
+
+		state.LastError = err.Error()
+		if ctx.Err() != nil {
+			// сервис останавливается: PR остается в PENDING и поднимется при следующем старте
+			state.Status = StatusPending
+			if saveErr := s.store.SaveState(state); saveErr != nil {
+				return state, saveErr
+			}
+			return state, ctx.Err()
+		}
+		if !retryable(err) || state.Attempts >= s.attempts {
+			state.Status = StatusFailed
+			metrics.ObserveReviewerSync(state.Provider, "failed")
+			if saveErr := s.store.SaveState(state); saveErr != nil {
+				return state, saveErr
+			}
+			return state, err
+		}
+
+		state.Status = StatusPending
+		metrics.ObserveReviewerSync(state.Provider, "retry")
+		if saveErr := s.store.SaveState(state); saveErr != nil {
+			return state, saveErr
+		}
+		s.logger.Infow("reviewer sync attempt failed, retrying", "prID", prID, "attempt", state.Attempts, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return state, ctx.Err()
+		case <-time.After(s.delay(state.Attempts)):
+		}
+	}
+}
+
+// wantedLogins - логины назначенных ревьюверов по алфавиту и ревьюверы без логина у провайдера
+func (s *Syncer) wantedLogins(provider string, pr *pullrequest.PullRequest) ([]string, []string, error) {
+	userIDs := make([]string, 0, len(pr.AssignedReviewers))
+	for _, r := range pr.AssignedReviewers {
+		userIDs = append(userIDs, r.UserID)
+	}
+
+	logins, err := s.logins.LoginsByUsers(provider, userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	want := make([]string, 0, len(userIDs))
+	var unmapped []string
+	for _, userID := range userIDs {
+		if login, ok := logins[userID]; ok {
+			want = append(want, login)
+		} else {
+			unmapped = append(unmapped, userID)
+		}
+	}
+	slices.Sort(want)
+	slices.Sort(unmapped)
+
+	return want, unmapped, nil
+}
+
+func (s *Syncer) delay(attempt int) time.Duration {
+	d := s.backoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+func equalFold(a, b []string) bool {
+	return slices.EqualFunc(a, b, strings.EqualFold)
+}
@@ -0,0 +1,455 @@
+package reviewersync_test
+
+import (
+	"assignerPR/internal/pullrequest"
+	"assignerPR/internal/reviewersync"
+	"assignerPR/pkg/user"
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memStore - SyncRepo в памяти, history - статусы всех сохранений по порядку
+type memStore struct {
+	mu      sync.Mutex
+	syncs   map[string]reviewersync.PRSync
+	history []string
+}
+
+func newMemStore() *memStore {
+	return &memStore{syncs: map[string]reviewersync.PRSync{}}
+}
+
+func (m *memStore) SaveRef(prID, provider, repo string, number int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.syncs[prID]
+	if !ok {
+		s = reviewersync.PRSync{PullRequestID: prID, Status: reviewersync.StatusPending}
+	}
+	s.Provider, s.Repo, s.Number = provider, repo, number
+	m.syncs[prID] = s
+	return nil
+}
+
+func (m *memStore) GetSync(prID string) (*reviewersync.PRSync, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.syncs[prID]
+	if !ok {
+		return nil, reviewersync.ErrSyncNotFound
+	}
+	return &s, nil
+}
+
+func (m *memStore) SaveState(s *reviewersync.PRSync) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.syncs[s.PullRequestID] = *s
+	m.history = append(m.history, s.Status)
+	return nil
+}
+
+func (m *memStore) MarkPending(prIDs ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, prID := range prIDs {
+		if s, ok := m.syncs[prID]; ok {
+			s.Status = reviewersync.StatusPending
+			m.syncs[prID] = s
+		}
+	}
+	return nil
+}
+
+func (m *memStore) ListPending(providers []string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var prIDs []string
+	for prID, s := range m.syncs {
+		if s.Status == reviewersync.StatusPending && slices.Contains(providers, s.Provider) {
+			prIDs = append(prIDs, prID)
+		}
+	}
+	slices.Sort(prIDs)
+	return prIDs, nil
+}
+
+func (m *memStore) get(prID string) reviewersync.PRSync {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.syncs[prID]
+}
+
+// fakePRs - GetPR и Reassign поверх PR в памяти
+type fakePRs struct {
+	pullrequest.PullRequestsRepo
+
+	mu  sync.Mutex
+	prs map[string]*pullrequest.PullRequest
+}
+
+func newFakePRs(prs ...*pullrequest.PullRequest) *fakePRs {
+	f := &fakePRs{prs: map[string]*pullrequest.PullRequest{}}
+	for _, pr := range prs {
+		f.prs[pr.PullRequestID] = pr
+	}
+	return f
+}
+
+func (f *fakePRs) GetPR(prID string) (*pullrequest.PullRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pr, ok := f.prs[prID]
+	if !ok {
+		return nil, pullrequest.ErrPRNotFound
+	}
+	cp := *pr
+	cp.AssignedReviewers = append([]*user.User(nil), pr.AssignedReviewers...)
+	return &cp, nil
+}
+
+func (f *fakePRs) Reassign(prID, oldUserID, newUserID string) (*pullrequest.PullRequest, []string, error) {
+	f.mu.Lock()
+	pr := f.prs[prID]
+	for i, r := range pr.AssignedReviewers {
+		if r.UserID == oldUserID {
+			pr.AssignedReviewers[i] = &user.User{UserID: newUserID}
+		}
+	}
+	f.mu.Unlock()
+
+	got, err := f.GetPR(prID)
+	return got, []string{newUserID}, err
+}
+
+type fakeLogins map[string]string
+
+func (f fakeLogins) LoginsByUsers(provider string, userIDs []string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, id := range userIDs {
+		if login, ok := f[id]; ok {
+			out[id] = login
+		}
+	}
+	return out, nil
+}
+
+var logins = fakeLogins{"u2": "bob", "u3": "alice", "u5": "erin"}
+
+func openPR(status string, reviewers ...string) *pullrequest.PullRequest {
+	pr := &pullrequest.PullRequest{PullRequestID: "github-1873322456", AuthorID: "u1", Status: status}
+	for _, r := range reviewers {
+		pr.AssignedReviewers = append(pr.AssignedReviewers, &user.User{UserID: r})
+	}
+	return pr
+}
+
+func newGitHubSyncer(host *fakeHost, prs pullrequest.PullRequestsRepo, store reviewersync.SyncRepo,
+	attempts int, backoff time.Duration) *reviewersync.Syncer {
+	return reviewersync.NewSyncer(zap.NewNop().Sugar(), prs, store, logins,
+		reviewersync.WithClient("github", reviewersync.NewGitHubClient(host.URL, "gh-token", nil)),
+		reviewersync.WithRetry(attempts, backoff),
+	)
+}
+
+func TestSyncer_Sync_RetriesWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	host := newFakeHost(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			writeJSON(w, http.StatusBadGateway, `{"message":"Server Error"}`)
+			return
+		}
+		writeJSON(w, http.StatusCreated, `{}`)
+	})
+
+	store := newMemStore()
+	require.NoError(t, store.SaveRef("github-1873322456", "github", "octo-org/assigner", 42))
+	backoff := 20 * time.Millisecond
+	syncer := newGitHubSyncer(host, newFakePRs(openPR(pullrequest.StatusOpen, "u2", "u3", "u4")), store, 5, backoff)
+
+	start := time.Now()
+	got, err := syncer.Sync(context.Background(), "github-1873322456")
+	require.NoError(t, err)
+
+	// паузы 20ms и 40ms
+	require.GreaterOrEqual(t, time.Since(start), 3*backoff)
+	require.Equal(t, reviewersync.StatusSynced, got.Status)
+	require.Equal(t, 3, got.Attempts)
+	require.Empty(t, got.LastError)
+	require.Equal(t, []string{"alice", "bob"}, got.Reviewers)
+	require.Equal(t, []string{"u4"}, got.Unmapped)
+	require.NotNil(t, got.SyncedAt)
+
+	require.Equal(t, []string{reviewersync.StatusPending, reviewersync.StatusPending, reviewersync.StatusSynced},
+		store.history)
+	require.Equal(t, *got, store.get("github-1873322456"))
+
+	reqs := host.Requests()
+	require.Len(t, reqs, 3)
+	for _, r := range reqs {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, map[string]any{"reviewers": []any{"alice", "bob"}}, r.Body)
+	}
+}
+
+func TestSyncer_Sync_Failed(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantAttempts int
+		wantHistory  []string
+	}{
+		{
+			name:         "not retryable",
+			status:       http.StatusForbidden,
+			wantAttempts: 1,
+			wantHistory:  []string{reviewersync.StatusFailed},
+		},
+		{
+			name:         "attempts exhausted",
+			status:       http.StatusServiceUnavailable,
+			wantAttempts: 3,
+			wantHistory:  []string{reviewersync.StatusPending, reviewersync.StatusPending, reviewersync.StatusFailed},
+		},
+		{
+			name:         "rate limited",
+			status:       http.StatusTooManyRequests,
+			wantAttempts: 3,
+			wantHistory:  []string{reviewersync.StatusPending, reviewersync.StatusPending, reviewersync.StatusFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := newFakeHost(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, tt.status, `{"message":"nope"}`)
+			})
+
+			store := newMemStore()
+			require.NoError(t, store.SaveRef("github-1873322456", "github", "octo-org/assigner", 42))
+			syncer := newGitHubSyncer(host, newFakePRs(openPR(pullrequest.StatusOpen, "u2")), store, 3, time.Millisecond)
+
+			got, err := syncer.Sync(context.Background(), "github-1873322456")
+
+			var hostErr *reviewersync.HostError
+			require.ErrorAs(t, err, &hostErr)
+			require.Equal(t, tt.status, hostErr.StatusCode)
+			require.Equal(t, reviewersync.StatusFailed, got.Status)
+			require.Equal(t, tt.wantAttempts, got.Attempts)
+			require.Contains(t, got.LastError, "nope")
+			require.Nil(t, got.Reviewers)
+			require.Equal(t, tt.wantHistory, store.history)
+			require.Len(t, host.Requests(), tt.wantAttempts)
+		})
+	}
+}
+
+func TestSyncer_Sync_NothingToDo(t *testing.T) {
+	synced := reviewersync.PRSync{
+		PullRequestID: "github-1873322456", Provider: "github", Repo: "octo-org/assigner", Number: 42,
+		Status: reviewersync.StatusSynced, Reviewers: []string{"alice", "bob"},
+	}
+
+	tests := []struct {
+		name    string
+		pr      *pullrequest.PullRequest
+		sync    *reviewersync.PRSync
+		wantErr error
+	}{
+		{
+			name:    "not tracked",
+			pr:      openPR(pullrequest.StatusOpen, "u2"),
+			wantErr: reviewersync.ErrSyncNotFound,
+		},
+		{
+			name: "already synced",
+			pr:   openPR(pullrequest.StatusOpen, "u3", "u2"),
+			sync: &synced,
+		},
+		{
+			name: "provider without client",
+			pr:   openPR(pullrequest.StatusOpen, "u2"),
+			sync: &reviewersync.PRSync{PullRequestID: "github-1873322456", Provider: "gitlab", Repo: "14", Number: 57},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := newFakeHost(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusCreated, `{}`)
+			})
+
+			store := newMemStore()
+			if tt.sync != nil {
+				store.syncs[tt.sync.PullRequestID] = *tt.sync
+			}
+			syncer := newGitHubSyncer(host, newFakePRs(tt.pr), store, 3, time.Millisecond)
+
+			_, err := syncer.Sync(context.Background(), "github-1873322456")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Empty(t, host.Requests())
+			require.Empty(t, store.history)
+		})
+	}
+}
+
+func TestSyncer_Sync_SkipsClosed(t *testing.T) {
+	for _, status := range []string{pullrequest.StatusMerged, pullrequest.StatusClosed} {
+		t.Run(status, func(t *testing.T) {
+			host := newFakeHost(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusCreated, `{}`)
+			})
+
+			store := newMemStore()
+			require.NoError(t, store.SaveRef("github-1873322456", "github", "octo-org/assigner", 42))
+			syncer := newGitHubSyncer(host, newFakePRs(openPR(status, "u5")), store, 3, time.Millisecond)
+
+			got, err := syncer.Sync(context.Background(), "github-1873322456")
+			require.NoError(t, err)
+			require.Equal(t, reviewersync.StatusSkipped, got.Status)
+
+			// повторно статус не пересохраняется
+			_, err = syncer.Sync(context.Background(), "github-1873322456")
+			require.NoError(t, err)
+
+			require.Empty(t, host.Requests())
+			require.Equal(t, []string{reviewersync.StatusSkipped}, store.history)
+		})
+	}
+}
+
+// после рестарта очередь пуста, PENDING из базы должен подняться сам
+func TestSyncer_Run_SweepsPending(t *testing.T) {
+	host := newFakeHost(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{}`)
+	})
+
+	store := newMemStore()
+	require.NoError(t, store.SaveRef("github-1873322456", "github", "octo-org/assigner", 42))
+	// PR другого провайдера без клиента не трогаем
+	require.NoError(t, store.SaveRef("gitlab-98213", "gitlab", "14", 57))
+	syncer := newGitHubSyncer(host, newFakePRs(openPR(pullrequest.StatusOpen, "u2", "u3")), store, 3, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		syncer.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Eventually(t, func() bool {
+		return store.get("github-1873322456").Status == reviewersync.StatusSynced
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, []string{"alice", "bob"}, store.get("github-1873322456").Reviewers)
+	require.Equal(t, reviewersync.StatusPending, store.get("gitlab-98213").Status)
+}
+
+func TestSyncingRepo_PushesReassignToHost(t *testing.T) {
+	host := newFakeHost(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{}`)
+	})
+
+	store := newMemStore()
+	prs := newFakePRs(openPR(pullrequest.StatusOpen, "u2", "u3"))
+	syncer := newGitHubSyncer(host, prs, store, 3, time.Millisecond)
+	repo := reviewersync.NewSyncingRepo(prs, syncer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		syncer.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	syncedWith := func(reviewers ...string) func() bool {
+		return func() bool {
+			s := store.get("github-1873322456")
+			return s.Status == reviewersync.StatusSynced && slices.Equal(s.Reviewers, reviewers)
+		}
+	}
+
+	// привязка из вебхука сразу отправляет уже назначенных ревьюверов
+	require.NoError(t, syncer.TrackHostPR("github-1873322456", "github", "octo-org/assigner", 42))
+	require.Eventually(t, syncedWith("alice", "bob"), time.Second, 5*time.Millisecond)
+
+	_, added, err := repo.Reassign("github-1873322456", "u2", "u5")
+	require.NoError(t, err)
+	require.Equal(t, []string{"u5"}, added)
+	require.Eventually(t, syncedWith("alice", "erin"), time.Second, 5*time.Millisecond)
+
+	reqs := host.Requests()
+	require.Len(t, reqs, 3)
+	require.Equal(t, http.MethodPost, reqs[0].Method)
+	require.Equal(t, map[string]any{"reviewers": []any{"alice", "bob"}}, reqs[0].Body)
+	require.Equal(t, http.MethodDelete, reqs[1].Method)
+	require.Equal(t, map[string]any{"reviewers": []any{"bob"}}, reqs[1].Body)
+	require.Equal(t, http.MethodPost, reqs[2].Method)
+	require.Equal(t, map[string]any{"reviewers": []any{"alice", "erin"}}, reqs[2].Body)
+}
+
+func TestBatch_NotifiesAfterFlush(t *testing.T) {
+	host := newFakeHost(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{}`)
+	})
+
+	store := newMemStore()
+	store.syncs["github-1873322456"] = reviewersync.PRSync{
+		PullRequestID: "github-1873322456", Provider: "github", Repo: "octo-org/assigner", Number: 42,
+		Status: reviewersync.StatusSynced, Reviewers: []string{"alice", "bob"},
+	}
+	prs := newFakePRs(openPR(pullrequest.StatusOpen, "u2", "u3"))
+	syncer := newGitHubSyncer(host, prs, store, 3, time.Millisecond)
+
+	batch := &reviewersync.Batch{}
+	repo := reviewersync.NewSyncingRepo(prs, batch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		syncer.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	_, _, err := repo.Reassign("github-1873322456", "u2", "u5")
+	require.NoError(t, err)
+
+	// до коммита транзакции на хост ничего не уходит
+	time.Sleep(20 * time.Millisecond)
+	require.Empty(t, host.Requests())
+
+	batch.Flush(syncer)
+	require.Eventually(t, func() bool {
+		s := store.get("github-1873322456")
+		return s.Status == reviewersync.StatusSynced && slices.Equal(s.Reviewers, []string{"alice", "erin"})
+	}, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,126 @@
+package reviewersync
+
+import (
+	"assignerPR/internal/pullrequest"
+
+	"go.uber.org/zap"
+)
+
+// Notifier - куда SyncingRepo сообщает о PR с изменившимися ревьюверами, реализуется Syncer и Batch
+type Notifier interface {
+	Notify(prIDs ...string)
+}
+
+// Batch копит PR до коммита транзакции: воркер Syncer читает PR мимо нее и иначе мог бы отправить
+// на хост ревьюверов, которых еще нет (или уже не будет) в базе
+type Batch struct {
+	prIDs []string
+}
+
+func (b *Batch) Notify(prIDs ...string) {
+	b.prIDs = append(b.prIDs, prIDs...)
+}
+
+// Flush - вызывать только после успешного коммита
+func (b *Batch) Flush(to Notifier) {
+	if len(b.prIDs) > 0 {
+		to.Notify(b.prIDs...)
+	}
+	b.prIDs = nil
+}
+
+// PendingNotifier - Notifier для процессов без Syncer (импорт ростера): PR только помечаются PENDING,
+// на хост их отправит запущенный сервис при очередном sweep
+type PendingNotifier struct {
+	logger *zap.SugaredLogger
+	store  SyncRepo
+}
+
+func NewPendingNotifier(logger *zap.SugaredLogger, store SyncRepo) *PendingNotifier {
+	return &PendingNotifier{
+		logger: logger,
+		store:  store,
+	}
+}
+
+func (n *PendingNotifier) Notify(prIDs ...string) {
+	if err := n.store.MarkPending(prIDs...); err != nil {
+		n.logger.Errorw("error marking reviewer syncs pending", "prIDs", prIDs, "err", err)
+	}
+}
+
+// SyncingRepo - PullRequestsRepo, который после каждого изменения ревьюверов ставит PR в очередь Syncer.
+// Остальные методы проходят как есть
+type SyncingRepo struct {
+	pullrequest.PullRequestsRepo
+	syncer Notifier
+}
+
+func NewSyncingRepo(repo pullrequest.PullRequestsRepo, syncer Notifier) *SyncingRepo {
+	return &SyncingRepo{
+		PullRequestsRepo: repo,
+		syncer:           syncer,
+	}
+}
+
+func (r *SyncingRepo) notifyPR(pr *pullrequest.PullRequest, err error) (*pullrequest.PullRequest, error) {
+	if err == nil {
+		r.syncer.Notify(pr.PullRequestID)
+	}
+	return pr, err
+}
+
+func (r *SyncingRepo) notifyReport(report *pullrequest.ReleaseReport, err error) (*pullrequest.ReleaseReport, error) {
+	if err == nil {
+		for _, m := range report.Moved {
+			r.syncer.Notify(m.PullRequestID)
+		}
+	}
+	return report, err
+}
+
+func (r *SyncingRepo) CreatePR(prID, prName, authorID string, opts pullrequest.CreatePROptions) (*pullrequest.PullRequest, error) {
+	return r.notifyPR(r.PullRequestsRepo.CreatePR(prID, prName, authorID, opts))
+}
+
+func (r *SyncingRepo) Ready(prID string) (*pullrequest.PullRequest, error) {
+	return r.notifyPR(r.PullRequestsRepo.Ready(prID))
+}
+
+// Reopen возвращает прежних ревьюверов, а на закрытом PR хост их мог уже не показывать
+func (r *SyncingRepo) Reopen(prID string) (*pullrequest.PullRequest, error) {
+	return r.notifyPR(r.PullRequestsRepo.Reopen(prID))
+}
+
+// Update - смена автора снимает его с ревью
+func (r *SyncingRepo) Update(prID string, upd pullrequest.PRUpdate) (*pullrequest.PullRequest, error) {
+	pr, err := r.PullRequestsRepo.Update(prID, upd)
+	if upd.AuthorID == nil {
+		return pr, err
+	}
+	return r.notifyPR(pr, err)
+}
+
+func (r *SyncingRepo) Reassign(prID, oldUserID, newUserID string) (*pullrequest.PullRequest, []string, error) {
+	pr, added, err := r.PullRequestsRepo.Reassign(prID, oldUserID, newUserID)
+	if err == nil {
+		r.syncer.Notify(prID)
+	}
+	return pr, added, err
+}
+
+func (r *SyncingRepo) AddReviewer(prID, userID string) (*pullrequest.PullRequest, error) {
+	return r.notifyPR(r.PullRequestsRepo.AddReviewer(prID, userID))
+}
+
+func (r *SyncingRepo) RemoveReviewer(prID, userID string) (*pullrequest.PullRequest, error) {
+	return r.notifyPR(r.PullRequestsRepo.RemoveReviewer(prID, userID))
+}
+
+func (r *SyncingRepo) ReleaseReviews(userIDs []string) (*pullrequest.ReleaseReport, error) {
+	return r.notifyReport(r.PullRequestsRepo.ReleaseReviews(userIDs))
+}
+
+func (r *SyncingRepo) ReleaseTeamReviews(userID, teamName string) (*pullrequest.ReleaseReport, error) {
+	return r.notifyReport(r.PullRequestsRepo.ReleaseTeamReviews(userID, teamName))
+}
@@ -0,0 +1,148 @@
+package roster
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// В CSV одна строка на пользователя, пустая команда - строка без user_id
+var csvHeader = []string{"team_name", "user_id", "username", "is_active", "max_open_reviews"}
+
+func Read(r io.Reader, format string) (*Roster, error) {
+	var (
+		roster *Roster
+		err    error
+	)
+	switch format {
+	case FormatJSON:
+		roster, err = readJSON(r)
+	case FormatCSV:
+		roster, err = readCSV(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := roster.Validate(); err != nil {
+		return nil, err
+	}
+	return roster, nil
+}
+
+func Write(w io.Writer, format string, roster *Roster) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(roster)
+	case FormatCSV:
+		return writeCSV(w, roster)
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+func readJSON(r io.Reader) (*Roster, error) {
+	var roster Roster
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&roster); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRoster, err)
+	}
+	return &roster, nil
+}
+
+func readCSV(r io.Reader) (*Roster, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRoster, err)
+	}
+	if len(records) == 0 {
+		return &Roster{Teams: []Team{}}, nil
+	}
+	for i, col := range csvHeader {
+		if records[0][i] != col {
+			return nil, fmt.Errorf("%w: expected header %v", ErrInvalidRoster, csvHeader)
+		}
+	}
+
+	roster := &Roster{Teams: []Team{}}
+	// порядок команд - по первому появлению в файле
+	index := make(map[string]int)
+	for line, rec := range records[1:] {
+		teamName := rec[0]
+		i, ok := index[teamName]
+		if !ok {
+			i = len(roster.Teams)
+			index[teamName] = i
+			roster.Teams = append(roster.Teams, Team{TeamName: teamName, Members: []Member{}})
+		}
+		if rec[1] == "" {
+			continue
+		}
+
+		// пустой is_active - активен
+		var isActive *bool
+		if rec[3] != "" {
+			b, err := strconv.ParseBool(rec[3])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: bad is_active %q", ErrInvalidRoster, line+2, rec[3])
+			}
+			isActive = &b
+		}
+		var limit *int
+		if rec[4] != "" {
+			n, err := strconv.Atoi(rec[4])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: bad max_open_reviews %q", ErrInvalidRoster, line+2, rec[4])
+			}
+			limit = &n
+		}
+
+		roster.Teams[i].Members = append(roster.Teams[i].Members, Member{
+			UserID:         rec[1],
+			Username:       rec[2],
+			IsActive:       isActive,
+			MaxOpenReviews: limit,
+		})
+	}
+
+	return roster, nil
+}
+
+func writeCSV(w io.Writer, roster *Roster) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, t := range roster.Teams {
+		if len(t.Members) == 0 {
+			if err := cw.Write([]string{t.TeamName, "", "", "", ""}); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, m := range t.Members {
+			limit := ""
+			if m.MaxOpenReviews != nil {
+				limit = strconv.Itoa(*m.MaxOpenReviews)
+			}
+			if err := cw.Write([]string{t.TeamName, m.UserID, m.Username, strconv.FormatBool(m.Active()), limit}); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,117 @@
+package roster_test
+
+import (
+	"assignerPR/internal/roster"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(n int) *int { return &n }
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestRoster_RoundTrip(t *testing.T) {
+	want := &roster.Roster{Teams: []roster.Team{
+		{TeamName: "backend", Members: []roster.Member{
+			{UserID: "u1", Username: "alice", IsActive: boolPtr(true), MaxOpenReviews: intPtr(3)},
+			{UserID: "u2", Username: "bob", IsActive: boolPtr(false)},
+		}},
+		{TeamName: "empty", Members: []roster.Member{}},
+	}}
+
+	for _, format := range []string{roster.FormatJSON, roster.FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, roster.Write(&buf, format, want))
+
+			got, err := roster.Read(&buf, format)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestRoster_Read_MissingIsActive(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{
+			name:   "json",
+			format: roster.FormatJSON,
+			input:  `{"teams":[{"team_name":"backend","members":[{"user_id":"u1","username":"alice"}]}]}`,
+		},
+		{
+			name:   "csv",
+			format: roster.FormatCSV,
+			input:  "team_name,user_id,username,is_active,max_open_reviews\nbackend,u1,alice,,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := roster.Read(strings.NewReader(tt.input), tt.format)
+			require.NoError(t, err)
+
+			m := got.Teams[0].Members[0]
+			require.Nil(t, m.IsActive)
+			require.True(t, m.Active())
+		})
+	}
+}
+
+func TestRoster_Read_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{
+			name:   "user in two teams",
+			format: roster.FormatCSV,
+			input:  "team_name,user_id,username,is_active,max_open_reviews\nbackend,u1,alice,true,\nfrontend,u1,alice,true,\n",
+		},
+		{
+			name:   "bad header",
+			format: roster.FormatCSV,
+			input:  "team,user,name,active,limit\nbackend,u1,alice,true,\n",
+		},
+		{
+			name:   "bad is_active",
+			format: roster.FormatCSV,
+			input:  "team_name,user_id,username,is_active,max_open_reviews\nbackend,u1,alice,yes please,\n",
+		},
+		{
+			name:   "negative limit",
+			format: roster.FormatJSON,
+			input:  `{"teams":[{"team_name":"backend","members":[{"user_id":"u1","username":"alice","is_active":true,"max_open_reviews":-1}]}]}`,
+		},
+		{
+			name:   "team listed twice",
+			format: roster.FormatJSON,
+			input:  `{"teams":[{"team_name":"backend","members":[]},{"team_name":"backend","members":[]}]}`,
+		},
+		{
+			name:   "unknown field",
+			format: roster.FormatJSON,
+			input:  `{"teams":[],"users":[]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := roster.Read(strings.NewReader(tt.input), tt.format)
+			require.ErrorIs(t, err, roster.ErrInvalidRoster)
+			require.Nil(t, got)
+		})
+	}
+}
+
+func TestRoster_Read_UnknownFormat(t *testing.T) {
+	_, err := roster.Read(strings.NewReader(""), "xml")
+	require.ErrorIs(t, err, roster.ErrUnknownFormat)
+}
@@ -0,0 +1,207 @@
+package roster
+
+import (
+	"assignerPR/internal/pullrequest"
+	"assignerPR/pkg/team"
+	"assignerPR/pkg/user"
+	"errors"
+	"slices"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// errDryRun - откат транзакции после подсчета изменений в режиме dry-run
+var errDryRun = errors.New("roster dry run")
+
+// Repos - репозитории поверх одной транзакции импорта
+type Repos struct {
+	Teams team.TeamsRepo
+	Users user.UsersRepo
+	PRs   pullrequest.PullRequestsRepo
+}
+
+type MovedMember struct {
+	UserID       string `json:"user_id"`
+	FromTeamName string `json:"from_team_name"`
+}
+
+type TeamDiff struct {
+	TeamName      string        `json:"team_name"`
+	Created       bool          `json:"created"`
+	Added         []string      `json:"added"`
+	Updated       []string      `json:"updated"`
+	Moved         []MovedMember `json:"moved"`
+	Deactivated   []string      `json:"deactivated"`
+	LimitsChanged []string      `json:"limits_changed"`
+}
+
+// ReassignedReviews - сколько ревью сняли с деактивированных и переведенных и скольким PR не хватило замены
+type ReassignedReviews struct {
+	Moved int `json:"moved"`
+	Short int `json:"short"`
+}
+
+type ImportReport struct {
+	DryRun       bool              `json:"dry_run"`
+	Teams        []TeamDiff        `json:"teams"`
+	Reassignment ReassignedReviews `json:"reassignment"`
+}
+
+type Importer struct {
+	logger   *zap.SugaredLogger
+	db       *gorm.DB
+	newRepos func(tx *gorm.DB) Repos
+}
+
+func NewImporter(logger *zap.SugaredLogger, db *gorm.DB, newRepos func(tx *gorm.DB) Repos) *Importer {
+	return &Importer{
+		logger:   logger,
+		db:       db,
+		newRepos: newRepos,
+	}
+}
+
+// Import приводит базу к выгрузке одной транзакцией: команды из выгрузки синхронизируются как в /team/sync,
+// команды, которых в ней нет, не трогаются. В режиме dryRun все изменения откатываются, отчет тот же
+func (imp *Importer) Import(roster *Roster, dryRun bool) (*ImportReport, error) {
+	imp.logger.Debugw("Import()", "teams", len(roster.Teams), "dryRun", dryRun)
+
+	if err := roster.Validate(); err != nil {
+		return nil, err
+	}
+
+	var report *ImportReport
+	err := imp.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		report, err = imp.importInTx(imp.newRepos(tx), roster)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		imp.logger.Errorw("roster import failed", "err", err)
+		return nil, err
+	}
+
+	report.DryRun = dryRun
+	imp.logger.Infow("roster imported", "teams", len(report.Teams), "dryRun", dryRun)
+	return report, nil
+}
+
+func (imp *Importer) importInTx(repos Repos, roster *Roster) (*ImportReport, error) {
+	report := &ImportReport{Teams: make([]TeamDiff, 0, len(roster.Teams))}
+
+	diffs := make([]TeamDiff, len(roster.Teams))
+	for i, t := range roster.Teams {
+		diffs[i] = TeamDiff{TeamName: t.TeamName, Moved: []MovedMember{}, LimitsChanged: []string{}}
+
+		if _, err := repos.Teams.GetTeam(t.TeamName); err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if _, err := repos.Teams.CreateTeam(t.TeamName, nil); err != nil {
+				return nil, err
+			}
+			diffs[i].Created = true
+		}
+	}
+
+	// Переводы между командами делаются до синхронизации: иначе команда, из которой пользователя забирают,
+	// может синхронизироваться раньше и деактивировать его как пропавшего
+	for i, t := range roster.Teams {
+		for _, m := range t.Members {
+			_, fromTeam, err := repos.Teams.MoveMember(m.UserID, t.TeamName)
+			if errors.Is(err, user.ErrUserNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if fromTeam != t.TeamName {
+				diffs[i].Moved = append(diffs[i].Moved, MovedMember{UserID: m.UserID, FromTeamName: fromTeam})
+			}
+		}
+	}
+
+	var deactivated []string
+	for i, t := range roster.Teams {
+		members := make([]*user.User, 0, len(t.Members))
+		for _, m := range t.Members {
+			members = append(members, &user.User{
+				UserID:   m.UserID,
+				Username: m.Username,
+				TeamName: t.TeamName,
+				IsActive: m.Active(),
+			})
+		}
+
+		result, err := repos.Teams.SyncTeam(t.TeamName, members)
+		if err != nil {
+			return nil, err
+		}
+		diffs[i].Added = result.Added
+		diffs[i].Updated = result.Updated
+		diffs[i].Deactivated = result.Deactivated
+		deactivated = append(deactivated, result.Deactivated...)
+
+		limits := make(map[string]*int, len(result.Team.Members))
+		for _, u := range result.Team.Members {
+			limits[u.UserID] = u.MaxOpenReviews
+		}
+		for _, m := range t.Members {
+			if sameLimit(limits[m.UserID], m.MaxOpenReviews) {
+				continue
+			}
+			if _, err := repos.Users.SetMaxOpenReviews(m.UserID, m.MaxOpenReviews); err != nil {
+				return nil, err
+			}
+			diffs[i].LimitsChanged = append(diffs[i].LimitsChanged, m.UserID)
+		}
+	}
+
+	released, err := repos.PRs.ReleaseReviews(deactivated)
+	if err != nil {
+		return nil, err
+	}
+	report.Reassignment.Moved += len(released.Moved)
+	report.Reassignment.Short += len(released.Short)
+
+	for _, d := range diffs {
+		for _, m := range d.Moved {
+			// с деактивированных уже сняты все ревью
+			if slices.Contains(deactivated, m.UserID) {
+				continue
+			}
+			released, err := repos.PRs.ReleaseTeamReviews(m.UserID, m.FromTeamName)
+			if err != nil {
+				return nil, err
+			}
+			report.Reassignment.Moved += len(released.Moved)
+			report.Reassignment.Short += len(released.Short)
+		}
+	}
+
+	report.Teams = diffs
+	return report, nil
+}
+
+func sameLimit(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// Export - текущий состав всех команд
+func Export(teamsRepo team.TeamsRepo) (*Roster, error) {
+	teams, err := teamsRepo.ListTeams()
+	if err != nil {
+		return nil, err
+	}
+	return FromTeams(teams), nil
+}
@@ -0,0 +1,160 @@
+package roster_test
+
+import (
+	"assignerPR/internal/pullrequest"
+	"assignerPR/internal/roster"
+	"assignerPR/pkg/team"
+	"assignerPR/pkg/user"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// fakeTeams - состав в памяти, нужные импорту методы реализованы, остальные паникуют через nil-интерфейс
+type fakeTeams struct {
+	team.TeamsRepo
+	teams map[string]bool
+	users map[string]*user.User
+	calls []string
+}
+
+func (f *fakeTeams) GetTeam(teamName string) (*team.Team, error) {
+	if !f.teams[teamName] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &team.Team{TeamName: teamName}, nil
+}
+
+func (f *fakeTeams) CreateTeam(teamName string, _ []*user.User) (*team.Team, error) {
+	f.teams[teamName] = true
+	return &team.Team{TeamName: teamName}, nil
+}
+
+func (f *fakeTeams) MoveMember(userID, toTeam string) (*user.User, string, error) {
+	u, ok := f.users[userID]
+	if !ok {
+		return nil, "", user.ErrUserNotFound
+	}
+	from := u.TeamName
+	u.TeamName = toTeam
+	return u, from, nil
+}
+
+func (f *fakeTeams) SyncTeam(teamName string, members []*user.User) (*team.SyncResult, error) {
+	f.calls = append(f.calls, "sync "+teamName)
+	result := &team.SyncResult{Team: &team.Team{TeamName: teamName}, Added: []string{}, Updated: []string{}, Deactivated: []string{}}
+
+	listed := make(map[string]bool)
+	for _, m := range members {
+		listed[m.UserID] = true
+		if _, ok := f.users[m.UserID]; !ok {
+			result.Added = append(result.Added, m.UserID)
+		}
+		u := *m
+		f.users[m.UserID] = &u
+		result.Team.Members = append(result.Team.Members, &u)
+	}
+	for id, u := range f.users {
+		if u.TeamName == teamName && !listed[id] && u.IsActive {
+			u.IsActive = false
+			result.Deactivated = append(result.Deactivated, id)
+		}
+	}
+	return result, nil
+}
+
+type fakeUsers struct {
+	user.UsersRepo
+	limits map[string]*int
+}
+
+func (f *fakeUsers) SetMaxOpenReviews(userID string, limit *int) (*user.User, error) {
+	f.limits[userID] = limit
+	return &user.User{UserID: userID, MaxOpenReviews: limit}, nil
+}
+
+type fakePRs struct {
+	pullrequest.PullRequestsRepo
+	released     []string
+	teamReleased []string
+}
+
+func (f *fakePRs) ReleaseReviews(userIDs []string) (*pullrequest.ReleaseReport, error) {
+	f.released = append(f.released, userIDs...)
+	return &pullrequest.ReleaseReport{}, nil
+}
+
+func (f *fakePRs) ReleaseTeamReviews(userID, teamName string) (*pullrequest.ReleaseReport, error) {
+	f.teamReleased = append(f.teamReleased, userID+"@"+teamName)
+	return &pullrequest.ReleaseReport{Moved: []pullrequest.MovedReview{{PullRequestID: "pr-1", FromUserID: userID}}}, nil
+}
+
+func TestImporter_Import(t *testing.T) {
+	tests := []struct {
+		name   string
+		dryRun bool
+		expect func(sqlmock.Sqlmock)
+	}{
+		{name: "apply", expect: func(m sqlmock.Sqlmock) { m.ExpectBegin(); m.ExpectCommit() }},
+		{name: "dry run", dryRun: true, expect: func(m sqlmock.Sqlmock) { m.ExpectBegin(); m.ExpectRollback() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer mockDB.Close()
+
+			db, err := gorm.Open(postgres.New(postgres.Config{Conn: mockDB}), &gorm.Config{})
+			require.NoError(t, err)
+			tt.expect(mock)
+
+			teams := &fakeTeams{
+				teams: map[string]bool{"backend": true, "frontend": true},
+				users: map[string]*user.User{
+					"u1": {UserID: "u1", Username: "alice", TeamName: "frontend", IsActive: true},
+					"u2": {UserID: "u2", Username: "bob", TeamName: "frontend", IsActive: true},
+				},
+			}
+			users := &fakeUsers{limits: map[string]*int{}}
+			prs := &fakePRs{}
+
+			importer := roster.NewImporter(zap.NewNop().Sugar(), db, func(*gorm.DB) roster.Repos {
+				return roster.Repos{Teams: teams, Users: users, PRs: prs}
+			})
+
+			// frontend синхронизируется первым, но u1 к этому моменту уже переведен в backend
+			report, err := importer.Import(&roster.Roster{Teams: []roster.Team{
+				{TeamName: "frontend", Members: []roster.Member{
+					{UserID: "u2", Username: "bob", IsActive: boolPtr(true)},
+				}},
+				{TeamName: "backend", Members: []roster.Member{
+					{UserID: "u1", Username: "alice", IsActive: boolPtr(true), MaxOpenReviews: intPtr(2)},
+					// без is_active пользователь активен
+					{UserID: "u3", Username: "carol"},
+				}},
+				{TeamName: "platform", Members: []roster.Member{}},
+			}}, tt.dryRun)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.dryRun, report.DryRun)
+			require.Len(t, report.Teams, 3)
+			require.Empty(t, report.Teams[0].Deactivated)
+			require.Equal(t, []roster.MovedMember{{UserID: "u1", FromTeamName: "frontend"}}, report.Teams[1].Moved)
+			require.Equal(t, []string{"u3"}, report.Teams[1].Added)
+			require.Equal(t, []string{"u1"}, report.Teams[1].LimitsChanged)
+			require.True(t, report.Teams[2].Created)
+			require.True(t, teams.users["u3"].IsActive)
+
+			require.Empty(t, prs.released)
+			require.Equal(t, []string{"u1@frontend"}, prs.teamReleased)
+			require.Equal(t, roster.ReassignedReviews{Moved: 1}, report.Reassignment)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
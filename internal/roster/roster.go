@@ -0,0 +1,87 @@
+package roster
+
+import (
+	"assignerPR/pkg/team"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidRoster = errors.New("INVALID_ROSTER")
+	ErrUnknownFormat = errors.New("UNKNOWN_ROSTER_FORMAT")
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Member - пользователь в выгрузке. IsActive nil - активен, как и в /team/sync. MaxOpenReviews nil - лимит команды
+type Member struct {
+	UserID         string `json:"user_id"`
+	Username       string `json:"username"`
+	IsActive       *bool  `json:"is_active,omitempty"`
+	MaxOpenReviews *int   `json:"max_open_reviews,omitempty"`
+}
+
+func (m Member) Active() bool {
+	return m.IsActive == nil || *m.IsActive
+}
+
+type Team struct {
+	TeamName string   `json:"team_name"`
+	Members  []Member `json:"members"`
+}
+
+// Roster - полный состав: все команды и их участники. Пользователь может быть только в одной команде
+type Roster struct {
+	Teams []Team `json:"teams"`
+}
+
+// Validate - проверки, без которых импорт упадет на середине
+func (r *Roster) Validate() error {
+	teams := make(map[string]struct{}, len(r.Teams))
+	users := make(map[string]string)
+
+	for _, t := range r.Teams {
+		if t.TeamName == "" || len(t.TeamName) > 64 {
+			return fmt.Errorf("%w: bad team name %q", ErrInvalidRoster, t.TeamName)
+		}
+		if _, ok := teams[t.TeamName]; ok {
+			return fmt.Errorf("%w: team %q listed twice", ErrInvalidRoster, t.TeamName)
+		}
+		teams[t.TeamName] = struct{}{}
+
+		for _, m := range t.Members {
+			if m.UserID == "" || len(m.UserID) > 64 || m.Username == "" || len(m.Username) > 255 {
+				return fmt.Errorf("%w: bad member %q in team %q", ErrInvalidRoster, m.UserID, t.TeamName)
+			}
+			if m.MaxOpenReviews != nil && *m.MaxOpenReviews < 0 {
+				return fmt.Errorf("%w: negative max_open_reviews for %q", ErrInvalidRoster, m.UserID)
+			}
+			if other, ok := users[m.UserID]; ok {
+				return fmt.Errorf("%w: user %q is in teams %q and %q", ErrInvalidRoster, m.UserID, other, t.TeamName)
+			}
+			users[m.UserID] = t.TeamName
+		}
+	}
+
+	return nil
+}
+
+func FromTeams(teams []*team.Team) *Roster {
+	out := &Roster{Teams: make([]Team, 0, len(teams))}
+	for _, t := range teams {
+		members := make([]Member, 0, len(t.Members))
+		for _, u := range t.Members {
+			members = append(members, Member{
+				UserID:         u.UserID,
+				Username:       u.Username,
+				IsActive:       &u.IsActive,
+				MaxOpenReviews: u.MaxOpenReviews,
+			})
+		}
+		out.Teams = append(out.Teams, Team{TeamName: t.TeamName, Members: members})
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+)
+
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// Действия над PR, к которым сводятся события провайдеров
+const (
+	ActionOpen   = "open"
+	ActionReopen = "reopen"
+	ActionReady  = "ready"
+	ActionMerge  = "merge"
+	ActionClose  = "close"
+	// ActionUpdate - название и метки, приходят целиком
+	ActionUpdate = "update"
+)
+
+// Чем закончилась обработка события
+const (
+	OutcomeApplied = "applied"
+	OutcomeSkipped = "skipped"
+	OutcomeIgnored = "ignored"
+)
+
+// Причины пропуска, отдаются провайдеру в ответе, чтобы было видно в логе доставок
+const (
+	ReasonUnknownAuthor = "UNKNOWN_AUTHOR"
+	ReasonPRExists      = "PR_EXISTS"
+	ReasonPRNotFound    = "PR_NOT_FOUND"
+	// ReasonPRMerged - у нас PR уже влит и больше не меняется (например, метки, навешенные после мержа)
+	ReasonPRMerged = "PR_MERGED"
+	// ReasonAuthorInactive - автор уволен, новых PR от его имени не заводим
+	ReasonAuthorInactive = "AUTHOR_INACTIVE"
+)
+
+var (
+	ErrBadSignature = errors.New("WEBHOOK_BAD_SIGNATURE")
+	ErrBadPayload   = errors.New("WEBHOOK_BAD_PAYLOAD")
+)
+
+// maxTitleLen - pull_request_name varchar(255)
+const maxTitleLen = 255
+
+// Event - событие PR у провайдера, сведенное к нашим действиям
+type Event struct {
+	Provider string
+	Action   string
+	// ExternalID - постоянный id PR у провайдера, не номер: номер уникален только внутри репозитория
+	ExternalID  int64
+	Title       string
+	AuthorLogin string
+	// AuthorExternalID - id автора у провайдера: у GitLab логина автора в событии может не быть
+	AuthorExternalID int64
+	Draft            bool
+	Labels           []string
+	// Repo и Number - где PR живет у провайдера: owner/name и номер у GitHub, id проекта и iid у GitLab
+	Repo   string
+	Number int64
+}
+
+// PullRequestID - id PR у нас, например github-1234567
+func (e *Event) PullRequestID() string {
+	return e.Provider + "-" + strconv.FormatInt(e.ExternalID, 10)
+}
+
+func truncateTitle(title string) string {
+	runes := []rune(title)
+	if len(runes) <= maxTitleLen {
+		return title
+	}
+	return string(runes[:maxTitleLen])
+}
@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	GitHubSignatureHeader = "X-Hub-Signature-256"
+	GitHubEventHeader     = "X-GitHub-Event"
+	GitHubDeliveryHeader  = "X-GitHub-Delivery"
+
+	githubEventPullRequest = "pull_request"
+	githubEventPing        = "ping"
+)
+
+// VerifyGitHubSignature - HMAC-SHA256 тела запроса, заголовок вида sha256=<hex>
+func VerifyGitHubSignature(secret, body []byte, header string) error {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok || len(secret) == 0 {
+		return ErrBadSignature
+	}
+
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return ErrBadSignature
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+type githubPREvent struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	PullRequest *struct {
+		ID     int64  `json:"id"`
+		Number int64  `json:"number"`
+		Title  string `json:"title"`
+		Draft  bool   `json:"draft"`
+		Merged bool   `json:"merged"`
+		User   struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	} `json:"pull_request"`
+}
+
+// ParseGitHub - событие, которое нас не интересует (ping, push, synchronize и т.д.), дает nil без ошибки
+func ParseGitHub(eventType string, body []byte) (*Event, error) {
+	if eventType != githubEventPullRequest {
+		return nil, nil
+	}
+
+	var payload githubPREvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadPayload, err)
+	}
+	if payload.PullRequest == nil || payload.PullRequest.ID == 0 {
+		return nil, fmt.Errorf("%w: no pull_request", ErrBadPayload)
+	}
+	pr := payload.PullRequest
+
+	var action string
+	switch payload.Action {
+	case "opened":
+		action = ActionOpen
+	case "reopened":
+		action = ActionReopen
+	case "ready_for_review":
+		action = ActionReady
+	case "closed":
+		action = ActionClose
+		if pr.Merged {
+			action = ActionMerge
+		}
+	case "edited", "labeled", "unlabeled":
+		action = ActionUpdate
+	default:
+		return nil, nil
+	}
+
+	labels := make([]string, 0, len(pr.Labels))
+	for _, l := range pr.Labels {
+		labels = append(labels, l.Name)
+	}
+
+	return &Event{
+		Provider:    ProviderGitHub,
+		Action:      action,
+		ExternalID:  pr.ID,
+		Title:       truncateTitle(pr.Title),
+		AuthorLogin: pr.User.Login,
+		Draft:       pr.Draft,
+		Labels:      labels,
+		Repo:        payload.Repository.FullName,
+		Number:      pr.Number,
+	}, nil
+}
+
+// IsGitHubPing - GitHub шлет ping при создании хука, на него отвечаем без обработки
+func IsGitHubPing(eventType string) bool {
+	return eventType == githubEventPing
+}
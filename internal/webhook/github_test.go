@@ -0,0 +1,350 @@
+package webhook_test
+
+import (
+	"assignerPR/internal/pullrequest"
+	"assignerPR/internal/webhook"
+	"assignerPR/pkg/user"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testSecret = []byte("webhook-test-secret")
+
+// fakePRs - PR в памяти с теми же переходами статусов, что и в PullRequestsRepoPg
+type fakePRs struct {
+	pullrequest.PullRequestsRepo
+	prs   map[string]*pullrequest.PullRequest
+	calls []string
+	// offboarded - авторы-надгробия, от их имени PR не заводятся
+	offboarded map[string]bool
+}
+
+func newFakePRs() *fakePRs {
+	return &fakePRs{prs: map[string]*pullrequest.PullRequest{}}
+}
+
+func (f *fakePRs) get(prID string) (*pullrequest.PullRequest, error) {
+	pr, ok := f.prs[prID]
+	if !ok {
+		return nil, pullrequest.ErrPRNotFound
+	}
+	return pr, nil
+}
+
+func (f *fakePRs) CreatePR(prID, prName, authorID string, opts pullrequest.CreatePROptions) (*pullrequest.PullRequest, error) {
+	f.calls = append(f.calls, "create "+prID)
+	if _, ok := f.prs[prID]; ok {
+		return nil, pullrequest.ErrPRExists
+	}
+	if f.offboarded[authorID] {
+		return nil, pullrequest.ErrAuthorInactive
+	}
+	status := pullrequest.StatusOpen
+	if opts.IsDraft {
+		status = pullrequest.StatusDraft
+	}
+	pr := &pullrequest.PullRequest{PullRequestID: prID, PullRequestName: prName, AuthorID: authorID, Status: status, Labels: opts.Labels}
+	f.prs[prID] = pr
+	return pr, nil
+}
+
+func (f *fakePRs) Ready(prID string) (*pullrequest.PullRequest, error) {
+	f.calls = append(f.calls, "ready "+prID)
+	pr, err := f.get(prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Status == pullrequest.StatusDraft {
+		pr.Status = pullrequest.StatusOpen
+	}
+	return pr, nil
+}
+
+func (f *fakePRs) Reopen(prID string) (*pullrequest.PullRequest, error) {
+	f.calls = append(f.calls, "reopen "+prID)
+	pr, err := f.get(prID)
+	if err != nil {
+		return nil, err
+	}
+	switch pr.Status {
+	case pullrequest.StatusMerged:
+		return nil, pullrequest.ErrPRMerged
+	case pullrequest.StatusDraft:
+		return nil, pullrequest.ErrPRDraft
+	}
+	pr.Status = pullrequest.StatusOpen
+	return pr, nil
+}
+
+func (f *fakePRs) GetPR(prID string) (*pullrequest.PullRequest, error) {
+	return f.get(prID)
+}
+
+func (f *fakePRs) Merge(prID string) (*pullrequest.PullRequest, error) {
+	f.calls = append(f.calls, "merge "+prID)
+	pr, err := f.get(prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Status == pullrequest.StatusClosed {
+		return nil, pullrequest.ErrPRClosed
+	}
+	pr.Status = pullrequest.StatusMerged
+	return pr, nil
+}
+
+func (f *fakePRs) Close(prID string) (*pullrequest.PullRequest, error) {
+	f.calls = append(f.calls, "close "+prID)
+	pr, err := f.get(prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Status == pullrequest.StatusMerged {
+		return nil, pullrequest.ErrPRMerged
+	}
+	pr.Status = pullrequest.StatusClosed
+	return pr, nil
+}
+
+func (f *fakePRs) Update(prID string, upd pullrequest.PRUpdate) (*pullrequest.PullRequest, error) {
+	f.calls = append(f.calls, "update "+prID)
+	pr, err := f.get(prID)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Status == pullrequest.StatusMerged {
+		return nil, pullrequest.ErrPRMerged
+	}
+	pr.PullRequestName = *upd.Name
+	pr.Labels = *upd.Labels
+	return pr, nil
+}
+
+// fakeTracker - последняя привязка каждого PR к хосту
+type fakeTracker map[string]string
+
+func (f fakeTracker) TrackHostPR(prID, provider, repo string, number int64) error {
+	f[prID] = fmt.Sprintf("%s %s#%d", provider, repo, number)
+	return nil
+}
+
+// fakeIdentities - "provider/login" и "provider/id" -> user_id
+type fakeIdentities map[string]string
+
+func (f fakeIdentities) ResolveIdentity(provider, login string) (string, error) {
+	id, ok := f[provider+"/"+strings.ToLower(login)]
+	if !ok {
+		return "", user.ErrIdentityNotFound
+	}
+	return id, nil
+}
+
+func (f fakeIdentities) ResolveExternalID(provider string, externalID int64) (string, error) {
+	id, ok := f[fmt.Sprintf("%s/%d", provider, externalID)]
+	if !ok {
+		return "", user.ErrIdentityNotFound
+	}
+	return id, nil
+}
+
+func sign(body []byte) string {
+	mac := hmac.New(sha256.New, testSecret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// replayGitHub - доставка записанного payload так же, как ее разбирает хендлер: подпись, тип события, обработка
+func replayGitHub(t *testing.T, p *webhook.Processor, eventType, fixture string) *webhook.Result {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", "github", fixture))
+	require.NoError(t, err)
+	require.NoError(t, webhook.VerifyGitHubSignature(testSecret, body, sign(body)))
+
+	ev, err := webhook.ParseGitHub(eventType, body)
+	require.NoError(t, err)
+	if ev == nil {
+		return nil
+	}
+
+	res, err := p.Apply(ev)
+	require.NoError(t, err)
+	return res
+}
+
+const fixturePRID = "github-1873322456"
+
+func TestGitHub_ReplayLifecycle(t *testing.T) {
+	prs := newFakePRs()
+	tracker := fakeTracker{}
+	p := webhook.NewProcessor(zap.NewNop().Sugar(), prs, fakeIdentities{"github/octocat-dev": "u1"}, tracker)
+
+	steps := []struct {
+		eventType   string
+		fixture     string
+		wantOutcome string
+		wantReason  string
+		wantStatus  string
+	}{
+		{"pull_request", "pull_request.opened_draft.json", webhook.OutcomeApplied, "", pullrequest.StatusDraft},
+		// повторная доставка того же события
+		{"pull_request", "pull_request.opened_draft.json", webhook.OutcomeSkipped, webhook.ReasonPRExists, pullrequest.StatusDraft},
+		{"pull_request", "pull_request.ready_for_review.json", webhook.OutcomeApplied, "", pullrequest.StatusOpen},
+		{"pull_request", "pull_request.labeled.json", webhook.OutcomeApplied, "", pullrequest.StatusOpen},
+		{"pull_request", "pull_request.edited.json", webhook.OutcomeApplied, "", pullrequest.StatusOpen},
+		{"pull_request", "pull_request.synchronize.json", "", "", pullrequest.StatusOpen},
+		{"pull_request", "pull_request.closed.json", webhook.OutcomeApplied, "", pullrequest.StatusClosed},
+		{"pull_request", "pull_request.reopened.json", webhook.OutcomeApplied, "", pullrequest.StatusOpen},
+		{"pull_request", "pull_request.closed_merged.json", webhook.OutcomeApplied, "", pullrequest.StatusMerged},
+		{"pull_request", "pull_request.closed_merged.json", webhook.OutcomeApplied, "", pullrequest.StatusMerged},
+		// метки после мержа уже не применяются
+		{"pull_request", "pull_request.labeled.json", webhook.OutcomeSkipped, webhook.ReasonPRMerged, pullrequest.StatusMerged},
+		{"ping", "ping.json", "", "", pullrequest.StatusMerged},
+	}
+
+	for _, s := range steps {
+		res := replayGitHub(t, p, s.eventType, s.fixture)
+		if s.wantOutcome == "" {
+			require.Nil(t, res, s.fixture)
+		} else {
+			require.Equal(t, s.wantOutcome, res.Outcome, s.fixture)
+			require.Equal(t, s.wantReason, res.Reason, s.fixture)
+			require.Equal(t, fixturePRID, res.PullRequestID, s.fixture)
+		}
+		require.Equal(t, s.wantStatus, prs.prs[fixturePRID].Status, s.fixture)
+	}
+
+	require.Equal(t, fakeTracker{fixturePRID: "github octo-org/assigner#42"}, tracker)
+
+	pr := prs.prs[fixturePRID]
+	require.Equal(t, "u1", pr.AuthorID)
+	require.Equal(t, "Add retry budget and jitter to reviewer sync", pr.PullRequestName)
+	// метки приходят целиком в каждом событии, последнее обновление - edited без security
+	require.Equal(t, []string{"backend"}, pr.Labels)
+	require.Equal(t, []string{
+		"create " + fixturePRID,
+		"create " + fixturePRID,
+		"ready " + fixturePRID,
+		"update " + fixturePRID,
+		"update " + fixturePRID,
+		"close " + fixturePRID,
+		"reopen " + fixturePRID,
+		"merge " + fixturePRID,
+		"merge " + fixturePRID,
+		"update " + fixturePRID,
+	}, prs.calls)
+}
+
+func TestGitHub_ReplayUnknownPR(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixture     string
+		identities  fakeIdentities
+		wantOutcome string
+		wantReason  string
+		wantStatus  string
+	}{
+		{
+			name:        "unknown author",
+			fixture:     "pull_request.opened.json",
+			identities:  fakeIdentities{},
+			wantOutcome: webhook.OutcomeSkipped,
+			wantReason:  webhook.ReasonUnknownAuthor,
+		},
+		{
+			name:        "merge of PR opened before the hook",
+			fixture:     "pull_request.closed_merged.json",
+			identities:  fakeIdentities{"github/octocat-dev": "u1"},
+			wantOutcome: webhook.OutcomeSkipped,
+			wantReason:  webhook.ReasonPRNotFound,
+		},
+		{
+			name:        "reopen of PR opened before the hook",
+			fixture:     "pull_request.reopened.json",
+			identities:  fakeIdentities{"github/octocat-dev": "u1"},
+			wantOutcome: webhook.OutcomeApplied,
+			wantStatus:  pullrequest.StatusOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prs := newFakePRs()
+			p := webhook.NewProcessor(zap.NewNop().Sugar(), prs, tt.identities, nil)
+
+			res := replayGitHub(t, p, "pull_request", tt.fixture)
+			require.Equal(t, tt.wantOutcome, res.Outcome)
+			require.Equal(t, tt.wantReason, res.Reason)
+			if tt.wantStatus == "" {
+				require.Nil(t, res.PR)
+				require.Empty(t, prs.prs)
+			} else {
+				require.Equal(t, tt.wantStatus, res.PR.Status)
+			}
+		})
+	}
+}
+
+// логин уволенного еще может остаться привязанным: PR от его имени все равно не заводится
+func TestGitHub_OffboardedAuthor(t *testing.T) {
+	prs := newFakePRs()
+	prs.offboarded = map[string]bool{"u1": true}
+	p := webhook.NewProcessor(zap.NewNop().Sugar(), prs, fakeIdentities{"github/octocat-dev": "u1"}, nil)
+
+	res := replayGitHub(t, p, "pull_request", "pull_request.opened.json")
+	require.Equal(t, webhook.OutcomeSkipped, res.Outcome)
+	require.Equal(t, webhook.ReasonAuthorInactive, res.Reason)
+	require.Empty(t, prs.prs)
+}
+
+// PR заведен черновиком, а на хосте его переоткрыли уже готовым: открывается через Ready
+func TestGitHub_ReopenDraft(t *testing.T) {
+	prs := newFakePRs()
+	prs.prs[fixturePRID] = &pullrequest.PullRequest{PullRequestID: fixturePRID, Status: pullrequest.StatusDraft}
+	p := webhook.NewProcessor(zap.NewNop().Sugar(), prs, fakeIdentities{}, nil)
+
+	res := replayGitHub(t, p, "pull_request", "pull_request.reopened.json")
+	require.Equal(t, webhook.OutcomeApplied, res.Outcome)
+	require.Equal(t, pullrequest.StatusOpen, res.PR.Status)
+	require.Equal(t, []string{"reopen " + fixturePRID, "ready " + fixturePRID}, prs.calls)
+}
+
+func TestVerifyGitHubSignature(t *testing.T) {
+	// пример из документации GitHub
+	secret := []byte("It's a Secret to Everybody")
+	body := []byte("Hello, World!")
+	valid := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+
+	require.NoError(t, webhook.VerifyGitHubSignature(secret, body, valid))
+
+	for name, header := range map[string]string{
+		"tampered":   "sha256=857107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		"sha1":       "sha1=01dc10d0c83e72ed246219cdd91669667fe2ca59",
+		"not hex":    "sha256=zz",
+		"no header":  "",
+		"other body": sign(body),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.ErrorIs(t, webhook.VerifyGitHubSignature(secret, body, header), webhook.ErrBadSignature)
+		})
+	}
+
+	require.ErrorIs(t, webhook.VerifyGitHubSignature(nil, body, valid), webhook.ErrBadSignature)
+}
+
+func TestParseGitHub_BadPayload(t *testing.T) {
+	_, err := webhook.ParseGitHub("pull_request", []byte(`{"action":"opened"}`))
+	require.ErrorIs(t, err, webhook.ErrBadPayload)
+
+	_, err = webhook.ParseGitHub("pull_request", []byte(`not json`))
+	require.ErrorIs(t, err, webhook.ErrBadPayload)
+}
@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+const (
+	GitLabTokenHeader    = "X-Gitlab-Token"
+	GitLabEventHeader    = "X-Gitlab-Event"
+	GitLabDeliveryHeader = "X-Gitlab-Event-UUID"
+
+	gitlabEventMergeRequest = "Merge Request Hook"
+)
+
+// VerifyGitLabToken - GitLab не подписывает тело, а присылает секрет как есть
+func VerifyGitLabToken(token []byte, header string) error {
+	if len(token) == 0 || subtle.ConstantTimeCompare(token, []byte(header)) != 1 {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+type gitlabLabel struct {
+	Title string `json:"title"`
+}
+
+type gitlabMREvent struct {
+	ObjectKind string `json:"object_kind"`
+	Project    struct {
+		ID int64 `json:"id"`
+	} `json:"project"`
+	// User - кто совершил действие, не обязательно автор: от автора MR есть только author_id
+	User struct {
+		ID       int64  `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	ObjectAttributes *struct {
+		ID       int64  `json:"id"`
+		IID      int64  `json:"iid"`
+		AuthorID int64  `json:"author_id"`
+		Title    string `json:"title"`
+		Action   string `json:"action"`
+		Draft    bool   `json:"draft"`
+		// WorkInProgress - до GitLab 15 вместо draft
+		WorkInProgress bool          `json:"work_in_progress"`
+		Labels         []gitlabLabel `json:"labels"`
+	} `json:"object_attributes"`
+	Changes map[string]json.RawMessage `json:"changes"`
+}
+
+// ParseGitLab - события кроме Merge Request Hook и действия, которые нас не интересуют
+// (approved, push в ветку MR и т.д.), дают nil без ошибки
+func ParseGitLab(eventType string, body []byte) (*Event, error) {
+	if eventType != gitlabEventMergeRequest {
+		return nil, nil
+	}
+
+	var payload gitlabMREvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadPayload, err)
+	}
+	if payload.ObjectKind != "merge_request" || payload.ObjectAttributes == nil || payload.ObjectAttributes.ID == 0 {
+		return nil, fmt.Errorf("%w: no object_attributes", ErrBadPayload)
+	}
+	mr := payload.ObjectAttributes
+	draft := mr.Draft || mr.WorkInProgress
+
+	var action string
+	switch mr.Action {
+	case "open":
+		action = ActionOpen
+	case "reopen":
+		action = ActionReopen
+	case "merge":
+		action = ActionMerge
+	case "close":
+		action = ActionClose
+	case "update":
+		action = gitlabUpdateAction(payload.Changes, draft)
+	}
+	if action == "" {
+		return nil, nil
+	}
+
+	labels := make([]string, 0, len(mr.Labels))
+	for _, l := range mr.Labels {
+		labels = append(labels, l.Title)
+	}
+
+	// логин берем, только если действие совершил сам автор, иначе автор ищется по author_id
+	var authorLogin string
+	if payload.User.ID == mr.AuthorID {
+		authorLogin = payload.User.Username
+	}
+
+	return &Event{
+		Provider:         ProviderGitLab,
+		Action:           action,
+		ExternalID:       mr.ID,
+		Title:            truncateTitle(mr.Title),
+		AuthorLogin:      authorLogin,
+		AuthorExternalID: mr.AuthorID,
+		Draft:            draft,
+		Labels:           labels,
+		Repo:             strconv.FormatInt(payload.Project.ID, 10),
+		Number:           mr.IID,
+	}, nil
+}
+
+// gitlabUpdateAction - update приходит и на push в ветку, смотрим, что поменялось.
+// Снятие draft у GitLab тоже update, его сводим к ready
+func gitlabUpdateAction(changes map[string]json.RawMessage, draft bool) string {
+	_, draftChanged := changes["draft"]
+	_, wipChanged := changes["work_in_progress"]
+	if (draftChanged || wipChanged) && !draft {
+		return ActionReady
+	}
+
+	_, titleChanged := changes["title"]
+	_, labelsChanged := changes["labels"]
+	if titleChanged || labelsChanged {
+		return ActionUpdate
+	}
+	return ""
+}
@@ -0,0 +1,265 @@
+package webhook_test
+
+import (
+	"assignerPR/internal/pullrequest"
+	"assignerPR/internal/webhook"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testGitLabToken    = "gitlab-test-token"
+	gitlabMR           = "Merge Request Hook"
+	fixtureGitLabPRID  = "gitlab-98213"
+	fixtureGitLabTitle = "Retry invoice export on S3 and GCS errors"
+)
+
+func readGitLab(t *testing.T, fixture string) []byte {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", "gitlab", fixture))
+	require.NoError(t, err)
+	return body
+}
+
+// replayGitLab - доставка записанного payload так же, как ее разбирает хендлер: токен, тип события, обработка
+func replayGitLab(t *testing.T, p *webhook.Processor, eventType, fixture string) *webhook.Result {
+	t.Helper()
+
+	require.NoError(t, webhook.VerifyGitLabToken([]byte(testGitLabToken), testGitLabToken))
+
+	ev, err := webhook.ParseGitLab(eventType, readGitLab(t, fixture))
+	require.NoError(t, err)
+	if ev == nil {
+		return nil
+	}
+
+	res, err := p.Apply(ev)
+	require.NoError(t, err)
+	return res
+}
+
+func TestParseGitLab(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		eventType  string
+		wantAction string
+		wantDraft  bool
+		wantTitle  string
+		wantLabels []string
+		// wantLogin - логин есть, только когда действие совершил сам автор (Jane.Ops, id 31)
+		wantLogin string
+	}{
+		{"merge_request.open.json", gitlabMR, webhook.ActionOpen, false, "Retry invoice export on S3 errors", []string{"backend"}, "Jane.Ops"},
+		{"merge_request.open_draft.json", gitlabMR, webhook.ActionOpen, true, "Draft: Retry invoice export on S3 errors", []string{"backend"}, "Jane.Ops"},
+		{"merge_request.update_ready.json", gitlabMR, webhook.ActionReady, false, "Retry invoice export on S3 errors", []string{"backend"}, "Jane.Ops"},
+		{"merge_request.update_labels.json", gitlabMR, webhook.ActionUpdate, false, "Retry invoice export on S3 errors", []string{"backend", "security"}, ""},
+		{"merge_request.update_title.json", gitlabMR, webhook.ActionUpdate, false, fixtureGitLabTitle, []string{"backend", "security"}, "Jane.Ops"},
+		{"merge_request.close.json", gitlabMR, webhook.ActionClose, false, fixtureGitLabTitle, []string{"backend", "security"}, ""},
+		{"merge_request.reopen.json", gitlabMR, webhook.ActionReopen, false, fixtureGitLabTitle, []string{"backend", "security"}, "Jane.Ops"},
+		{"merge_request.merge.json", gitlabMR, webhook.ActionMerge, false, fixtureGitLabTitle, []string{"backend", "security"}, ""},
+		// push в ветку MR, approve и комментарии на PR не влияют
+		{"merge_request.update_push.json", gitlabMR, "", false, "", nil, ""},
+		{"merge_request.approved.json", gitlabMR, "", false, "", nil, ""},
+		{"note.json", "Note Hook", "", false, "", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			ev, err := webhook.ParseGitLab(tt.eventType, readGitLab(t, tt.fixture))
+			require.NoError(t, err)
+
+			if tt.wantAction == "" {
+				require.Nil(t, ev)
+				return
+			}
+			require.Equal(t, webhook.ProviderGitLab, ev.Provider)
+			require.Equal(t, tt.wantAction, ev.Action)
+			require.Equal(t, fixtureGitLabPRID, ev.PullRequestID())
+			require.Equal(t, tt.wantDraft, ev.Draft)
+			require.Equal(t, tt.wantTitle, ev.Title)
+			require.Equal(t, tt.wantLabels, ev.Labels)
+			// автор - по author_id, а не user (тот, кто совершил действие)
+			require.Equal(t, tt.wantLogin, ev.AuthorLogin)
+			require.Equal(t, int64(31), ev.AuthorExternalID)
+		})
+	}
+}
+
+func TestGitLab_ReplayLifecycle(t *testing.T) {
+	prs := newFakePRs()
+	tracker := fakeTracker{}
+	// автор MR в фикстурах Jane.Ops с id 31, petr.lead (id 7) только совершает действия
+	p := webhook.NewProcessor(zap.NewNop().Sugar(), prs, fakeIdentities{"gitlab/jane.ops": "u2", "gitlab/31": "u2"}, tracker)
+
+	steps := []struct {
+		eventType   string
+		fixture     string
+		wantOutcome string
+		wantReason  string
+		wantStatus  string
+	}{
+		{gitlabMR, "merge_request.open_draft.json", webhook.OutcomeApplied, "", pullrequest.StatusDraft},
+		// повторная доставка того же события
+		{gitlabMR, "merge_request.open_draft.json", webhook.OutcomeSkipped, webhook.ReasonPRExists, pullrequest.StatusDraft},
+		{gitlabMR, "merge_request.update_ready.json", webhook.OutcomeApplied, "", pullrequest.StatusOpen},
+		{gitlabMR, "merge_request.update_labels.json", webhook.OutcomeApplied, "", pullrequest.StatusOpen},
+		{gitlabMR, "merge_request.update_push.json", "", "", pullrequest.StatusOpen},
+		{gitlabMR, "merge_request.update_title.json", webhook.OutcomeApplied, "", pullrequest.StatusOpen},
+		{gitlabMR, "merge_request.approved.json", "", "", pullrequest.StatusOpen},
+		{"Note Hook", "note.json", "", "", pullrequest.StatusOpen},
+		{gitlabMR, "merge_request.close.json", webhook.OutcomeApplied, "", pullrequest.StatusClosed},
+		{gitlabMR, "merge_request.reopen.json", webhook.OutcomeApplied, "", pullrequest.StatusOpen},
+		{gitlabMR, "merge_request.merge.json", webhook.OutcomeApplied, "", pullrequest.StatusMerged},
+		{gitlabMR, "merge_request.merge.json", webhook.OutcomeApplied, "", pullrequest.StatusMerged},
+	}
+
+	for _, s := range steps {
+		res := replayGitLab(t, p, s.eventType, s.fixture)
+		if s.wantOutcome == "" {
+			require.Nil(t, res, s.fixture)
+		} else {
+			require.Equal(t, s.wantOutcome, res.Outcome, s.fixture)
+			require.Equal(t, s.wantReason, res.Reason, s.fixture)
+			require.Equal(t, fixtureGitLabPRID, res.PullRequestID, s.fixture)
+		}
+		require.Equal(t, s.wantStatus, prs.prs[fixtureGitLabPRID].Status, s.fixture)
+	}
+
+	require.Equal(t, fakeTracker{fixtureGitLabPRID: "gitlab 14#57"}, tracker)
+
+	pr := prs.prs[fixtureGitLabPRID]
+	require.Equal(t, "u2", pr.AuthorID)
+	require.Equal(t, fixtureGitLabTitle, pr.PullRequestName)
+	require.Equal(t, []string{"backend", "security"}, pr.Labels)
+	require.Equal(t, []string{
+		"create " + fixtureGitLabPRID,
+		"create " + fixtureGitLabPRID,
+		// ready + название без префикса Draft:
+		"ready " + fixtureGitLabPRID,
+		"update " + fixtureGitLabPRID,
+		"update " + fixtureGitLabPRID,
+		"update " + fixtureGitLabPRID,
+		"close " + fixtureGitLabPRID,
+		"reopen " + fixtureGitLabPRID,
+		"merge " + fixtureGitLabPRID,
+		"merge " + fixtureGitLabPRID,
+	}, prs.calls)
+}
+
+func TestGitLab_ReplayUnknownPR(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixture     string
+		identities  fakeIdentities
+		wantOutcome string
+		wantReason  string
+		wantStatus  string
+		wantAuthor  string
+	}{
+		{
+			name:        "unknown author",
+			fixture:     "merge_request.open.json",
+			identities:  fakeIdentities{"github/jane.ops": "u2", "github/31": "u2"},
+			wantOutcome: webhook.OutcomeSkipped,
+			wantReason:  webhook.ReasonUnknownAuthor,
+		},
+		{
+			// MR открыл сам автор, хватает привязки логина
+			name:        "opened by author",
+			fixture:     "merge_request.open.json",
+			identities:  fakeIdentities{"gitlab/jane.ops": "u2"},
+			wantOutcome: webhook.OutcomeApplied,
+			wantStatus:  pullrequest.StatusOpen,
+			wantAuthor:  "u2",
+		},
+		{
+			// логин привязан без id: по логину не нашли, ищем по author_id
+			name:        "login not linked, id linked",
+			fixture:     "merge_request.open.json",
+			identities:  fakeIdentities{"gitlab/31": "u2"},
+			wantOutcome: webhook.OutcomeApplied,
+			wantStatus:  pullrequest.StatusOpen,
+			wantAuthor:  "u2",
+		},
+		{
+			// логин того, кто переоткрыл MR, автором не считается, даже если он привязан
+			name:        "reopened by maintainer, author id not linked",
+			fixture:     "merge_request.reopen_by_maintainer.json",
+			identities:  fakeIdentities{"gitlab/jane.ops": "u2", "gitlab/petr.lead": "u7", "gitlab/7": "u7"},
+			wantOutcome: webhook.OutcomeSkipped,
+			wantReason:  webhook.ReasonUnknownAuthor,
+		},
+		{
+			name:        "reopened by maintainer",
+			fixture:     "merge_request.reopen_by_maintainer.json",
+			identities:  fakeIdentities{"gitlab/petr.lead": "u7", "gitlab/7": "u7", "gitlab/31": "u2"},
+			wantOutcome: webhook.OutcomeApplied,
+			wantStatus:  pullrequest.StatusOpen,
+			wantAuthor:  "u2",
+		},
+		{
+			name:        "merge of MR opened before the hook",
+			fixture:     "merge_request.merge.json",
+			identities:  fakeIdentities{"gitlab/31": "u2"},
+			wantOutcome: webhook.OutcomeSkipped,
+			wantReason:  webhook.ReasonPRNotFound,
+		},
+		{
+			name:        "reopen of MR opened before the hook",
+			fixture:     "merge_request.reopen.json",
+			identities:  fakeIdentities{"gitlab/jane.ops": "u2"},
+			wantOutcome: webhook.OutcomeApplied,
+			wantStatus:  pullrequest.StatusOpen,
+			wantAuthor:  "u2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prs := newFakePRs()
+			p := webhook.NewProcessor(zap.NewNop().Sugar(), prs, tt.identities, nil)
+
+			res := replayGitLab(t, p, gitlabMR, tt.fixture)
+			require.Equal(t, tt.wantOutcome, res.Outcome)
+			require.Equal(t, tt.wantReason, res.Reason)
+			if tt.wantStatus == "" {
+				require.Nil(t, res.PR)
+				require.Empty(t, prs.prs)
+			} else {
+				require.Equal(t, tt.wantStatus, res.PR.Status)
+				require.Equal(t, tt.wantAuthor, res.PR.AuthorID)
+			}
+		})
+	}
+}
+
+func TestVerifyGitLabToken(t *testing.T) {
+	token := []byte(testGitLabToken)
+
+	require.NoError(t, webhook.VerifyGitLabToken(token, testGitLabToken))
+
+	for name, header := range map[string]string{
+		"wrong":     "gitlab-test-tokem",
+		"prefix":    "gitlab-test",
+		"no header": "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.ErrorIs(t, webhook.VerifyGitLabToken(token, header), webhook.ErrBadSignature)
+		})
+	}
+
+	require.ErrorIs(t, webhook.VerifyGitLabToken(nil, ""), webhook.ErrBadSignature)
+}
+
+func TestParseGitLab_BadPayload(t *testing.T) {
+	_, err := webhook.ParseGitLab(gitlabMR, []byte(`{"object_kind":"merge_request"}`))
+	require.ErrorIs(t, err, webhook.ErrBadPayload)
+
+	_, err = webhook.ParseGitLab(gitlabMR, []byte(`not json`))
+	require.ErrorIs(t, err, webhook.ErrBadPayload)
+}
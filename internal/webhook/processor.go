@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"assignerPR/internal/metrics"
+	"assignerPR/internal/pullrequest"
+	"assignerPR/pkg/user"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// IdentityResolver - логин или числовой id у провайдера -> user_id, реализуется UsersRepo
+type IdentityResolver interface {
+	ResolveIdentity(provider, login string) (string, error)
+	ResolveExternalID(provider string, externalID int64) (string, error)
+}
+
+// HostTracker - запоминает, где PR живет у провайдера, чтобы отправлять туда назначенных ревьюверов
+type HostTracker interface {
+	TrackHostPR(prID, provider, repo string, number int64) error
+}
+
+// Result - что сделано по событию. PR пустой, если событие пропущено
+type Result struct {
+	Action        string
+	PullRequestID string
+	Outcome       string
+	Reason        string
+	PR            *pullrequest.PullRequest
+}
+
+type Processor struct {
+	logger     *zap.SugaredLogger
+	prRepo     pullrequest.PullRequestsRepo
+	identities IdentityResolver
+	tracker    HostTracker
+}
+
+// NewProcessor - tracker может быть nil, тогда ревьюверы на провайдер не отправляются
+func NewProcessor(
+	logger *zap.SugaredLogger,
+	prRepo pullrequest.PullRequestsRepo,
+	identities IdentityResolver,
+	tracker HostTracker,
+) *Processor {
+	return &Processor{
+		logger:     logger,
+		prRepo:     prRepo,
+		identities: identities,
+		tracker:    tracker,
+	}
+}
+
+// Apply переводит событие в вызовы PullRequestsRepo. Провайдеры повторяют доставки, поэтому повтор
+// уже примененного события - не ошибка: дубликат открытия пропускается, а Merge/Close/Ready/Reopen сами идемпотентны.
+// PR, открытые до подключения хука, заводятся при первом reopen/ready_for_review
+func (p *Processor) Apply(ev *Event) (*Result, error) {
+	prID := ev.PullRequestID()
+	p.logger.Debugw("Apply()", "provider", ev.Provider, "action", ev.Action, "prID", prID)
+
+	res := &Result{Action: ev.Action, PullRequestID: prID, Outcome: OutcomeApplied}
+
+	var (
+		pr  *pullrequest.PullRequest
+		err error
+	)
+	switch ev.Action {
+	case ActionOpen:
+		pr, err = p.create(ev, ev.Draft)
+	case ActionReopen:
+		pr, err = p.prRepo.Reopen(prID)
+		switch {
+		case errors.Is(err, pullrequest.ErrPRNotFound):
+			pr, err = p.create(ev, ev.Draft)
+		// у нас PR черновик: Reopen его не открывает, это делает Ready, если на хосте он уже не черновик
+		case errors.Is(err, pullrequest.ErrPRDraft) && ev.Draft:
+			pr, err = p.prRepo.GetPR(prID)
+		case errors.Is(err, pullrequest.ErrPRDraft):
+			pr, err = p.prRepo.Ready(prID)
+		}
+	case ActionReady:
+		pr, err = p.prRepo.Ready(prID)
+		if errors.Is(err, pullrequest.ErrPRNotFound) {
+			pr, err = p.create(ev, false)
+		}
+		// у GitLab снятие draft - это еще и смена названия (убирается префикс Draft:)
+		if err == nil && pr.PullRequestName != ev.Title {
+			pr, err = p.prRepo.Update(prID, pullrequest.PRUpdate{Name: &ev.Title, Labels: &ev.Labels})
+		}
+	case ActionMerge:
+		pr, err = p.prRepo.Merge(prID)
+	case ActionClose:
+		pr, err = p.prRepo.Close(prID)
+	case ActionUpdate:
+		pr, err = p.prRepo.Update(prID, pullrequest.PRUpdate{Name: &ev.Title, Labels: &ev.Labels})
+	default:
+		res.Outcome = OutcomeIgnored
+		metrics.ObserveWebhook(ev.Provider, ev.Action, res.Outcome)
+		return res, nil
+	}
+
+	switch {
+	case errors.Is(err, user.ErrIdentityNotFound):
+		res.Outcome, res.Reason = OutcomeSkipped, ReasonUnknownAuthor
+	case errors.Is(err, pullrequest.ErrPRExists):
+		res.Outcome, res.Reason = OutcomeSkipped, ReasonPRExists
+	case errors.Is(err, pullrequest.ErrPRNotFound):
+		res.Outcome, res.Reason = OutcomeSkipped, ReasonPRNotFound
+	case errors.Is(err, pullrequest.ErrPRMerged):
+		res.Outcome, res.Reason = OutcomeSkipped, ReasonPRMerged
+	case errors.Is(err, pullrequest.ErrAuthorInactive):
+		res.Outcome, res.Reason = OutcomeSkipped, ReasonAuthorInactive
+	case err != nil:
+		metrics.ObserveWebhook(ev.Provider, ev.Action, "error")
+		p.logger.Warnw("error applying webhook event", "provider", ev.Provider, "action", ev.Action,
+			"prID", prID, "err", err)
+		return nil, err
+	default:
+		res.PR = pr
+		p.track(ev, pr)
+	}
+
+	if res.Outcome == OutcomeSkipped {
+		p.logger.Infow("webhook event skipped", "provider", ev.Provider, "action", ev.Action,
+			"prID", prID, "reason", res.Reason, "login", ev.AuthorLogin, "authorID", ev.AuthorExternalID)
+	}
+	metrics.ObserveWebhook(ev.Provider, ev.Action, res.Outcome)
+	return res, nil
+}
+
+func (p *Processor) create(ev *Event, draft bool) (*pullrequest.PullRequest, error) {
+	authorID, err := p.resolveAuthor(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.prRepo.CreatePR(ev.PullRequestID(), ev.Title, authorID, pullrequest.CreatePROptions{
+		Labels:  ev.Labels,
+		IsDraft: draft,
+	})
+}
+
+// resolveAuthor - по логину, а если логина нет или он не привязан, то по id автора у провайдера
+// (у GitLab логин есть, только когда действие совершил сам автор)
+func (p *Processor) resolveAuthor(ev *Event) (string, error) {
+	if ev.AuthorLogin != "" {
+		authorID, err := p.identities.ResolveIdentity(ev.Provider, ev.AuthorLogin)
+		if !errors.Is(err, user.ErrIdentityNotFound) || ev.AuthorExternalID == 0 {
+			return authorID, err
+		}
+	}
+	if ev.AuthorExternalID == 0 {
+		return "", user.ErrIdentityNotFound
+	}
+
+	return p.identities.ResolveExternalID(ev.Provider, ev.AuthorExternalID)
+}
+
+// track - привязка сохраняется на каждом событии, а не только при создании: так ее получают и PR,
+// заведенные до подключения синхронизации. Ошибка не валит событие, PR у нас уже обновлен
+func (p *Processor) track(ev *Event, pr *pullrequest.PullRequest) {
+	if p.tracker == nil || ev.Repo == "" || ev.Number == 0 {
+		return
+	}
+	if pr.Status != pullrequest.StatusOpen && pr.Status != pullrequest.StatusDraft {
+		return
+	}
+
+	if err := p.tracker.TrackHostPR(pr.PullRequestID, ev.Provider, ev.Repo, ev.Number); err != nil {
+		p.logger.Warnw("error tracking host PR", "provider", ev.Provider, "prID", pr.PullRequestID, "err", err)
+	}
+}
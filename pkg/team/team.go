@@ -7,18 +7,93 @@ import (
 )
 
 var (
-	ErrTeamExists = errors.New("TEAM_EXISTS")
+	ErrTeamExists        = errors.New("TEAM_EXISTS")
+	ErrTeamNotFound      = errors.New("TEAM_NOT_FOUND")
+	ErrTeamNotEmpty      = errors.New("TEAM_NOT_EMPTY")
+	ErrMemberHasPRs      = errors.New("TEAM_MEMBER_HAS_PRS")
+	ErrMemberHasReviews  = errors.New("TEAM_MEMBER_HAS_REVIEWS")
+	ErrMemberOfOtherTeam = errors.New("TEAM_MEMBER_OF_OTHER_TEAM")
 )
 
 type Team struct {
-	TeamName  string `gorm:"primaryKey;type:varchar(64);column:team_name"`
+	TeamName          string `gorm:"primaryKey;type:varchar(64);column:team_name"`
+	SelectionStrategy string `gorm:"type:varchar(32);not null;default:LEAST_LOADED;column:selection_strategy"`
+	ReviewersRequired int    `gorm:"not null;default:2;column:reviewers_required"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+
+	// MaxOpenReviews - лимит открытых ревью на человека по умолчанию для команды, 0 - без лимита
+	MaxOpenReviews int `gorm:"not null;default:0;column:max_open_reviews"`
+	// RequiredApprovals - сколько одобрений нужно для мержа PR авторов команды, 0 - мерж без проверки
+	RequiredApprovals int `gorm:"not null;default:0;column:required_approvals"`
+
+	Members   []*user.User `gorm:"foreignKey:TeamName;references:TeamName;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	Fallbacks []*Fallback  `gorm:"foreignKey:TeamName;references:TeamName;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// Fallback - запасная команда, из которой добираются ревьюверы, если своих не хватило. Обходятся по Position
+type Fallback struct {
+	TeamName         string `gorm:"primaryKey;type:varchar(64);column:team_name"`
+	FallbackTeamName string `gorm:"primaryKey;type:varchar(64);column:fallback_team_name"`
+	Position         int    `gorm:"not null;column:position"`
+}
+
+func (Fallback) TableName() string {
+	return "team_fallbacks"
+}
+
+// PathRule - строка правил владения в духе CODEOWNERS: шаблон пути и один из его владельцев.
+// Position - номер строки, при совпадении нескольких строк действует последняя
+type PathRule struct {
+	ID        uint   `gorm:"primaryKey;column:id"`
+	TeamName  string `gorm:"type:varchar(64);index;not null;column:team_name"`
+	Position  int    `gorm:"not null;column:position"`
+	Pattern   string `gorm:"type:varchar(255);not null;column:pattern"`
+	UserID    string `gorm:"type:varchar(64);not null;column:user_id"`
 	CreatedAt time.Time
-	UpdatedAt time.Time
+}
+
+func (PathRule) TableName() string {
+	return "team_path_rules"
+}
+
+// Settings - частичное обновление настроек команды, nil поля не трогаются
+type Settings struct {
+	SelectionStrategy *string
+	ReviewersRequired *int
+	MaxOpenReviews    *int
+	RequiredApprovals *int
+	FallbackTeams     *[]string
+}
+
+// MovedMember - пользователь, которого синхронизация забрала из другой команды
+type MovedMember struct {
+	UserID   string
+	FromTeam string
+}
 
-	Members []*user.User `gorm:"foreignKey:TeamName;references:TeamName;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+// SyncResult - что изменила синхронизация состава. Deactivated - все, кто был активен и перестал:
+// и пропавшие из списка, и пришедшие с is_active=false
+type SyncResult struct {
+	Team        *Team
+	Created     bool
+	Added       []string
+	Updated     []string
+	Moved       []MovedMember
+	Deactivated []string
 }
 
 type TeamsRepo interface {
 	CreateTeam(teamName string, members []*user.User) (*Team, error)
 	GetTeam(teamName string) (*Team, error)
+	ListTeams() ([]*Team, error)
+	UpdateSettings(teamName string, settings Settings) (*Team, error)
+	SetPathRules(teamName string, rules []*PathRule) ([]*PathRule, error)
+	GetPathRules(teamName string) ([]*PathRule, error)
+	AddMembers(teamName string, members []*user.User) (*Team, error)
+	RemoveMember(teamName, userID string) error
+	MoveMember(userID, toTeam string) (member *user.User, fromTeam string, err error)
+	RenameTeam(teamName, newName string) (*Team, error)
+	DeleteTeam(teamName string) error
+	SyncTeam(teamName string, members []*user.User) (*SyncResult, error)
 }
@@ -42,6 +42,10 @@ func (repo *TeamsRepoPg) CreateTeam(teamName string, members []*user.User) (*Tea
 		}
 
 		if len(members) > 0 {
+			if err := repo.rejectOffboardedInTx(tx, members); err != nil {
+				return err
+			}
+
 			usersCopy := make([]*user.User, len(members))
 			for i, m := range members {
 				copyU := *m
@@ -85,3 +89,590 @@ func (repo *TeamsRepoPg) GetTeam(teamName string) (*Team, error) {
 	repo.logger.Debugw("Team found", "teamName", teamName)
 	return &team, nil
 }
+
+// ListTeams - все команды с участниками, в стабильном порядке для выгрузки
+func (repo *TeamsRepoPg) ListTeams() ([]*Team, error) {
+	repo.logger.Debugw("ListTeams()")
+
+	var teams []*Team
+	if err := repo.db.
+		Preload("Members", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("users.user_id ASC")
+		}).
+		Order("team_name ASC").
+		Find(&teams).Error; err != nil {
+		repo.logger.Errorw("failed to list teams", "err", err)
+		return nil, err
+	}
+
+	repo.logger.Debugw("teams listed", "count", len(teams))
+	return teams, nil
+}
+
+func (repo *TeamsRepoPg) UpdateSettings(teamName string, settings Settings) (*Team, error) {
+	repo.logger.Debugw("UpdateSettings()", "teamName", teamName)
+
+	updates := make(map[string]interface{})
+	if settings.SelectionStrategy != nil {
+		updates["selection_strategy"] = *settings.SelectionStrategy
+	}
+	if settings.ReviewersRequired != nil {
+		updates["reviewers_required"] = *settings.ReviewersRequired
+	}
+	if settings.MaxOpenReviews != nil {
+		updates["max_open_reviews"] = *settings.MaxOpenReviews
+	}
+	if settings.RequiredApprovals != nil {
+		updates["required_approvals"] = *settings.RequiredApprovals
+	}
+
+	var team Team
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&team, "team_name = ?", teamName).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				repo.logger.Warnw("team does not exist", "teamName", teamName)
+				return ErrTeamNotFound
+			}
+			return err
+		}
+
+		if len(updates) > 0 {
+			if err := tx.Model(&team).Updates(updates).Error; err != nil {
+				repo.logger.Errorw("error updating team settings", "teamName", teamName, "err", err)
+				return err
+			}
+		}
+
+		if settings.FallbackTeams != nil {
+			if err := repo.replaceFallbacksInTx(tx, teamName, *settings.FallbackTeams); err != nil {
+				return err
+			}
+		}
+
+		return tx.Preload("Fallbacks", func(tx2 *gorm.DB) *gorm.DB {
+			return tx2.Order("team_fallbacks.position ASC")
+		}).First(&team, "team_name = ?", teamName).Error
+	})
+
+	if err != nil {
+		repo.logger.Errorw("failed to update team settings", "teamName", teamName, "err", err)
+		return nil, err
+	}
+
+	repo.logger.Debugw("team settings updated", "teamName", teamName)
+	return &team, nil
+}
+
+func (repo *TeamsRepoPg) replaceFallbacksInTx(tx *gorm.DB, teamName string, fallbackTeams []string) error {
+	if len(fallbackTeams) > 0 {
+		var count int64
+		if err := tx.Model(&Team{}).Where("team_name IN ?", fallbackTeams).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(fallbackTeams) {
+			repo.logger.Warnw("fallback team does not exist", "teamName", teamName, "fallbacks", fallbackTeams)
+			return ErrTeamNotFound
+		}
+	}
+
+	if err := tx.Where("team_name = ?", teamName).Delete(&Fallback{}).Error; err != nil {
+		repo.logger.Errorw("error deleting fallback teams", "teamName", teamName, "err", err)
+		return err
+	}
+
+	if len(fallbackTeams) == 0 {
+		return nil
+	}
+
+	fallbacks := make([]*Fallback, 0, len(fallbackTeams))
+	for i, name := range fallbackTeams {
+		fallbacks = append(fallbacks, &Fallback{
+			TeamName:         teamName,
+			FallbackTeamName: name,
+			Position:         i,
+		})
+	}
+
+	if err := tx.Create(&fallbacks).Error; err != nil {
+		repo.logger.Errorw("error creating fallback teams", "teamName", teamName, "err", err)
+		return err
+	}
+
+	return nil
+}
+
+// SetPathRules - правила заменяются целиком, как при перезаписи файла CODEOWNERS.
+// Владельцами могут быть только члены команды
+func (repo *TeamsRepoPg) SetPathRules(teamName string, rules []*PathRule) ([]*PathRule, error) {
+	repo.logger.Debugw("SetPathRules()", "teamName", teamName, "rulesCount", len(rules))
+
+	var saved []*PathRule
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var t Team
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&t, "team_name = ?", teamName).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				repo.logger.Warnw("team does not exist", "teamName", teamName)
+				return ErrTeamNotFound
+			}
+			return err
+		}
+
+		ownerSet := make(map[string]struct{}, len(rules))
+		for _, r := range rules {
+			ownerSet[r.UserID] = struct{}{}
+		}
+		owners := make([]string, 0, len(ownerSet))
+		for id := range ownerSet {
+			owners = append(owners, id)
+		}
+
+		if len(owners) > 0 {
+			var members int64
+			if err := tx.Model(&user.User{}).
+				Where("team_name = ? AND user_id IN ?", teamName, owners).
+				Count(&members).Error; err != nil {
+				return err
+			}
+			if int(members) != len(owners) {
+				repo.logger.Warnw("path rule owner is not a team member", "teamName", teamName)
+				return user.ErrUserNotFound
+			}
+		}
+
+		if err := tx.Where("team_name = ?", teamName).Delete(&PathRule{}).Error; err != nil {
+			repo.logger.Errorw("error deleting path rules", "teamName", teamName, "err", err)
+			return err
+		}
+
+		saved = make([]*PathRule, 0, len(rules))
+		for _, r := range rules {
+			ruleCopy := *r
+			ruleCopy.ID = 0
+			ruleCopy.TeamName = teamName
+			saved = append(saved, &ruleCopy)
+		}
+
+		if len(saved) > 0 {
+			if err := tx.Create(&saved).Error; err != nil {
+				repo.logger.Errorw("error creating path rules", "teamName", teamName, "err", err)
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		repo.logger.Errorw("failed to set path rules", "teamName", teamName, "err", err)
+		return nil, err
+	}
+
+	repo.logger.Debugw("path rules set", "teamName", teamName, "rulesCount", len(saved))
+	return saved, nil
+}
+
+func (repo *TeamsRepoPg) GetPathRules(teamName string) ([]*PathRule, error) {
+	repo.logger.Debugw("GetPathRules()", "teamName", teamName)
+
+	var count int64
+	if err := repo.db.Model(&Team{}).Where("team_name = ?", teamName).Count(&count).Error; err != nil {
+		repo.logger.Errorw("failed to query team", "teamName", teamName, "err", err)
+		return nil, err
+	}
+	if count == 0 {
+		repo.logger.Warnw("team does not exist", "teamName", teamName)
+		return nil, ErrTeamNotFound
+	}
+
+	var rules []*PathRule
+	if err := repo.db.
+		Where("team_name = ?", teamName).
+		Order("position ASC").
+		Order("id ASC").
+		Find(&rules).Error; err != nil {
+		repo.logger.Errorw("failed to query path rules", "teamName", teamName, "err", err)
+		return nil, err
+	}
+
+	repo.logger.Debugw("path rules found", "teamName", teamName, "rulesCount", len(rules))
+	return rules, nil
+}
+
+// AddMembers - добавление в существующую команду. Пользователей из других команд так не перетащить,
+// для этого есть MoveMember, который заодно переназначает их ревью
+func (repo *TeamsRepoPg) AddMembers(teamName string, members []*user.User) (*Team, error) {
+	repo.logger.Debugw("AddMembers()", "teamName", teamName, "membersCount", len(members))
+
+	var team Team
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := repo.lockTeamInTx(tx, teamName, &team); err != nil {
+			return err
+		}
+
+		ids := make([]string, 0, len(members))
+		usersCopy := make([]*user.User, 0, len(members))
+		for _, m := range members {
+			copyU := *m
+			copyU.TeamName = teamName
+			ids = append(ids, m.UserID)
+			usersCopy = append(usersCopy, &copyU)
+		}
+
+		var others int64
+		if err := tx.Model(&user.User{}).
+			Where("user_id IN ? AND team_name <> ?", ids, teamName).
+			Count(&others).Error; err != nil {
+			return err
+		}
+		if others > 0 {
+			repo.logger.Warnw("some users belong to another team", "teamName", teamName, "count", others)
+			return ErrMemberOfOtherTeam
+		}
+		if err := repo.rejectOffboardedInTx(tx, members); err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"username", "is_active"}),
+		}).Create(&usersCopy).Error; err != nil {
+			repo.logger.Errorw("error adding members", "teamName", teamName, "err", err)
+			return err
+		}
+
+		return tx.Preload("Members").First(&team, "team_name = ?", teamName).Error
+	})
+
+	if err != nil {
+		repo.logger.Errorw("failed to add members", "teamName", teamName, "err", err)
+		return nil, err
+	}
+
+	repo.logger.Debugw("members added", "teamName", teamName, "membersCount", len(members))
+	return &team, nil
+}
+
+// RemoveMember удаляет пользователя совсем: вне команды пользователей не бывает (users.team_name NOT NULL).
+// Авторов PR и тех, у кого есть история ревью, удалить нельзя: каскад унес бы ее вместе с пользователем,
+// их можно только перевести или деактивировать. Открытые ревью надо снять до вызова, иначе они тоже считаются историей
+func (repo *TeamsRepoPg) RemoveMember(teamName, userID string) error {
+	repo.logger.Debugw("RemoveMember()", "teamName", teamName, "userID", userID)
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var member user.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&member, "user_id = ? AND team_name = ?", userID, teamName).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				repo.logger.Warnw("user is not a team member", "teamName", teamName, "userID", userID)
+				return user.ErrUserNotFound
+			}
+			return err
+		}
+
+		var authored int64
+		if err := tx.Table("pull_requests").Where("author_id = ?", userID).Count(&authored).Error; err != nil {
+			return err
+		}
+		if authored > 0 {
+			repo.logger.Warnw("member has authored PRs", "teamName", teamName, "userID", userID, "count", authored)
+			return ErrMemberHasPRs
+		}
+
+		// назначения на закрытые и смерженные PR и вердикты - история, ее не теряем
+		var reviewed int64
+		if err := tx.Table("pr_reviewers").Where("user_id = ?", userID).Count(&reviewed).Error; err != nil {
+			return err
+		}
+		if reviewed == 0 {
+			if err := tx.Table("pr_reviews").Where("user_id = ?", userID).Count(&reviewed).Error; err != nil {
+				return err
+			}
+		}
+		if reviewed > 0 {
+			repo.logger.Warnw("member has review history", "teamName", teamName, "userID", userID)
+			return ErrMemberHasReviews
+		}
+
+		return tx.Delete(&member).Error
+	})
+
+	if err != nil {
+		repo.logger.Errorw("failed to remove member", "teamName", teamName, "userID", userID, "err", err)
+		return err
+	}
+
+	repo.logger.Debugw("member removed", "teamName", teamName, "userID", userID)
+	return nil
+}
+
+// MoveMember переводит пользователя в другую команду и возвращает его прежнюю команду.
+// Правила владения путями старой команды на него снимаются, ревью переназначает вызывающий
+func (repo *TeamsRepoPg) MoveMember(userID, toTeam string) (*user.User, string, error) {
+	repo.logger.Debugw("MoveMember()", "userID", userID, "toTeam", toTeam)
+
+	var member user.User
+	var fromTeam string
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var target Team
+		if err := repo.lockTeamInTx(tx, toTeam, &target); err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&member, "user_id = ?", userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				repo.logger.Warnw("user does not exist", "userID", userID)
+				return user.ErrUserNotFound
+			}
+			return err
+		}
+
+		fromTeam = member.TeamName
+		if fromTeam == toTeam {
+			return nil
+		}
+
+		if err := tx.Where("team_name = ? AND user_id = ?", fromTeam, userID).Delete(&PathRule{}).Error; err != nil {
+			repo.logger.Errorw("error deleting path rules", "teamName", fromTeam, "userID", userID, "err", err)
+			return err
+		}
+
+		return tx.Model(&member).Update("team_name", toTeam).Error
+	})
+
+	if err != nil {
+		repo.logger.Errorw("failed to move member", "userID", userID, "toTeam", toTeam, "err", err)
+		return nil, "", err
+	}
+
+	repo.logger.Debugw("member moved", "userID", userID, "fromTeam", fromTeam, "toTeam", toTeam)
+	return &member, fromTeam, nil
+}
+
+// RenameTeam - новое имя расходится по users, правилам и запасным командам через ON UPDATE CASCADE
+func (repo *TeamsRepoPg) RenameTeam(teamName, newName string) (*Team, error) {
+	repo.logger.Debugw("RenameTeam()", "teamName", teamName, "newName", newName)
+
+	var team Team
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := repo.lockTeamInTx(tx, teamName, &team); err != nil {
+			return err
+		}
+
+		var taken int64
+		if err := tx.Model(&Team{}).Where("team_name = ?", newName).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			repo.logger.Warnw("team name already taken", "teamName", teamName, "newName", newName)
+			return ErrTeamExists
+		}
+
+		if err := tx.Model(&team).Update("team_name", newName).Error; err != nil {
+			repo.logger.Errorw("error renaming team", "teamName", teamName, "err", err)
+			return err
+		}
+
+		return tx.Preload("Members").First(&team, "team_name = ?", newName).Error
+	})
+
+	if err != nil {
+		repo.logger.Errorw("failed to rename team", "teamName", teamName, "newName", newName, "err", err)
+		return nil, err
+	}
+
+	repo.logger.Debugw("team renamed", "teamName", teamName, "newName", newName)
+	return &team, nil
+}
+
+// DeleteTeam - только пустая команда. Правила, курсор ротации и упоминания в запасных удаляются каскадом
+func (repo *TeamsRepoPg) DeleteTeam(teamName string) error {
+	repo.logger.Debugw("DeleteTeam()", "teamName", teamName)
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var team Team
+		if err := repo.lockTeamInTx(tx, teamName, &team); err != nil {
+			return err
+		}
+
+		var members int64
+		if err := tx.Model(&user.User{}).Where("team_name = ?", teamName).Count(&members).Error; err != nil {
+			return err
+		}
+		if members > 0 {
+			repo.logger.Warnw("team is not empty", "teamName", teamName, "members", members)
+			return ErrTeamNotEmpty
+		}
+
+		return tx.Delete(&team).Error
+	})
+
+	if err != nil {
+		repo.logger.Errorw("failed to delete team", "teamName", teamName, "err", err)
+		return err
+	}
+
+	repo.logger.Debugw("team deleted", "teamName", teamName)
+	return nil
+}
+
+// rejectOffboardedInTx - уволенных (надгробия с offboarded_at) upsert не должен снова сделать активными
+func (repo *TeamsRepoPg) rejectOffboardedInTx(tx *gorm.DB, members []*user.User) error {
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		if m.IsActive {
+			ids = append(ids, m.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var offboarded int64
+	if err := tx.Model(&user.User{}).
+		Where("user_id IN ? AND offboarded_at IS NOT NULL", ids).
+		Count(&offboarded).Error; err != nil {
+		return err
+	}
+	if offboarded > 0 {
+		repo.logger.Warnw("cannot reactivate offboarded users", "count", offboarded)
+		return user.ErrUserOffboarded
+	}
+
+	return nil
+}
+
+func (repo *TeamsRepoPg) lockTeamInTx(tx *gorm.DB, teamName string, team *Team) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(team, "team_name = ?", teamName).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			repo.logger.Warnw("team does not exist", "teamName", teamName)
+			return ErrTeamNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SyncTeam приводит состав команды к переданному списку: создает команду, если ее нет, добавляет и обновляет
+// пользователей, забирает их из других команд. Пропавших из списка не удаляем, а деактивируем - у них могут быть PR.
+// Повторный вызов с тем же списком ничего не меняет. Ревью деактивированных и переведенных переназначает вызывающий
+func (repo *TeamsRepoPg) SyncTeam(teamName string, members []*user.User) (*SyncResult, error) {
+	repo.logger.Debugw("SyncTeam()", "teamName", teamName, "membersCount", len(members))
+
+	result := &SyncResult{
+		Added:       []string{},
+		Updated:     []string{},
+		Moved:       []MovedMember{},
+		Deactivated: []string{},
+	}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Team{TeamName: teamName})
+		if created.Error != nil {
+			repo.logger.Errorw("error creating team", "teamName", teamName, "err", created.Error)
+			return created.Error
+		}
+		result.Created = created.RowsAffected > 0
+
+		var team Team
+		if err := repo.lockTeamInTx(tx, teamName, &team); err != nil {
+			return err
+		}
+
+		ids := make([]string, 0, len(members))
+		for _, m := range members {
+			ids = append(ids, m.UserID)
+		}
+
+		var existing []*user.User
+		q := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("team_name = ?", teamName)
+		if len(ids) > 0 {
+			q = q.Or("user_id IN ?", ids)
+		}
+		if err := q.Order("user_id ASC").Find(&existing).Error; err != nil {
+			repo.logger.Errorw("error loading members", "teamName", teamName, "err", err)
+			return err
+		}
+
+		current := make(map[string]*user.User, len(existing))
+		for _, u := range existing {
+			current[u.UserID] = u
+		}
+
+		upsert := make([]*user.User, 0, len(members))
+		for _, m := range members {
+			copyU := *m
+			copyU.TeamName = teamName
+
+			cur, ok := current[m.UserID]
+			if ok && cur.OffboardedAt != nil && m.IsActive {
+				repo.logger.Warnw("cannot reactivate offboarded user", "teamName", teamName, "userID", m.UserID)
+				return user.ErrUserOffboarded
+			}
+			switch {
+			case !ok:
+				result.Added = append(result.Added, m.UserID)
+			case cur.TeamName != teamName:
+				result.Moved = append(result.Moved, MovedMember{UserID: m.UserID, FromTeam: cur.TeamName})
+				if err := tx.Where("team_name = ? AND user_id = ?", cur.TeamName, m.UserID).
+					Delete(&PathRule{}).Error; err != nil {
+					repo.logger.Errorw("error deleting path rules", "teamName", cur.TeamName, "userID", m.UserID, "err", err)
+					return err
+				}
+			case cur.Username != m.Username || cur.IsActive != m.IsActive:
+				result.Updated = append(result.Updated, m.UserID)
+			default:
+				continue
+			}
+
+			if ok && cur.IsActive && !m.IsActive {
+				result.Deactivated = append(result.Deactivated, m.UserID)
+			}
+			upsert = append(upsert, &copyU)
+		}
+
+		listed := make(map[string]struct{}, len(ids))
+		for _, id := range ids {
+			listed[id] = struct{}{}
+		}
+		missing := make([]string, 0)
+		for _, u := range existing {
+			if _, ok := listed[u.UserID]; !ok && u.TeamName == teamName && u.IsActive {
+				missing = append(missing, u.UserID)
+			}
+		}
+
+		if len(upsert) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"username", "team_name", "is_active", "updated_at"}),
+			}).Create(&upsert).Error; err != nil {
+				repo.logger.Errorw("error upserting members", "teamName", teamName, "err", err)
+				return err
+			}
+		}
+
+		if len(missing) > 0 {
+			if err := tx.Model(&user.User{}).Where("user_id IN ?", missing).
+				Update("is_active", false).Error; err != nil {
+				repo.logger.Errorw("error deactivating missing members", "teamName", teamName, "err", err)
+				return err
+			}
+			result.Deactivated = append(result.Deactivated, missing...)
+		}
+
+		result.Team = &team
+		return tx.Preload("Members", func(tx2 *gorm.DB) *gorm.DB {
+			return tx2.Order("users.user_id ASC")
+		}).First(&team, "team_name = ?", teamName).Error
+	})
+
+	if err != nil {
+		repo.logger.Errorw("failed to sync team", "teamName", teamName, "err", err)
+		return nil, err
+	}
+
+	repo.logger.Debugw("team synced", "teamName", teamName, "created", result.Created,
+		"added", len(result.Added), "updated", len(result.Updated), "moved", len(result.Moved),
+		"deactivated", len(result.Deactivated))
+	return result, nil
+}
@@ -79,10 +79,13 @@ func TestTeamsRepoPg_CreateTeam(t *testing.T) {
 			mockFunc: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
 				m.ExpectExec(`INSERT INTO "teams"`).
-					WithArgs("backend", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs("backend", "LEAST_LOADED", 2, sqlmock.AnyArg(), sqlmock.AnyArg(), 0, 0).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectQuery(`SELECT count(*) FROM "users" WHERE user_id IN ($1) AND offboarded_at IS NOT NULL`).
+					WithArgs("user-123").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 				m.ExpectExec(`INSERT INTO "users"`).
-					WithArgs("user-123", "abobus", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs("user-123", "abobus", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				rows := sqlmock.NewRows([]string{
 					"team_name", "created_at", "updated_at",
@@ -115,6 +118,25 @@ func TestTeamsRepoPg_CreateTeam(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "offboarded member",
+			args: createTeamArgs{
+				teamName: "backend",
+				members:  []*user.User{{UserID: "user-123", Username: "abobus", IsActive: true}},
+			},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec(`INSERT INTO "teams"`).
+					WithArgs("backend", "LEAST_LOADED", 2, sqlmock.AnyArg(), sqlmock.AnyArg(), 0, 0).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				m.ExpectQuery(`SELECT count(*) FROM "users" WHERE user_id IN ($1) AND offboarded_at IS NOT NULL`).
+					WithArgs("user-123").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				m.ExpectRollback()
+			},
+			wantErr:  user.ErrUserOffboarded,
+			wantTeam: nil,
+		},
 		{
 			name: "team already exists",
 			args: createTeamArgs{
@@ -124,7 +146,7 @@ func TestTeamsRepoPg_CreateTeam(t *testing.T) {
 			mockFunc: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
 				m.ExpectExec(`INSERT INTO "teams"`).
-					WithArgs("backend", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs("backend", "LEAST_LOADED", 2, sqlmock.AnyArg(), sqlmock.AnyArg(), 0, 0).
 					WillReturnError(errors.New("SQLSTATE 23505"))
 				m.ExpectRollback()
 			},
@@ -140,7 +162,7 @@ func TestTeamsRepoPg_CreateTeam(t *testing.T) {
 			mockFunc: func(m sqlmock.Sqlmock) {
 				m.ExpectBegin()
 				m.ExpectExec(`INSERT INTO "teams"`).
-					WithArgs("backend", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs("backend", "LEAST_LOADED", 2, sqlmock.AnyArg(), sqlmock.AnyArg(), 0, 0).
 					WillReturnError(gorm.ErrInvalidDB)
 				m.ExpectRollback()
 			},
@@ -288,3 +310,724 @@ func TestTeamsRepoPg_GetTeam(t *testing.T) {
 		})
 	}
 }
+
+func TestTeamsRepoPg_UpdateSettings(t *testing.T) {
+	strategy := "ROUND_ROBIN"
+	reviewersRequired := 3
+	fallbacks := []string{"platform", "sre"}
+
+	teamRows := func(strategy string, reviewersRequired int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"team_name", "selection_strategy", "reviewers_required", "created_at", "updated_at",
+		}).AddRow(
+			"backend", strategy, reviewersRequired, time.Now(), time.Now(),
+		)
+	}
+
+	tests := []struct {
+		name          string
+		teamName      string
+		settings      team.Settings
+		mockFunc      func(sqlmock.Sqlmock)
+		wantErr       error
+		wantStrategy  string
+		wantFallbacks []string
+	}{
+		{
+			name:     "success",
+			teamName: "backend",
+			settings: team.Settings{SelectionStrategy: &strategy},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WithArgs("backend", 1).
+					WillReturnRows(teamRows("LEAST_LOADED", 2))
+				m.ExpectExec(`UPDATE "teams" SET "selection_strategy"=$1`).
+					WithArgs("ROUND_ROBIN", sqlmock.AnyArg(), "backend").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(teamRows("ROUND_ROBIN", 2))
+				m.ExpectQuery(`SELECT * FROM "team_fallbacks"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name", "fallback_team_name", "position"}))
+				m.ExpectCommit()
+			},
+			wantStrategy:  "ROUND_ROBIN",
+			wantFallbacks: []string{},
+		},
+		{
+			name:     "reviewers required and fallbacks",
+			teamName: "backend",
+			settings: team.Settings{
+				SelectionStrategy: &strategy,
+				ReviewersRequired: &reviewersRequired,
+				FallbackTeams:     &fallbacks,
+			},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(teamRows("LEAST_LOADED", 2))
+				m.ExpectExec(`UPDATE "teams" SET "reviewers_required"=$1,"selection_strategy"=$2`).
+					WithArgs(3, "ROUND_ROBIN", sqlmock.AnyArg(), "backend").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectQuery(`SELECT count(*) FROM "teams"`).
+					WithArgs("platform", "sre").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+				m.ExpectExec(`DELETE FROM "team_fallbacks"`).
+					WithArgs("backend").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectExec(`INSERT INTO "team_fallbacks"`).
+					WithArgs("backend", "platform", 0, "backend", "sre", 1).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(teamRows("ROUND_ROBIN", 3))
+				m.ExpectQuery(`SELECT * FROM "team_fallbacks"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name", "fallback_team_name", "position"}).
+						AddRow("backend", "platform", 0).
+						AddRow("backend", "sre", 1))
+				m.ExpectCommit()
+			},
+			wantStrategy:  "ROUND_ROBIN",
+			wantFallbacks: []string{"platform", "sre"},
+		},
+		{
+			name:     "unknown fallback team",
+			teamName: "backend",
+			settings: team.Settings{FallbackTeams: &fallbacks},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(teamRows("LEAST_LOADED", 2))
+				m.ExpectQuery(`SELECT count(*) FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+				m.ExpectRollback()
+			},
+			wantErr: team.ErrTeamNotFound,
+		},
+		{
+			name:     "team not found",
+			teamName: "unknown",
+			settings: team.Settings{SelectionStrategy: &strategy},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WithArgs("unknown", 1).
+					WillReturnError(gorm.ErrRecordNotFound)
+				m.ExpectRollback()
+			},
+			wantErr: team.ErrTeamNotFound,
+		},
+		{
+			name:     "sql error",
+			teamName: "backend",
+			settings: team.Settings{SelectionStrategy: &strategy},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(teamRows("LEAST_LOADED", 2))
+				m.ExpectExec(`UPDATE "teams" SET "selection_strategy"=$1`).
+					WithArgs("ROUND_ROBIN", sqlmock.AnyArg(), "backend").
+					WillReturnError(gorm.ErrInvalidDB)
+				m.ExpectRollback()
+			},
+			wantErr: gorm.ErrInvalidDB,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			logger := zap.NewNop().Sugar()
+			repo := team.NewTeamsRepoPg(logger, db)
+
+			tt.mockFunc(mock)
+
+			got, err := repo.UpdateSettings(tt.teamName, tt.settings)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, got)
+				require.Equal(t, tt.wantStrategy, got.SelectionStrategy)
+				require.Len(t, got.Fallbacks, len(tt.wantFallbacks))
+				for i, fb := range got.Fallbacks {
+					require.Equal(t, tt.wantFallbacks[i], fb.FallbackTeamName)
+				}
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTeamsRepoPg_SetPathRules(t *testing.T) {
+	rules := []*team.PathRule{
+		{Position: 0, Pattern: "*", UserID: "u1"},
+		{Position: 1, Pattern: "/docs/", UserID: "u2"},
+	}
+
+	tests := []struct {
+		name      string
+		teamName  string
+		rules     []*team.PathRule
+		mockFunc  func(sqlmock.Sqlmock)
+		wantErr   error
+		wantRules int
+	}{
+		{
+			name:     "success",
+			teamName: "backend",
+			rules:    rules,
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+				m.ExpectQuery(`SELECT count(*) FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+				m.ExpectExec(`DELETE FROM "team_path_rules"`).
+					WithArgs("backend").
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				m.ExpectQuery(`INSERT INTO "team_path_rules"`).
+					WithArgs(
+						"backend", 0, "*", "u1", sqlmock.AnyArg(),
+						"backend", 1, "/docs/", "u2", sqlmock.AnyArg(),
+					).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+				m.ExpectCommit()
+			},
+			wantRules: 2,
+		},
+		{
+			name:     "owner outside team",
+			teamName: "backend",
+			rules:    rules,
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+				m.ExpectQuery(`SELECT count(*) FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+				m.ExpectRollback()
+			},
+			wantErr: user.ErrUserNotFound,
+		},
+		{
+			name:     "team not found",
+			teamName: "unknown",
+			rules:    rules,
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnError(gorm.ErrRecordNotFound)
+				m.ExpectRollback()
+			},
+			wantErr: team.ErrTeamNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			logger := zap.NewNop().Sugar()
+			repo := team.NewTeamsRepoPg(logger, db)
+
+			tt.mockFunc(mock)
+
+			got, err := repo.SetPathRules(tt.teamName, tt.rules)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, got, tt.wantRules)
+				for _, r := range got {
+					require.Equal(t, tt.teamName, r.TeamName)
+				}
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTeamsRepoPg_GetPathRules(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := team.NewTeamsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectQuery(`SELECT count(*) FROM "teams"`).
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+	rows := sqlmock.NewRows([]string{"id", "team_name", "position", "pattern", "user_id", "created_at"}).
+		AddRow(1, "backend", 0, "*", "u1", time.Now()).
+		AddRow(2, "backend", 0, "*", "u2", time.Now())
+	mock.ExpectQuery(`SELECT * FROM "team_path_rules"`).
+		WithArgs("backend").
+		WillReturnRows(rows)
+
+	got, err := repo.GetPathRules("backend")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "u2", got[1].UserID)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTeamsRepoPg_RemoveMember(t *testing.T) {
+	tests := []struct {
+		name     string
+		mockFunc func(sqlmock.Sqlmock)
+		wantErr  error
+	}{
+		{
+			name: "success",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WithArgs("u1", "backend", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "team_name"}).AddRow("u1", "backend"))
+				m.ExpectQuery(`SELECT count(*) FROM "pull_requests"`).
+					WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+				m.ExpectQuery(`SELECT count(*) FROM "pr_reviewers"`).
+					WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+				m.ExpectQuery(`SELECT count(*) FROM "pr_reviews"`).
+					WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+				m.ExpectExec(`DELETE FROM "users"`).
+					WithArgs("u1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "author of PRs",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "team_name"}).AddRow("u1", "backend"))
+				m.ExpectQuery(`SELECT count(*) FROM "pull_requests"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(2)))
+				m.ExpectRollback()
+			},
+			wantErr: team.ErrMemberHasPRs,
+		},
+		{
+			name: "assigned to merged PRs",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "team_name"}).AddRow("u1", "backend"))
+				m.ExpectQuery(`SELECT count(*) FROM "pull_requests"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+				m.ExpectQuery(`SELECT count(*) FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(3)))
+				m.ExpectRollback()
+			},
+			wantErr: team.ErrMemberHasReviews,
+		},
+		{
+			name: "left verdicts only",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "team_name"}).AddRow("u1", "backend"))
+				m.ExpectQuery(`SELECT count(*) FROM "pull_requests"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+				m.ExpectQuery(`SELECT count(*) FROM "pr_reviewers"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+				m.ExpectQuery(`SELECT count(*) FROM "pr_reviews"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+				m.ExpectRollback()
+			},
+			wantErr: team.ErrMemberHasReviews,
+		},
+		{
+			name: "not a member",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnError(gorm.ErrRecordNotFound)
+				m.ExpectRollback()
+			},
+			wantErr: user.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := team.NewTeamsRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			err := repo.RemoveMember("backend", "u1")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTeamsRepoPg_MoveMember(t *testing.T) {
+	tests := []struct {
+		name         string
+		mockFunc     func(sqlmock.Sqlmock)
+		wantErr      error
+		wantFromTeam string
+	}{
+		{
+			name: "success",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WithArgs("frontend", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("frontend"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WithArgs("u1", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "team_name"}).AddRow("u1", "backend"))
+				m.ExpectExec(`DELETE FROM "team_path_rules"`).
+					WithArgs("backend", "u1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(`UPDATE "users" SET "team_name"`).
+					WithArgs("frontend", sqlmock.AnyArg(), "u1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			wantFromTeam: "backend",
+		},
+		{
+			name: "already in team",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("frontend"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "team_name"}).AddRow("u1", "frontend"))
+				m.ExpectCommit()
+			},
+			wantFromTeam: "frontend",
+		},
+		{
+			name: "target team not found",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).WillReturnError(gorm.ErrRecordNotFound)
+				m.ExpectRollback()
+			},
+			wantErr: team.ErrTeamNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := team.NewTeamsRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, fromTeam, err := repo.MoveMember("u1", "frontend")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantFromTeam, fromTeam)
+				require.Equal(t, "frontend", got.TeamName)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTeamsRepoPg_DeleteTeam(t *testing.T) {
+	tests := []struct {
+		name     string
+		mockFunc func(sqlmock.Sqlmock)
+		wantErr  error
+	}{
+		{
+			name: "success",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+				m.ExpectQuery(`SELECT count(*) FROM "users"`).
+					WithArgs("backend").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+				m.ExpectExec(`DELETE FROM "teams"`).
+					WithArgs("backend").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "not empty",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+				m.ExpectQuery(`SELECT count(*) FROM "users"`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(3)))
+				m.ExpectRollback()
+			},
+			wantErr: team.ErrTeamNotEmpty,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := team.NewTeamsRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			err := repo.DeleteTeam("backend")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTeamsRepoPg_RenameTeam_Taken(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := team.NewTeamsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT * FROM "teams"`).
+		WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+	mock.ExpectQuery(`SELECT count(*) FROM "teams"`).
+		WithArgs("platform").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+	mock.ExpectRollback()
+
+	got, err := repo.RenameTeam("backend", "platform")
+	require.ErrorIs(t, err, team.ErrTeamExists)
+	require.Nil(t, got)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTeamsRepoPg_AddMembers_OtherTeam(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := team.NewTeamsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT * FROM "teams"`).
+		WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+	mock.ExpectQuery(`SELECT count(*) FROM "users"`).
+		WithArgs("u1", "backend").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+	mock.ExpectRollback()
+
+	got, err := repo.AddMembers("backend", []*user.User{{UserID: "u1", Username: "alice", IsActive: true}})
+	require.ErrorIs(t, err, team.ErrMemberOfOtherTeam)
+	require.Nil(t, got)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTeamsRepoPg_SyncTeam(t *testing.T) {
+	userCols := []string{"user_id", "username", "team_name", "is_active"}
+
+	tests := []struct {
+		name     string
+		members  []*user.User
+		mockFunc func(sqlmock.Sqlmock)
+		want     *team.SyncResult
+	}{
+		{
+			name: "reconcile",
+			members: []*user.User{
+				{UserID: "u1", Username: "alice", IsActive: true},
+				{UserID: "u2", Username: "bob-renamed", IsActive: true},
+				{UserID: "u3", Username: "carol", IsActive: true},
+				{UserID: "u5", Username: "eve", IsActive: true},
+			},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec(`INSERT INTO "teams"`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WithArgs("backend", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+				m.ExpectQuery(`SELECT * FROM "users" WHERE team_name = $1 OR user_id IN ($2,$3,$4,$5) ORDER BY user_id ASC FOR UPDATE`).
+					WithArgs("backend", "u1", "u2", "u3", "u5").
+					WillReturnRows(sqlmock.NewRows(userCols).
+						AddRow("u2", "bob", "backend", true).
+						AddRow("u3", "carol", "frontend", true).
+						AddRow("u4", "dave", "backend", true).
+						AddRow("u5", "eve", "backend", true))
+				// u3 уходит из frontend вместе со своими правилами владения
+				m.ExpectExec(`DELETE FROM "team_path_rules"`).
+					WithArgs("frontend", "u3").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				// u5 не изменился и в upsert не попадает
+				m.ExpectExec(`INSERT INTO "users"`).
+					WithArgs(
+						"u1", "alice", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil,
+						"u2", "bob-renamed", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil,
+						"u3", "carol", "backend", true, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil,
+					).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				m.ExpectExec(`UPDATE "users" SET "is_active"`).
+					WithArgs(false, sqlmock.AnyArg(), "u4").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("u1", "alice", "backend", true))
+				m.ExpectCommit()
+			},
+			want: &team.SyncResult{
+				Created:     false,
+				Added:       []string{"u1"},
+				Updated:     []string{"u2"},
+				Moved:       []team.MovedMember{{UserID: "u3", FromTeam: "frontend"}},
+				Deactivated: []string{"u4"},
+			},
+		},
+		{
+			name: "team created",
+			members: []*user.User{
+				{UserID: "u1", Username: "alice", IsActive: true},
+			},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec(`INSERT INTO "teams"`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows(userCols))
+				m.ExpectExec(`INSERT INTO "users"`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("u1", "alice", "backend", true))
+				m.ExpectCommit()
+			},
+			want: &team.SyncResult{
+				Created:     true,
+				Added:       []string{"u1"},
+				Updated:     []string{},
+				Moved:       []team.MovedMember{},
+				Deactivated: []string{},
+			},
+		},
+		{
+			name: "already in sync",
+			members: []*user.User{
+				{UserID: "u1", Username: "alice", IsActive: true},
+			},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectExec(`INSERT INTO "teams"`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("u1", "alice", "backend", true))
+				m.ExpectQuery(`SELECT * FROM "teams"`).
+					WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+				m.ExpectQuery(`SELECT * FROM "users"`).
+					WillReturnRows(sqlmock.NewRows(userCols).AddRow("u1", "alice", "backend", true))
+				m.ExpectCommit()
+			},
+			want: &team.SyncResult{
+				Created:     false,
+				Added:       []string{},
+				Updated:     []string{},
+				Moved:       []team.MovedMember{},
+				Deactivated: []string{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := team.NewTeamsRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.SyncTeam("backend", tt.members)
+			require.NoError(t, err)
+			require.Equal(t, "backend", got.Team.TeamName)
+			require.Equal(t, tt.want.Created, got.Created)
+			require.Equal(t, tt.want.Added, got.Added)
+			require.Equal(t, tt.want.Updated, got.Updated)
+			require.Equal(t, tt.want.Moved, got.Moved)
+			require.Equal(t, tt.want.Deactivated, got.Deactivated)
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTeamsRepoPg_SyncTeam_Offboarded(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := team.NewTeamsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "teams"`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT * FROM "teams"`).
+		WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+	mock.ExpectQuery(`SELECT * FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "offboarded_at"}).
+			AddRow("u1", "deleted-user-1", "backend", false, time.Now()))
+	mock.ExpectRollback()
+
+	// надгробие в справочнике активным обратно не становится
+	got, err := repo.SyncTeam("backend", []*user.User{{UserID: "u1", Username: "alice", IsActive: true}})
+	require.ErrorIs(t, err, user.ErrUserOffboarded)
+	require.Nil(t, got)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTeamsRepoPg_ListTeams(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := team.NewTeamsRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectQuery(`SELECT * FROM "teams" ORDER BY team_name ASC`).
+		WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend").AddRow("empty"))
+	mock.ExpectQuery(`SELECT * FROM "users" WHERE "users"."team_name" IN ($1,$2) ORDER BY users.user_id ASC`).
+		WithArgs("backend", "empty").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active"}).
+			AddRow("u1", "alice", "backend", true))
+
+	got, err := repo.ListTeams()
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Len(t, got[0].Members, 1)
+	require.Empty(t, got[1].Members)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
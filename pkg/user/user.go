@@ -6,7 +6,17 @@ import (
 )
 
 var (
-	ErrUserNotFound = errors.New("USER_NOT_FOUND")
+	ErrUserNotFound           = errors.New("USER_NOT_FOUND")
+	ErrUnavailabilityNotFound = errors.New("UNAVAILABILITY_NOT_FOUND")
+	ErrInvalidCursor          = errors.New("USER_INVALID_CURSOR")
+	ErrIdentityNotFound       = errors.New("IDENTITY_NOT_FOUND")
+	// ErrUserOffboarded - попытка снова активировать надгробие уволенного
+	ErrUserOffboarded = errors.New("USER_OFFBOARDED")
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 100
 )
 
 type User struct {
@@ -16,9 +26,90 @@ type User struct {
 	IsActive  bool   `gorm:"not null;default:true;column:is_active" json:"is_active"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// MaxOpenReviews - личный лимит открытых ревью, nil - берется лимит команды
+	MaxOpenReviews *int `gorm:"column:max_open_reviews" json:"max_open_reviews"`
+	// OffboardedAt - надгробие уволенного, которого нельзя удалить из-за авторства PR или истории ревью
+	OffboardedAt *time.Time `gorm:"column:offboarded_at" json:"offboarded_at"`
+}
+
+// Unavailability - окно [StartsAt, EndsAt), в которое пользователя не назначают ревьювером (отпуск, OOO).
+// В отличие от is_active само заканчивается, выключать руками не нужно
+type Unavailability struct {
+	ID        uint64    `gorm:"primaryKey;column:id"`
+	UserID    string    `gorm:"type:varchar(64);index;not null;column:user_id"`
+	StartsAt  time.Time `gorm:"not null;column:starts_at"`
+	EndsAt    time.Time `gorm:"not null;column:ends_at"`
+	Reason    string    `gorm:"type:varchar(255);not null;default:'';column:reason"`
+	CreatedAt time.Time
+}
+
+func (Unavailability) TableName() string {
+	return "user_unavailabilities"
+}
+
+// Identity - логин во внешней системе (GitHub, GitLab), под которым пользователь приходит в вебхуках.
+// Логины хранятся в нижнем регистре: у провайдеров они регистронезависимые
+type Identity struct {
+	Provider string `gorm:"primaryKey;type:varchar(32);column:provider"`
+	Login    string `gorm:"primaryKey;type:varchar(255);column:login"`
+	UserID   string `gorm:"type:varchar(64);index;not null;column:user_id"`
+	// ExternalID - числовой id у провайдера: в событиях MR у GitLab от автора есть только author_id
+	ExternalID *int64 `gorm:"column:external_id"`
+	CreatedAt  time.Time
+}
+
+func (Identity) TableName() string {
+	return "user_identities"
+}
+
+// UserFilter - фильтры списка пользователей, пустые поля не применяются. Search - подстрока username без учета регистра
+type UserFilter struct {
+	TeamName string
+	IsActive *bool
+	Search   string
+	Cursor   string
+	Limit    int
+}
+
+// UserPage - страница списка по user_id, NextCursor пустой на последней странице
+type UserPage struct {
+	Users      []*User
+	NextCursor string
+}
+
+// UserUpdate - частичное обновление, nil поля не трогаются. Команда меняется только через перевод в команде
+type UserUpdate struct {
+	Username *string
+	IsActive *bool
+}
+
+// OffboardResult - что стало с самим пользователем при увольнении. User - состояние до удаления или надгробие
+type OffboardResult struct {
+	User       *User
+	Deleted    bool
+	Anonymized bool
+
+	PathRulesDeleted        int64
+	UnavailabilitiesDeleted int64
+	IdentitiesDeleted       int64
 }
 
 type UsersRepo interface {
+	GetUser(userID string) (*User, error)
+	ListUsers(filter UserFilter) (*UserPage, error)
+	UpdateUser(userID string, upd UserUpdate) (*User, error)
+	Offboard(userID string, anonymize bool) (*OffboardResult, error)
+	SetIdentity(identity *Identity) (*Identity, error)
+	ListIdentities(provider string) ([]*Identity, error)
+	DeleteIdentity(provider, login string) error
+	ResolveIdentity(provider, login string) (string, error)
+	ResolveExternalID(provider string, externalID int64) (string, error)
+	LoginsByUsers(provider string, userIDs []string) (map[string]string, error)
 	SetIsActive(userID string, isActive bool) (*User, error)
 	SetIsActiveByTeam(teamName string, isActive bool) ([]*User, error)
+	SetMaxOpenReviews(userID string, limit *int) (*User, error)
+	AddUnavailability(window *Unavailability) (*Unavailability, error)
+	ListUnavailability(userID string) ([]*Unavailability, error)
+	DeleteUnavailability(userID string, id uint64) error
 }
@@ -1,6 +1,13 @@
 package user
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -26,24 +33,50 @@ func NewUsersRepoPg(logger *zap.SugaredLogger, db *gorm.DB) *UsersRepoPg {
 func (repo *UsersRepoPg) SetIsActive(userID string, isActive bool) (*User, error) {
 	repo.logger.Debugw("setIsActive()", "userID", userID, "isActive", isActive)
 
+	var user User
+	q := repo.db.Model(&user).Where("user_id = ?", userID)
+	if isActive {
+		q = q.Where("offboarded_at IS NULL")
+	}
+	tx := q.Clauses(clause.Returning{}).Update("is_active", isActive)
+
+	if tx.Error != nil {
+		repo.logger.Errorw("error setting is_active", "userID", userID, "err", tx.Error)
+		return nil, tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		if isActive {
+			return nil, repo.notReactivatedErr(userID)
+		}
+		repo.logger.Errorw("error setting is_active - no user found with this id", "userID", userID)
+		return nil, ErrUserNotFound
+	}
+
+	repo.logger.Debugw("got user by id", "userID", userID)
+	return &user, nil
+}
+
+func (repo *UsersRepoPg) SetMaxOpenReviews(userID string, limit *int) (*User, error) {
+	repo.logger.Debugw("SetMaxOpenReviews()", "userID", userID, "limit", limit)
+
 	var user User
 	tx := repo.db.
 		Model(&user).
 		Where("user_id = ?", userID).
 		Clauses(clause.Returning{}).
-		Update("is_active", isActive)
+		Update("max_open_reviews", limit)
 
 	if tx.Error != nil {
-		repo.logger.Errorw("error setting is_active", "userID", userID, "err", tx.Error)
+		repo.logger.Errorw("error setting max_open_reviews", "userID", userID, "err", tx.Error)
 		return nil, tx.Error
 	}
 
 	if tx.RowsAffected == 0 {
-		repo.logger.Errorw("error setting is_active - no user found with this id", "userID", userID)
+		repo.logger.Warnw("error setting max_open_reviews - no user found with this id", "userID", userID)
 		return nil, ErrUserNotFound
 	}
 
-	repo.logger.Debugw("got user by id", "userID", userID)
 	return &user, nil
 }
 
@@ -51,9 +84,11 @@ func (repo *UsersRepoPg) SetIsActiveByTeam(teamName string, isActive bool) ([]*U
 	repo.logger.Debugw("SetIsActiveByTeam()", "teamName", teamName)
 
 	var updatedUsers []*User
-	tx := repo.db.
-		Model(&User{}).
-		Where("team_name = ?", teamName).
+	q := repo.db.Model(&User{}).Where("team_name = ?", teamName)
+	if isActive {
+		q = q.Where("offboarded_at IS NULL")
+	}
+	tx := q.
 		Clauses(clause.Returning{}).
 		Update("is_active", isActive).
 		Scan(&updatedUsers)
@@ -70,3 +105,404 @@ func (repo *UsersRepoPg) SetIsActiveByTeam(teamName string, isActive bool) ([]*U
 	repo.logger.Debugw("team members deactivated", "teamName", teamName, "affected", tx.RowsAffected)
 	return updatedUsers, nil
 }
+
+func (repo *UsersRepoPg) AddUnavailability(window *Unavailability) (*Unavailability, error) {
+	repo.logger.Debugw("AddUnavailability()", "userID", window.UserID,
+		"startsAt", window.StartsAt, "endsAt", window.EndsAt)
+
+	if err := repo.ensureUserExists(window.UserID); err != nil {
+		return nil, err
+	}
+
+	if err := repo.db.Create(window).Error; err != nil {
+		repo.logger.Errorw("error creating unavailability", "userID", window.UserID, "err", err)
+		return nil, err
+	}
+
+	return window, nil
+}
+
+func (repo *UsersRepoPg) ListUnavailability(userID string) ([]*Unavailability, error) {
+	repo.logger.Debugw("ListUnavailability()", "userID", userID)
+
+	if err := repo.ensureUserExists(userID); err != nil {
+		return nil, err
+	}
+
+	var windows []*Unavailability
+	if err := repo.db.
+		Where("user_id = ?", userID).
+		Order("starts_at ASC").
+		Find(&windows).Error; err != nil {
+		repo.logger.Errorw("error listing unavailability", "userID", userID, "err", err)
+		return nil, err
+	}
+
+	return windows, nil
+}
+
+func (repo *UsersRepoPg) DeleteUnavailability(userID string, id uint64) error {
+	repo.logger.Debugw("DeleteUnavailability()", "userID", userID, "id", id)
+
+	tx := repo.db.Where("id = ? AND user_id = ?", id, userID).Delete(&Unavailability{})
+	if tx.Error != nil {
+		repo.logger.Errorw("error deleting unavailability", "userID", userID, "id", id, "err", tx.Error)
+		return tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		repo.logger.Warnw("no unavailability window to delete", "userID", userID, "id", id)
+		return ErrUnavailabilityNotFound
+	}
+
+	return nil
+}
+
+// notReactivatedErr - активация не затронула ни одной строки: пользователя нет или он уволен.
+// Надгробие (offboarded_at) обратно не активируется
+func (repo *UsersRepoPg) notReactivatedErr(userID string) error {
+	if err := repo.ensureUserExists(userID); err != nil {
+		return err
+	}
+
+	repo.logger.Warnw("cannot reactivate offboarded user", "userID", userID)
+	return ErrUserOffboarded
+}
+
+func (repo *UsersRepoPg) ensureUserExists(userID string) error {
+	var count int64
+	if err := repo.db.Model(&User{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		repo.logger.Errorw("error checking user", "userID", userID, "err", err)
+		return err
+	}
+
+	if count == 0 {
+		repo.logger.Warnw("user not found", "userID", userID)
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (repo *UsersRepoPg) GetUser(userID string) (*User, error) {
+	repo.logger.Debugw("GetUser()", "userID", userID)
+
+	var user User
+	if err := repo.db.First(&user, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			repo.logger.Warnw("user not found", "userID", userID)
+			return nil, ErrUserNotFound
+		}
+		repo.logger.Errorw("error getting user", "userID", userID, "err", err)
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ListUsers - keyset-пагинация по user_id, курсор - последний user_id страницы
+func (repo *UsersRepoPg) ListUsers(filter UserFilter) (*UserPage, error) {
+	repo.logger.Debugw("ListUsers()", "filter", filter)
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	limit = min(limit, MaxListLimit)
+
+	q := repo.db.Model(&User{})
+	if filter.TeamName != "" {
+		q = q.Where("team_name = ?", filter.TeamName)
+	}
+	if filter.IsActive != nil {
+		q = q.Where("is_active = ?", *filter.IsActive)
+	}
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		q = q.Where("username ILIKE ?", "%"+likeEscaper.Replace(search)+"%")
+	}
+	if filter.Cursor != "" {
+		after, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil || len(after) == 0 {
+			repo.logger.Warnw("invalid cursor", "cursor", filter.Cursor)
+			return nil, ErrInvalidCursor
+		}
+		q = q.Where("user_id > ?", string(after))
+	}
+
+	var users []*User
+	if err := q.Order("user_id ASC").Limit(limit + 1).Find(&users).Error; err != nil {
+		repo.logger.Errorw("error listing users", "err", err)
+		return nil, err
+	}
+
+	page := &UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(page.Users[limit-1].UserID))
+	}
+
+	repo.logger.Debugw("listed users", "count", len(page.Users), "hasMore", page.NextCursor != "")
+	return page, nil
+}
+
+// likeEscaper - поиск по подстроке, а не по шаблону: % и _ из запроса ищутся буквально
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (repo *UsersRepoPg) UpdateUser(userID string, upd UserUpdate) (*User, error) {
+	repo.logger.Debugw("UpdateUser()", "userID", userID)
+
+	columns := make(map[string]any, 2)
+	if upd.Username != nil {
+		columns["username"] = *upd.Username
+	}
+	if upd.IsActive != nil {
+		columns["is_active"] = *upd.IsActive
+	}
+	if len(columns) == 0 {
+		return repo.GetUser(userID)
+	}
+
+	reactivate := upd.IsActive != nil && *upd.IsActive
+
+	var user User
+	q := repo.db.Model(&user).Where("user_id = ?", userID)
+	if reactivate {
+		q = q.Where("offboarded_at IS NULL")
+	}
+	tx := q.Clauses(clause.Returning{}).Updates(columns)
+
+	if tx.Error != nil {
+		repo.logger.Errorw("error updating user", "userID", userID, "err", tx.Error)
+		return nil, tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		if reactivate {
+			return nil, repo.notReactivatedErr(userID)
+		}
+		repo.logger.Warnw("error updating user - no user found with this id", "userID", userID)
+		return nil, ErrUserNotFound
+	}
+
+	return &user, nil
+}
+
+// AnonymousUsername - обезличенное имя, стабильное для одного user_id, чтобы повторное увольнение ничего не меняло
+func AnonymousUsername(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return "deleted-user-" + hex.EncodeToString(sum[:4])
+}
+
+// Offboard - последний шаг увольнения: ревью к этому моменту уже сняты, а PR переданы или закрыты.
+// Правила владения, отпуска и привязки логинов удаляются всегда. Совсем удаляется только пользователь без истории,
+// автор PR или ревьювер (назначения и вердикты ушли бы каскадом) остается неактивным надгробием
+func (repo *UsersRepoPg) Offboard(userID string, anonymize bool) (*OffboardResult, error) {
+	repo.logger.Debugw("Offboard()", "userID", userID, "anonymize", anonymize)
+
+	result := &OffboardResult{}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "user_id = ?", userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				repo.logger.Warnw("user not found", "userID", userID)
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		rules := tx.Exec("DELETE FROM team_path_rules WHERE user_id = ?", userID)
+		if rules.Error != nil {
+			repo.logger.Errorw("error deleting path rules", "userID", userID, "err", rules.Error)
+			return rules.Error
+		}
+		result.PathRulesDeleted = rules.RowsAffected
+
+		windows := tx.Where("user_id = ?", userID).Delete(&Unavailability{})
+		if windows.Error != nil {
+			repo.logger.Errorw("error deleting unavailability", "userID", userID, "err", windows.Error)
+			return windows.Error
+		}
+		result.UnavailabilitiesDeleted = windows.RowsAffected
+
+		// без привязок вебхуки больше не найдут уволенного по логину
+		identities := tx.Where("user_id = ?", userID).Delete(&Identity{})
+		if identities.Error != nil {
+			repo.logger.Errorw("error deleting identities", "userID", userID, "err", identities.Error)
+			return identities.Error
+		}
+		result.IdentitiesDeleted = identities.RowsAffected
+
+		// история - авторство, назначения (в том числе на закрытые и смерженные PR) и вердикты
+		hasHistory := false
+		for _, ref := range []struct{ table, column string }{
+			{"pull_requests", "author_id"}, {"pr_reviewers", "user_id"}, {"pr_reviews", "user_id"},
+		} {
+			var count int64
+			if err := tx.Table(ref.table).Where(ref.column+" = ?", userID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				hasHistory = true
+				break
+			}
+		}
+
+		if !hasHistory {
+			if err := tx.Delete(&user).Error; err != nil {
+				repo.logger.Errorw("error deleting user", "userID", userID, "err", err)
+				return err
+			}
+			result.User = &user
+			result.Deleted = true
+			return nil
+		}
+
+		columns := map[string]any{"is_active": false}
+		if user.OffboardedAt == nil {
+			columns["offboarded_at"] = time.Now()
+		}
+		if anonymize {
+			columns["username"] = AnonymousUsername(userID)
+			result.Anonymized = true
+		}
+		if err := tx.Model(&user).Clauses(clause.Returning{}).Updates(columns).Error; err != nil {
+			repo.logger.Errorw("error tombstoning user", "userID", userID, "err", err)
+			return err
+		}
+		result.User = &user
+		return nil
+	})
+
+	if err != nil {
+		repo.logger.Errorw("failed to offboard user", "userID", userID, "err", err)
+		return nil, err
+	}
+
+	repo.logger.Infow("user offboarded", "userID", userID, "deleted", result.Deleted, "anonymized", result.Anonymized)
+	return result, nil
+}
+
+// SetIdentity - привязка логина к пользователю, повторная привязка того же логина перевешивает его.
+// Внешний id перезаписывается вместе с логином и снимается с прежнего логина (у GitLab логин можно сменить, id нет)
+func (repo *UsersRepoPg) SetIdentity(identity *Identity) (*Identity, error) {
+	repo.logger.Debugw("SetIdentity()", "provider", identity.Provider, "login", identity.Login, "userID", identity.UserID)
+
+	if err := repo.ensureUserExists(identity.UserID); err != nil {
+		return nil, err
+	}
+
+	identity.Login = strings.ToLower(identity.Login)
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if identity.ExternalID != nil {
+			if err := tx.Model(&Identity{}).
+				Where("provider = ? AND external_id = ? AND login <> ?", identity.Provider, *identity.ExternalID, identity.Login).
+				Update("external_id", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "provider"}, {Name: "login"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "external_id"}),
+		}).Create(identity).Error
+	})
+	if err != nil {
+		repo.logger.Errorw("error setting identity", "provider", identity.Provider, "login", identity.Login, "err", err)
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (repo *UsersRepoPg) ListIdentities(provider string) ([]*Identity, error) {
+	repo.logger.Debugw("ListIdentities()", "provider", provider)
+
+	q := repo.db.Model(&Identity{})
+	if provider != "" {
+		q = q.Where("provider = ?", provider)
+	}
+
+	var identities []*Identity
+	if err := q.Order("provider ASC, login ASC").Find(&identities).Error; err != nil {
+		repo.logger.Errorw("error listing identities", "provider", provider, "err", err)
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+func (repo *UsersRepoPg) DeleteIdentity(provider, login string) error {
+	repo.logger.Debugw("DeleteIdentity()", "provider", provider, "login", login)
+
+	tx := repo.db.Where("provider = ? AND login = ?", provider, strings.ToLower(login)).Delete(&Identity{})
+	if tx.Error != nil {
+		repo.logger.Errorw("error deleting identity", "provider", provider, "login", login, "err", tx.Error)
+		return tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		repo.logger.Warnw("no identity to delete", "provider", provider, "login", login)
+		return ErrIdentityNotFound
+	}
+
+	return nil
+}
+
+func (repo *UsersRepoPg) ResolveIdentity(provider, login string) (string, error) {
+	repo.logger.Debugw("ResolveIdentity()", "provider", provider, "login", login)
+
+	var identity Identity
+	if err := repo.db.First(&identity, "provider = ? AND login = ?", provider, strings.ToLower(login)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			repo.logger.Warnw("unknown identity", "provider", provider, "login", login)
+			return "", ErrIdentityNotFound
+		}
+		repo.logger.Errorw("error resolving identity", "provider", provider, "login", login, "err", err)
+		return "", err
+	}
+
+	return identity.UserID, nil
+}
+
+// ResolveExternalID - как ResolveIdentity, но по числовому id у провайдера
+func (repo *UsersRepoPg) ResolveExternalID(provider string, externalID int64) (string, error) {
+	repo.logger.Debugw("ResolveExternalID()", "provider", provider, "externalID", externalID)
+
+	var identity Identity
+	if err := repo.db.First(&identity, "provider = ? AND external_id = ?", provider, externalID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			repo.logger.Warnw("unknown external id", "provider", provider, "externalID", externalID)
+			return "", ErrIdentityNotFound
+		}
+		repo.logger.Errorw("error resolving external id", "provider", provider, "externalID", externalID, "err", err)
+		return "", err
+	}
+
+	return identity.UserID, nil
+}
+
+// LoginsByUsers - обратная сторона ResolveIdentity: user_id -> логин у провайдера. Если логинов у пользователя
+// несколько, берется первый по алфавиту, пользователей без логина в ответе нет
+func (repo *UsersRepoPg) LoginsByUsers(provider string, userIDs []string) (map[string]string, error) {
+	repo.logger.Debugw("LoginsByUsers()", "provider", provider, "userIDs", userIDs)
+
+	logins := make(map[string]string, len(userIDs))
+	if len(userIDs) == 0 {
+		return logins, nil
+	}
+
+	var identities []*Identity
+	if err := repo.db.Where("provider = ? AND user_id IN ?", provider, userIDs).
+		Order("user_id ASC, login ASC").Find(&identities).Error; err != nil {
+		repo.logger.Errorw("error loading logins", "provider", provider, "userIDs", userIDs, "err", err)
+		return nil, err
+	}
+
+	for _, i := range identities {
+		if _, ok := logins[i.UserID]; !ok {
+			logins[i.UserID] = i.Login
+		}
+	}
+
+	return logins, nil
+}
@@ -109,15 +109,38 @@ func TestUsersRepoPg_SetIsActive(t *testing.T) {
 				})
 
 				m.ExpectBegin()
-				m.ExpectQuery(`UPDATE "users" SET "is_active"=$1`).
+				m.ExpectQuery(`UPDATE "users" SET "is_active"=$1,"updated_at"=$2 WHERE user_id = $3 AND offboarded_at IS NULL`).
 					WithArgs(true, sqlmock.AnyArg(), "unknown").
 					WillReturnRows(rows)
 				m.ExpectCommit()
+				m.ExpectQuery(`SELECT count(*) FROM "users" WHERE user_id = $1`).
+					WithArgs("unknown").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 			},
 			wantErr:  user.ErrUserNotFound,
 			wantUser: nil,
 		},
 
+		{
+			name: "offboarded user is not reactivated",
+			args: setActiveArgs{
+				userID:   "user-123",
+				isActive: true,
+			},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`UPDATE "users" SET "is_active"=$1,"updated_at"=$2 WHERE user_id = $3 AND offboarded_at IS NULL`).
+					WithArgs(true, sqlmock.AnyArg(), "user-123").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+				m.ExpectCommit()
+				m.ExpectQuery(`SELECT count(*) FROM "users" WHERE user_id = $1`).
+					WithArgs("user-123").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			},
+			wantErr:  user.ErrUserOffboarded,
+			wantUser: nil,
+		},
+
 		{
 			name: "sql error",
 			args: setActiveArgs{
@@ -286,3 +309,736 @@ func TestUsersRepoPg_SetIsActiveByTeam(t *testing.T) {
 		})
 	}
 }
+
+func TestUsersRepoPg_AddUnavailability(t *testing.T) {
+	startsAt := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	endsAt := time.Date(2025, 7, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		window   *user.Unavailability
+		mockFunc func(sqlmock.Sqlmock)
+		wantErr  error
+	}{
+		{
+			name:   "success",
+			window: &user.Unavailability{UserID: "u1", StartsAt: startsAt, EndsAt: endsAt, Reason: "vacation"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT count(*) FROM "users"`).
+					WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+				m.ExpectBegin()
+				m.ExpectQuery(`INSERT INTO "user_unavailabilities"`).
+					WithArgs("u1", startsAt, endsAt, "vacation", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:   "user not found",
+			window: &user.Unavailability{UserID: "unknown", StartsAt: startsAt, EndsAt: endsAt},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT count(*) FROM "users"`).
+					WithArgs("unknown").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+			},
+			wantErr: user.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.AddUnavailability(tt.window)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, uint64(7), got.ID)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUsersRepoPg_ListUnavailability(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectQuery(`SELECT count(*) FROM "users"`).
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+	rows := sqlmock.NewRows([]string{"id", "user_id", "starts_at", "ends_at", "reason", "created_at"}).
+		AddRow(1, "u1", time.Now(), time.Now().Add(time.Hour), "dentist", time.Now()).
+		AddRow(2, "u1", time.Now().Add(24*time.Hour), time.Now().Add(48*time.Hour), "", time.Now())
+	mock.ExpectQuery(`SELECT * FROM "user_unavailabilities" WHERE user_id = $1 ORDER BY starts_at ASC`).
+		WithArgs("u1").
+		WillReturnRows(rows)
+
+	got, err := repo.ListUnavailability("u1")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "dentist", got[0].Reason)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUsersRepoPg_DeleteUnavailability(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected int64
+		wantErr  error
+	}{
+		{name: "success", affected: 1},
+		{name: "not found", affected: 0, wantErr: user.ErrUnavailabilityNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(`DELETE FROM "user_unavailabilities" WHERE id = $1 AND user_id = $2`).
+				WithArgs(uint64(3), "u1").
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+			mock.ExpectCommit()
+
+			err := repo.DeleteUnavailability("u1", 3)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUsersRepoPg_SetMaxOpenReviews(t *testing.T) {
+	limit := 3
+
+	tests := []struct {
+		name     string
+		userID   string
+		limit    *int
+		mockFunc func(sqlmock.Sqlmock)
+		wantErr  error
+	}{
+		{
+			name:   "set limit",
+			userID: "u1",
+			limit:  &limit,
+			mockFunc: func(m sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{
+					"user_id", "username", "team_name", "is_active", "max_open_reviews", "created_at", "updated_at",
+				}).AddRow("u1", "alice", "backend", true, 3, time.Now(), time.Now())
+
+				m.ExpectBegin()
+				m.ExpectQuery(`UPDATE "users" SET "max_open_reviews"=$1`).
+					WithArgs(3, sqlmock.AnyArg(), "u1").
+					WillReturnRows(rows)
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:   "reset to team default",
+			userID: "u1",
+			limit:  nil,
+			mockFunc: func(m sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{
+					"user_id", "username", "team_name", "is_active", "max_open_reviews", "created_at", "updated_at",
+				}).AddRow("u1", "alice", "backend", true, nil, time.Now(), time.Now())
+
+				m.ExpectBegin()
+				m.ExpectQuery(`UPDATE "users" SET "max_open_reviews"=$1`).
+					WithArgs(nil, sqlmock.AnyArg(), "u1").
+					WillReturnRows(rows)
+				m.ExpectCommit()
+			},
+		},
+		{
+			name:   "user not found",
+			userID: "unknown",
+			limit:  &limit,
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`UPDATE "users" SET "max_open_reviews"=$1`).
+					WithArgs(3, sqlmock.AnyArg(), "unknown").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+				m.ExpectCommit()
+			},
+			wantErr: user.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.SetMaxOpenReviews(tt.userID, tt.limit)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.limit, got.MaxOpenReviews)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUsersRepoPg_GetUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		mockFunc func(sqlmock.Sqlmock)
+		wantErr  error
+	}{
+		{
+			name: "success",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "users" WHERE user_id = $1`).
+					WithArgs("user-123", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active"}).
+						AddRow("user-123", "abobus", "backend", true))
+			},
+		},
+		{
+			name: "user not found",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "users" WHERE user_id = $1`).
+					WithArgs("user-123", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+			},
+			wantErr: user.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.GetUser("user-123")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "abobus", got.Username)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUsersRepoPg_ListUsers(t *testing.T) {
+	active := true
+	cols := []string{"user_id", "username", "team_name", "is_active"}
+
+	tests := []struct {
+		name           string
+		filter         user.UserFilter
+		mockFunc       func(sqlmock.Sqlmock)
+		wantErr        error
+		wantIDs        []string
+		wantNextCursor string
+	}{
+		{
+			name:   "filters and next page",
+			filter: user.UserFilter{TeamName: "backend", IsActive: &active, Search: "50%_off", Limit: 2},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "users" WHERE team_name = $1 AND is_active = $2 AND username ILIKE $3 ORDER BY user_id ASC LIMIT $4`).
+					WithArgs("backend", true, `%50\%\_off%`, 3).
+					WillReturnRows(sqlmock.NewRows(cols).
+						AddRow("u1", "50%_off-a", "backend", true).
+						AddRow("u2", "50%_off-b", "backend", true).
+						AddRow("u3", "50%_off-c", "backend", true))
+			},
+			wantIDs:        []string{"u1", "u2"},
+			wantNextCursor: "dTI",
+		},
+		{
+			name:   "last page",
+			filter: user.UserFilter{Cursor: "dTI"},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "users" WHERE user_id > $1 ORDER BY user_id ASC LIMIT $2`).
+					WithArgs("u2", user.DefaultListLimit+1).
+					WillReturnRows(sqlmock.NewRows(cols).AddRow("u3", "carol", "frontend", false))
+			},
+			wantIDs: []string{"u3"},
+		},
+		{
+			name:     "invalid cursor",
+			filter:   user.UserFilter{Cursor: "not base64!"},
+			mockFunc: func(m sqlmock.Sqlmock) {},
+			wantErr:  user.ErrInvalidCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.ListUsers(tt.filter)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				ids := make([]string, 0, len(got.Users))
+				for _, u := range got.Users {
+					ids = append(ids, u.UserID)
+				}
+				require.Equal(t, tt.wantIDs, ids)
+				require.Equal(t, tt.wantNextCursor, got.NextCursor)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUsersRepoPg_UpdateUser(t *testing.T) {
+	newName := "abobus-2"
+	inactive := false
+	active := true
+	cols := []string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at"}
+
+	tests := []struct {
+		name     string
+		upd      user.UserUpdate
+		mockFunc func(sqlmock.Sqlmock)
+		wantErr  error
+		wantUser *user.User
+	}{
+		{
+			name: "rename and deactivate",
+			upd:  user.UserUpdate{Username: &newName, IsActive: &inactive},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`UPDATE "users" SET "is_active"=$1,"username"=$2,"updated_at"=$3 WHERE user_id = $4 RETURNING *`).
+					WithArgs(false, newName, sqlmock.AnyArg(), "user-123").
+					WillReturnRows(sqlmock.NewRows(cols).AddRow("user-123", newName, "backend", false, time.Now(), time.Now()))
+				m.ExpectCommit()
+			},
+			wantUser: &user.User{UserID: "user-123", Username: newName, TeamName: "backend", IsActive: false},
+		},
+		{
+			name: "nothing to update",
+			upd:  user.UserUpdate{},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "users" WHERE user_id = $1`).
+					WillReturnRows(sqlmock.NewRows(cols).AddRow("user-123", "abobus", "backend", true, time.Now(), time.Now()))
+			},
+			wantUser: &user.User{UserID: "user-123", Username: "abobus", TeamName: "backend", IsActive: true},
+		},
+		{
+			name: "user not found",
+			upd:  user.UserUpdate{Username: &newName},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`UPDATE "users" SET "username"=$1,"updated_at"=$2`).
+					WillReturnRows(sqlmock.NewRows(cols))
+				m.ExpectCommit()
+			},
+			wantErr: user.ErrUserNotFound,
+		},
+		{
+			name: "offboarded user is not reactivated",
+			upd:  user.UserUpdate{IsActive: &active},
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`UPDATE "users" SET "is_active"=$1,"updated_at"=$2 WHERE user_id = $3 AND offboarded_at IS NULL`).
+					WithArgs(true, sqlmock.AnyArg(), "user-123").
+					WillReturnRows(sqlmock.NewRows(cols))
+				m.ExpectCommit()
+				m.ExpectQuery(`SELECT count(*) FROM "users" WHERE user_id = $1`).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			},
+			wantErr: user.ErrUserOffboarded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.UpdateUser("user-123", tt.upd)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantUser.Username, got.Username)
+				require.Equal(t, tt.wantUser.IsActive, got.IsActive)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUsersRepoPg_Offboard(t *testing.T) {
+	cols := []string{"user_id", "username", "team_name", "is_active", "created_at", "updated_at", "offboarded_at"}
+
+	expectCleanup := func(m sqlmock.Sqlmock) {
+		m.ExpectQuery(`SELECT * FROM "users" WHERE user_id = $1 ORDER BY "users"."user_id" LIMIT $2 FOR UPDATE`).
+			WithArgs("user-123", 1).
+			WillReturnRows(sqlmock.NewRows(cols).AddRow("user-123", "abobus", "backend", false, time.Now(), time.Now(), nil))
+		m.ExpectExec(`DELETE FROM team_path_rules WHERE user_id = $1`).
+			WithArgs("user-123").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		m.ExpectExec(`DELETE FROM "user_unavailabilities" WHERE user_id = $1`).
+			WithArgs("user-123").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		m.ExpectExec(`DELETE FROM "user_identities" WHERE user_id = $1`).
+			WithArgs("user-123").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+	}
+	// счетчики истории по порядку: авторство, назначения, вердикты. Проверка останавливается на первом ненулевом
+	expectHistory := func(m sqlmock.Sqlmock, counts ...int64) {
+		queries := []string{
+			`SELECT count(*) FROM "pull_requests" WHERE author_id = $1`,
+			`SELECT count(*) FROM "pr_reviewers" WHERE user_id = $1`,
+			`SELECT count(*) FROM "pr_reviews" WHERE user_id = $1`,
+		}
+		for i, count := range counts {
+			m.ExpectQuery(queries[i]).
+				WithArgs("user-123").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+		}
+	}
+	expectTombstone := func(m sqlmock.Sqlmock) {
+		m.ExpectQuery(`UPDATE "users" SET "is_active"=$1,"offboarded_at"=$2,"updated_at"=$3 WHERE "user_id" = $4 RETURNING *`).
+			WithArgs(false, sqlmock.AnyArg(), sqlmock.AnyArg(), "user-123").
+			WillReturnRows(sqlmock.NewRows(cols).
+				AddRow("user-123", "abobus", "backend", false, time.Now(), time.Now(), time.Now()))
+	}
+
+	tests := []struct {
+		name         string
+		anonymize    bool
+		mockFunc     func(sqlmock.Sqlmock)
+		wantErr      error
+		wantDeleted  bool
+		wantUsername string
+	}{
+		{
+			name: "deleted",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectCleanup(m)
+				expectHistory(m, 0, 0, 0)
+				m.ExpectExec(`DELETE FROM "users" WHERE "users"."user_id" = $1`).
+					WithArgs("user-123").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+			wantDeleted:  true,
+			wantUsername: "abobus",
+		},
+		{
+			name:      "tombstoned author",
+			anonymize: true,
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectCleanup(m)
+				expectHistory(m, 3)
+				m.ExpectQuery(`UPDATE "users" SET "is_active"=$1,"offboarded_at"=$2,"username"=$3,"updated_at"=$4 WHERE "user_id" = $5 RETURNING *`).
+					WithArgs(false, sqlmock.AnyArg(), user.AnonymousUsername("user-123"), sqlmock.AnyArg(), "user-123").
+					WillReturnRows(sqlmock.NewRows(cols).
+						AddRow("user-123", user.AnonymousUsername("user-123"), "backend", false, time.Now(), time.Now(), time.Now()))
+				m.ExpectCommit()
+			},
+			wantUsername: user.AnonymousUsername("user-123"),
+		},
+		{
+			name: "tombstoned reviewer of merged PRs",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectCleanup(m)
+				expectHistory(m, 0, 2)
+				expectTombstone(m)
+				m.ExpectCommit()
+			},
+			wantUsername: "abobus",
+		},
+		{
+			name: "tombstoned with verdicts only",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				expectCleanup(m)
+				expectHistory(m, 0, 0, 4)
+				expectTombstone(m)
+				m.ExpectCommit()
+			},
+			wantUsername: "abobus",
+		},
+		{
+			name: "user not found",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectBegin()
+				m.ExpectQuery(`SELECT * FROM "users"`).WillReturnError(gorm.ErrRecordNotFound)
+				m.ExpectRollback()
+			},
+			wantErr: user.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.Offboard("user-123", tt.anonymize)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantDeleted, got.Deleted)
+				require.Equal(t, tt.anonymize, got.Anonymized)
+				require.Equal(t, tt.wantUsername, got.User.Username)
+				require.Equal(t, int64(2), got.PathRulesDeleted)
+				require.Equal(t, int64(1), got.UnavailabilitiesDeleted)
+				require.Equal(t, int64(3), got.IdentitiesDeleted)
+				if !tt.wantDeleted {
+					require.NotNil(t, got.User.OffboardedAt)
+				}
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUsersRepoPg_SetIdentity(t *testing.T) {
+	gitlabID := int64(31)
+
+	tests := []struct {
+		name       string
+		externalID *int64
+		mockFunc   func(sqlmock.Sqlmock)
+		wantErr    error
+	}{
+		{
+			name: "success",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT count(*) FROM "users"`).
+					WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+				m.ExpectBegin()
+				m.ExpectExec(`INSERT INTO "user_identities"`).
+					WithArgs("github", "octocat-dev", "u1", nil, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			// id переезжает с прежнего логина
+			name:       "with external id",
+			externalID: &gitlabID,
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT count(*) FROM "users"`).
+					WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+				m.ExpectBegin()
+				m.ExpectExec(`UPDATE "user_identities" SET "external_id"=$1 WHERE provider = $2 AND external_id = $3 AND login <> $4`).
+					WithArgs(nil, "github", gitlabID, "octocat-dev").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectExec(`INSERT INTO "user_identities"`).
+					WithArgs("github", "octocat-dev", "u1", gitlabID, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				m.ExpectCommit()
+			},
+		},
+		{
+			name: "user not found",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT count(*) FROM "users"`).
+					WithArgs("u1").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(0)))
+			},
+			wantErr: user.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.SetIdentity(&user.Identity{Provider: "github", Login: "Octocat-Dev", UserID: "u1", ExternalID: tt.externalID})
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, "octocat-dev", got.Login)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUsersRepoPg_ResolveIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		mockFunc func(sqlmock.Sqlmock)
+		want     string
+		wantErr  error
+	}{
+		{
+			name: "success",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "user_identities" WHERE provider = $1 AND login = $2`).
+					WithArgs("github", "octocat-dev", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"provider", "login", "user_id"}).
+						AddRow("github", "octocat-dev", "u1"))
+			},
+			want: "u1",
+		},
+		{
+			name: "unknown login",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "user_identities" WHERE provider = $1 AND login = $2`).
+					WithArgs("github", "octocat-dev", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"provider"}))
+			},
+			wantErr: user.ErrIdentityNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.ResolveIdentity("github", "Octocat-Dev")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUsersRepoPg_ResolveExternalID(t *testing.T) {
+	tests := []struct {
+		name     string
+		mockFunc func(sqlmock.Sqlmock)
+		want     string
+		wantErr  error
+	}{
+		{
+			name: "success",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "user_identities" WHERE provider = $1 AND external_id = $2`).
+					WithArgs("gitlab", int64(31), 1).
+					WillReturnRows(sqlmock.NewRows([]string{"provider", "login", "user_id", "external_id"}).
+						AddRow("gitlab", "jane.ops", "u2", int64(31)))
+			},
+			want: "u2",
+		},
+		{
+			name: "unknown id",
+			mockFunc: func(m sqlmock.Sqlmock) {
+				m.ExpectQuery(`SELECT * FROM "user_identities" WHERE provider = $1 AND external_id = $2`).
+					WithArgs("gitlab", int64(31), 1).
+					WillReturnRows(sqlmock.NewRows([]string{"provider"}))
+			},
+			wantErr: user.ErrIdentityNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, cleanup := newMockDB(t)
+			defer cleanup()
+
+			repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+			tt.mockFunc(mock)
+
+			got, err := repo.ResolveExternalID("gitlab", 31)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUsersRepoPg_LoginsByUsers(t *testing.T) {
+	db, mock, cleanup := newMockDB(t)
+	defer cleanup()
+
+	repo := user.NewUsersRepoPg(zap.NewNop().Sugar(), db)
+
+	mock.ExpectQuery(`SELECT * FROM "user_identities" WHERE provider = $1 AND user_id IN ($2,$3,$4)`).
+		WithArgs("github", "u1", "u2", "u3").
+		WillReturnRows(sqlmock.NewRows([]string{"provider", "login", "user_id"}).
+			AddRow("github", "alice", "u1").
+			AddRow("github", "alice-work", "u1").
+			AddRow("github", "bob", "u2"))
+
+	got, err := repo.LoginsByUsers("github", []string{"u1", "u2", "u3"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"u1": "alice", "u2": "bob"}, got)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// без пользователей в БД не ходим
+	got, err = repo.LoginsByUsers("github", nil)
+	require.NoError(t, err)
+	require.Empty(t, got)
+}